@@ -0,0 +1,92 @@
+package netlink
+
+// defaultArenaChunk is the size of each chunk of memory allocated by an Arena
+// if no size is specified.
+const defaultArenaChunk = 64 * 1024
+
+// An Arena is a bump allocator for the short-lived data produced while
+// decoding a large dump, such as a periodic scrape of a full routing table.
+// Memory is allocated from large chunks rather than individually, and is
+// released all at once by calling Reset, which greatly reduces the work done
+// by the garbage collector.
+//
+// An Arena can be used by an AttributeDecoder for Attribute data, and by
+// Conn.ExecuteArena for the data of received Messages.
+//
+// Data allocated from an Arena must not be used after Reset is called. An
+// Arena is not safe for concurrent use.
+type Arena struct {
+	size int
+
+	// chunks are all of the chunks allocated by the Arena, which are reused
+	// after Reset. chunks[i] is the current chunk, and off is the offset of
+	// the next allocation within it.
+	chunks [][]byte
+	i      int
+	off    int
+}
+
+// NewArena creates an Arena which allocates memory in chunks of the specified
+// size in bytes. If size is less than 1, a default size is used. Requests for
+// more than size bytes are allocated individually.
+func NewArena(size int) *Arena {
+	if size < 1 {
+		size = defaultArenaChunk
+	}
+
+	return &Arena{size: size}
+}
+
+// bufferArena creates an Arena which allocates memory from b before
+// allocating chunks of len(b) bytes.
+func bufferArena(b []byte) *Arena {
+	if len(b) == 0 {
+		return nil
+	}
+
+	return &Arena{
+		size:   len(b),
+		chunks: [][]byte{b[:len(b):len(b)]},
+	}
+}
+
+// Reset releases all memory allocated from the Arena for reuse. Any data
+// previously allocated from the Arena must no longer be used.
+func (a *Arena) Reset() {
+	a.i, a.off = 0, 0
+}
+
+// alloc returns a slice of n bytes from the Arena. The contents of the slice
+// are not zeroed. A nil Arena allocates using make.
+func (a *Arena) alloc(n int) []byte {
+	if a == nil || n > a.size {
+		return make([]byte, n)
+	}
+
+	if len(a.chunks) == 0 || a.off+n > a.size {
+		if len(a.chunks) > 0 {
+			// Advance to the next chunk, reusing one from before the last
+			// Reset if possible.
+			a.i++
+		}
+		if a.i == len(a.chunks) {
+			a.chunks = append(a.chunks, make([]byte, a.size))
+		}
+
+		a.off = 0
+	}
+
+	b := a.chunks[a.i][a.off : a.off+n : a.off+n]
+	a.off += n
+	return b
+}
+
+// trim shortens b, which must have been the most recent allocation from the
+// Arena, to n bytes and releases the remainder for reuse.
+func (a *Arena) trim(b []byte, n int) []byte {
+	if a != nil && len(b) <= a.size {
+		a.off -= len(b) - n
+	}
+
+	return b[:n:n]
+}
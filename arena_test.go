@@ -0,0 +1,120 @@
+package netlink
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestArena(t *testing.T) {
+	a := NewArena(16)
+
+	// Allocations are made consecutively from the first chunk.
+	b1 := a.alloc(8)
+	b2 := a.alloc(8)
+	if &b1[0] == &b2[0] || cap(b1) != 8 {
+		t.Fatal("allocations overlap")
+	}
+
+	// The next chunk is allocated once the first is full, and large
+	// allocations are made individually.
+	b3 := a.alloc(4)
+	big := a.alloc(32)
+	if len(a.chunks) != 2 || len(big) != 32 {
+		t.Fatalf("unexpected number of chunks: %d", len(a.chunks))
+	}
+
+	// Trimming the most recent allocation releases the remainder.
+	b4 := a.trim(a.alloc(8), 2)
+	b5 := a.alloc(2)
+	if &b5[0] != &a.chunks[1][6] || len(b4) != 2 || cap(b4) != 2 {
+		t.Fatal("trimmed allocation was not released")
+	}
+
+	// Chunks are reused after Reset.
+	a.Reset()
+	if b := a.alloc(4); &b[0] != &b1[0] {
+		t.Fatal("first chunk was not reused")
+	}
+	if b := a.alloc(16); &b[0] != &b3[0] || len(a.chunks) != 2 {
+		t.Fatal("second chunk was not reused")
+	}
+}
+
+func TestBufferArena(t *testing.T) {
+	if a := bufferArena(nil); a != nil {
+		t.Fatal("expected nil Arena for empty buffer")
+	}
+
+	buf := make([]byte, 8)
+	a := bufferArena(buf)
+
+	// Allocations are made from buf until it is full.
+	if b := a.alloc(8); &b[0] != &buf[0] {
+		t.Fatal("allocation was not made from buffer")
+	}
+	if b := a.alloc(8); &b[0] == &buf[0] || len(a.chunks) != 2 {
+		t.Fatal("allocation was made from full buffer")
+	}
+}
+
+func TestAttributeDecoderArena(t *testing.T) {
+	ae := NewAttributeEncoder()
+	ae.Bytes(1, []byte{0xff})
+	ae.Nested(2, func(nae *AttributeEncoder) error {
+		nae.String(1, "hello")
+		return nil
+	})
+
+	b, err := ae.Encode()
+	if err != nil {
+		t.Fatalf("failed to encode attributes: %v", err)
+	}
+
+	ad, err := NewAttributeDecoder(b)
+	if err != nil {
+		t.Fatalf("failed to create attribute decoder: %v", err)
+	}
+
+	arena := NewArena(0)
+	ad.Arena = arena
+
+	var (
+		bs []byte
+		s  string
+	)
+
+	for ad.Next() {
+		switch ad.Type() {
+		case 1:
+			bs = ad.Bytes()
+		case 2:
+			ad.Nested(func(nad *AttributeDecoder) error {
+				if nad.Arena != arena {
+					t.Fatal("nested decoder did not inherit arena")
+				}
+
+				for nad.Next() {
+					s = nad.String()
+				}
+				return nil
+			})
+		}
+	}
+
+	if err := ad.Err(); err != nil {
+		t.Fatalf("failed to decode attributes: %v", err)
+	}
+
+	if diff := cmp.Diff([]byte{0xff}, bs); diff != "" {
+		t.Fatalf("unexpected bytes (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff("hello", s); diff != "" {
+		t.Fatalf("unexpected string (-want +got):\n%s", diff)
+	}
+
+	// All attribute data was allocated from a single chunk.
+	if len(arena.chunks) != 1 || arena.off == 0 {
+		t.Fatalf("unexpected arena state: %d chunks, offset %d", len(arena.chunks), arena.off)
+	}
+}
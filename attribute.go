@@ -5,6 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/josharian/native"
 	"github.com/mdlayher/netlink/nlenc"
@@ -13,6 +16,75 @@ import (
 // errInvalidAttribute specifies if an Attribute's length is incorrect.
 var errInvalidAttribute = errors.New("invalid attribute; length too short or too large")
 
+// maxAttrDataLen is the maximum length of data which can be described by the
+// 16-bit length field of a netlink attribute header.
+var maxAttrDataLen = math.MaxUint16 - nlaHeaderLen
+
+// An AttributeTooLargeError is returned by an AttributeEncoder when attribute
+// data exceeds the size which can be described by a netlink attribute header.
+//
+// The AttributeEncoder does not split oversized data automatically. Some
+// netlink families accept a large value spread across several attributes,
+// such as repeated or nested array attributes, in which case the caller may
+// split the data; otherwise the data cannot be sent in a single attribute.
+type AttributeTooLargeError struct {
+	// Kind describes the source of the attribute data, such as "string".
+	Kind string
+
+	// Type is the type of the attribute which could not be encoded.
+	Type uint16
+
+	// Size is the length of the attribute data, and Limit is the maximum
+	// allowed length of attribute data.
+	Size, Limit int
+}
+
+// Error implements error.
+func (e *AttributeTooLargeError) Error() string {
+	return fmt.Sprintf("%s is too large to fit in a netlink attribute: type: %d, size: %d, limit: %d",
+		e.Kind, e.Type, e.Size, e.Limit)
+}
+
+// An AttributeOrderError is returned by AttributeEncoder.Encode when the
+// OrderStrict AttributeOrder is set and an attribute was added after another
+// attribute with a greater type.
+type AttributeOrderError struct {
+	// Type is the type of the out of order attribute, and Previous is the
+	// type of the attribute which preceded it, excluding any flags.
+	Type, Previous uint16
+}
+
+// Error implements error.
+func (e *AttributeOrderError) Error() string {
+	return fmt.Sprintf("netlink: attribute type %d encoded after type %d", e.Type, e.Previous)
+}
+
+// errSortPad64 is returned by AttributeEncoder.Encode when the OrderSort
+// AttributeOrder is set along with Pad64, as sorting would misalign 64-bit
+// values.
+var errSortPad64 = errors.New("netlink: AttributeEncoder cannot sort attributes when Pad64 is set")
+
+// An AttributeOrder specifies how an AttributeEncoder orders attributes.
+type AttributeOrder int
+
+// Possible AttributeOrder values.
+const (
+	// OrderAsIs encodes attributes in the order in which they were added.
+	OrderAsIs AttributeOrder = iota
+
+	// OrderStrict encodes attributes in the order in which they were added,
+	// but Encode returns an *AttributeOrderError if they are not in
+	// ascending order of type. Attributes of the same type may be repeated.
+	// OrderStrict is useful in tests to catch ordering regressions in
+	// encoders for kernel parsers which are sensitive to attribute order.
+	OrderStrict
+
+	// OrderSort encodes attributes in ascending order of type, preserving the
+	// order in which attributes of the same type were added. OrderSort cannot
+	// be used along with Pad64.
+	OrderSort
+)
+
 // An Attribute is a netlink attribute.  Attributes are packed and unpacked
 // to and from the Data field of Message for some netlink families.
 type Attribute struct {
@@ -42,8 +114,9 @@ func (a *Attribute) marshal(b []byte) (int, error) {
 	return nlaHeaderLen + nlaAlign(n), nil
 }
 
-// unmarshal unmarshals the contents of a byte slice into an Attribute.
-func (a *Attribute) unmarshal(b []byte) error {
+// unmarshal unmarshals the contents of a byte slice into an Attribute,
+// allocating its data from arena.
+func (a *Attribute) unmarshal(b []byte, arena *Arena) error {
 	if len(b) < nlaHeaderLen {
 		return errInvalidAttribute
 	}
@@ -64,13 +137,25 @@ func (a *Attribute) unmarshal(b []byte) error {
 		return errInvalidAttribute
 	// Data present
 	case int(a.Length) >= nlaHeaderLen:
-		a.Data = make([]byte, len(b[nlaHeaderLen:a.Length]))
+		a.Data = arena.alloc(len(b[nlaHeaderLen:a.Length]))
 		copy(a.Data, b[nlaHeaderLen:a.Length])
 	}
 
 	return nil
 }
 
+// SizeAttributes returns the length in bytes of attrs when packed by
+// MarshalAttributes, including attribute headers and padding, so callers can
+// preallocate buffers or enforce message size limits before marshaling.
+func SizeAttributes(attrs []Attribute) int {
+	var n int
+	for _, a := range attrs {
+		n += nlaHeaderLen + nlaAlign(len(a.Data))
+	}
+
+	return n
+}
+
 // MarshalAttributes packs a slice of Attributes into a single byte slice.
 // In most cases, the Length field of each Attribute should be set to 0, so it
 // can be calculated and populated automatically for each Attribute.
@@ -78,15 +163,9 @@ func (a *Attribute) unmarshal(b []byte) error {
 // It is recommend to use the AttributeEncoder type where possible instead of
 // calling MarshalAttributes and using package nlenc functions directly.
 func MarshalAttributes(attrs []Attribute) ([]byte, error) {
-	// Count how many bytes we should allocate to store each attribute's contents.
-	var c int
-	for _, a := range attrs {
-		c += nlaHeaderLen + nlaAlign(len(a.Data))
-	}
-
 	// Advance through b with idx to place attribute data at the correct offset.
 	var idx int
-	b := make([]byte, c)
+	b := make([]byte, SizeAttributes(attrs))
 	for _, a := range attrs {
 		// Infer the length of attribute if zero.
 		if a.Length == 0 {
@@ -134,6 +213,65 @@ func UnmarshalAttributes(b []byte) ([]Attribute, error) {
 	return attrs, nil
 }
 
+// SortAttributes sorts attrs in place by type in ascending order, ignoring the
+// Nested and NetByteOrder flags. The relative order of attributes with the
+// same type is preserved, because some netlink families use repeated
+// attributes to encode arrays.
+func SortAttributes(attrs []Attribute) {
+	sort.SliceStable(attrs, func(i, j int) bool {
+		return attrs[i].Type&attrTypeMask < attrs[j].Type&attrTypeMask
+	})
+}
+
+// NormalizeAttributes returns attrs in a new slice with a canonical encoding, so
+// that attributes which are semantically equal also compare equal using
+// reflect.DeepEqual or cmp.Diff, such as in tests or when deduplicating
+// cached values. The Length of each Attribute is recomputed from its data,
+// empty data is set to nil, and the data of attributes with the Nested flag is
+// normalized recursively, which removes any excess padding between or after
+// the nested attributes. The data of other attributes is not copied.
+//
+// NormalizeAttributes does not reorder attributes; use SortAttributes to do so.
+func NormalizeAttributes(attrs []Attribute) ([]Attribute, error) {
+	if attrs == nil {
+		return nil, nil
+	}
+
+	out := make([]Attribute, 0, len(attrs))
+	for _, a := range attrs {
+		data := a.Data
+		if a.Type&Nested != 0 {
+			nested, err := UnmarshalAttributes(a.Data)
+			if err != nil {
+				return nil, fmt.Errorf("netlink: failed to normalize nested attribute %d: %v", a.Type&attrTypeMask, err)
+			}
+
+			if nested, err = NormalizeAttributes(nested); err != nil {
+				return nil, err
+			}
+
+			if data, err = MarshalAttributes(nested); err != nil {
+				return nil, err
+			}
+		}
+
+		if len(data) == 0 {
+			data = nil
+		}
+		if len(data) > maxAttrDataLen {
+			return nil, errInvalidAttribute
+		}
+
+		out = append(out, Attribute{
+			Length: uint16(nlaHeaderLen + len(data)),
+			Type:   a.Type,
+			Data:   data,
+		})
+	}
+
+	return out, nil
+}
+
 // An AttributeDecoder provides a safe, iterator-like, API around attribute
 // decoding.
 //
@@ -150,8 +288,43 @@ type AttributeDecoder struct {
 	// If not set, the native byte order will be used.
 	ByteOrder binary.ByteOrder
 
-	// The current attribute being worked on.
-	a Attribute
+	// Pad64 specifies the attribute type used by a netlink family to align
+	// 64-bit values on 8-byte boundaries, as produced by the kernel's
+	// nla_put_64bit function (for example, IFLA_PAD in route netlink).
+	//
+	// If non-zero, zero-length attributes of type Pad64 are skipped by Next.
+	// Pad64 should be set immediately after creating the AttributeDecoder, and
+	// is not inherited by nested AttributeDecoders because padding types are
+	// specific to each set of attributes. Len includes any padding attributes.
+	Pad64 uint16
+
+	// CollectUnknown specifies whether the decoder should record attributes
+	// whose data was not accessed by the caller before advancing to the next
+	// attribute. These attributes can be retrieved by calling Unknown once
+	// iteration is complete. This is useful for logging or forwarding
+	// attributes added by newer kernels which the caller does not understand.
+	//
+	// CollectUnknown should be set immediately after creating the
+	// AttributeDecoder: before any attributes are parsed.
+	CollectUnknown bool
+
+	// Arena, if not nil, is used to allocate the data of each attribute and
+	// the slices returned by Bytes, rather than allocating them individually.
+	// This reduces the cost of garbage collection when decoding large dumps,
+	// but the data must no longer be used once Arena.Reset is called.
+	//
+	// Arena should be set immediately after creating the AttributeDecoder, and
+	// is inherited by nested AttributeDecoders.
+	Arena *Arena
+
+	// The current attribute being worked on, and whether or not its data has
+	// been accessed by the caller.
+	a        Attribute
+	current  bool
+	consumed bool
+
+	// Attributes which were not accessed when CollectUnknown is set.
+	unknown []Attribute
 
 	// The slice of input bytes and its iterator index.
 	b []byte
@@ -185,29 +358,63 @@ func NewAttributeDecoder(b []byte) (*AttributeDecoder, error) {
 // Next advances the decoder to the next netlink attribute.  It returns false
 // when no more attributes are present, or an error was encountered.
 func (ad *AttributeDecoder) Next() bool {
-	if ad.err != nil {
-		// Hit an error, stop iteration.
-		return false
-	}
+	ad.collect()
 
-	// Exit if array pointer is at or beyond the end of the slice.
-	if ad.i >= len(ad.b) {
-		return false
-	}
+	for {
+		if ad.err != nil {
+			// Hit an error, stop iteration.
+			return false
+		}
 
-	if err := ad.a.unmarshal(ad.b[ad.i:]); err != nil {
-		ad.err = err
-		return false
+		// Exit if array pointer is at or beyond the end of the slice.
+		if ad.i >= len(ad.b) {
+			return false
+		}
+
+		if err := ad.a.unmarshal(ad.b[ad.i:], ad.Arena); err != nil {
+			ad.err = err
+			return false
+		}
+
+		// Advance the pointer by at least one header's length.
+		if int(ad.a.Length) < nlaHeaderLen {
+			ad.i += nlaHeaderLen
+		} else {
+			ad.i += nlaAlign(int(ad.a.Length))
+		}
+
+		if !ad.isPad() {
+			ad.current, ad.consumed = true, false
+			return true
+		}
 	}
+}
 
-	// Advance the pointer by at least one header's length.
-	if int(ad.a.Length) < nlaHeaderLen {
-		ad.i += nlaHeaderLen
-	} else {
-		ad.i += nlaAlign(int(ad.a.Length))
+// collect records the current attribute as unknown if CollectUnknown is set
+// and the attribute's data was never accessed.
+func (ad *AttributeDecoder) collect() {
+	if ad.CollectUnknown && ad.current && !ad.consumed && ad.a.Length != 0 {
+		// The decoder allocates new data for each attribute, so there is no
+		// need to copy it here.
+		ad.unknown = append(ad.unknown, ad.a)
 	}
 
-	return true
+	ad.current = false
+}
+
+// Unknown returns the attributes whose data was not accessed by the caller
+// during iteration, in the order they were encountered. Unknown always returns
+// nil unless CollectUnknown is set.
+//
+// Unknown should be called after Next returns false. The returned Attributes
+// retain any flags present in their Type field, so they may be re-encoded
+// byte-for-byte.
+func (ad *AttributeDecoder) Unknown() []Attribute { return ad.unknown }
+
+// isPad reports whether the current attribute is a 64-bit alignment padding
+// attribute which should be skipped.
+func (ad *AttributeDecoder) isPad() bool {
+	return ad.Pad64 != 0 && ad.Type() == ad.Pad64 && len(ad.a.Data) == 0
 }
 
 // Type returns the Attribute.Type field of the current netlink attribute
@@ -262,8 +469,12 @@ func (ad *AttributeDecoder) available() (int, error) {
 	return count, nil
 }
 
-// data returns the Data field of the current Attribute pointed to by the decoder.
-func (ad *AttributeDecoder) data() []byte { return ad.a.Data }
+// data returns the Data field of the current Attribute pointed to by the
+// decoder, and marks the Attribute as consumed.
+func (ad *AttributeDecoder) data() []byte {
+	ad.consumed = true
+	return ad.a.Data
+}
 
 // Err returns the first error encountered by the decoder.
 func (ad *AttributeDecoder) Err() error { return ad.err }
@@ -271,7 +482,7 @@ func (ad *AttributeDecoder) Err() error { return ad.err }
 // Bytes returns the raw bytes of the current Attribute's data.
 func (ad *AttributeDecoder) Bytes() []byte {
 	src := ad.data()
-	dest := make([]byte, len(src))
+	dest := ad.Arena.alloc(len(src))
 	copy(dest, src)
 	return dest
 }
@@ -345,6 +556,61 @@ func (ad *AttributeDecoder) Uint64() uint64 {
 	return ad.ByteOrder.Uint64(b)
 }
 
+// Counter64 returns the uint64 representation of the current Attribute's
+// data, widening the value if the attribute contains a uint32.
+//
+// Counter64 is useful for counters which may be sent as either 32-bit or
+// 64-bit values, depending on the kernel version or the size of the value.
+func (ad *AttributeDecoder) Counter64() uint64 {
+	if ad.err != nil {
+		return 0
+	}
+
+	b := ad.data()
+	switch len(b) {
+	case 4:
+		return uint64(ad.ByteOrder.Uint32(b))
+	case 8:
+		return ad.ByteOrder.Uint64(b)
+	default:
+		ad.err = fmt.Errorf("netlink: attribute %d is not a uint32 or uint64; length: %d", ad.Type(), len(b))
+		return 0
+	}
+}
+
+// UintAuto returns the unsigned integer representation of the current
+// Attribute's data, which may be 1, 2, 4, or 8 bytes in length. If the
+// NetByteOrder flag is set in the Attribute's type, the data is decoded in big
+// endian byte order rather than using ByteOrder.
+//
+// UintAuto is useful for generic tools which walk the attributes of families
+// whose integer sizes and byte orders are not known in advance.
+func (ad *AttributeDecoder) UintAuto() uint64 {
+	if ad.err != nil {
+		return 0
+	}
+
+	order := ad.ByteOrder
+	if ad.TypeFlags()&NetByteOrder != 0 {
+		order = binary.BigEndian
+	}
+
+	b := ad.data()
+	switch len(b) {
+	case 1:
+		return uint64(b[0])
+	case 2:
+		return uint64(order.Uint16(b))
+	case 4:
+		return uint64(order.Uint32(b))
+	case 8:
+		return order.Uint64(b)
+	default:
+		ad.err = fmt.Errorf("netlink: attribute %d is not an unsigned integer; length: %d", ad.Type(), len(b))
+		return 0
+	}
+}
+
 // Int8 returns the Int8 representation of the current Attribute's data.
 func (ad *AttributeDecoder) Int8() int8 {
 	if ad.err != nil {
@@ -445,8 +711,14 @@ func (ad *AttributeDecoder) Do(fn func(b []byte) error) {
 // attributes. When calling Nested, the Err method does not need to be called on
 // the nested AttributeDecoder.
 //
-// The nested AttributeDecoder nad inherits the same ByteOrder setting as the
-// top-level AttributeDecoder ad.
+// Any error encountered while decoding the nested attributes is prefixed with
+// the types of each enclosing nested attribute, such as "attr 4 > 1: ", so
+// that errors deep within a set of nested attributes can be located. If fn
+// annotates such an error, the types of the attributes nested within fn's
+// attribute follow the annotation, such as "attr 4: decoding link: attr 1: ".
+//
+// The nested AttributeDecoder nad inherits the same ByteOrder and Arena
+// settings as the top-level AttributeDecoder ad.
 func (ad *AttributeDecoder) Nested(fn func(nad *AttributeDecoder) error) {
 	// Because we are wrapping Do, there is no need to check ad.err immediately.
 	ad.Do(func(b []byte) error {
@@ -455,15 +727,77 @@ func (ad *AttributeDecoder) Nested(fn func(nad *AttributeDecoder) error) {
 			return err
 		}
 		nad.ByteOrder = ad.ByteOrder
+		nad.Arena = ad.Arena
 
 		if err := fn(nad); err != nil {
-			return err
+			return wrapNested(ad.Type(), err)
 		}
 
-		return nad.Err()
+		return wrapNested(ad.Type(), nad.Err())
 	})
 }
 
+// A nestedError records the types of the nested attributes which were being
+// decoded when an error occurred.
+type nestedError struct {
+	// path is the full path to the attribute which produced the error.
+	path []uint16
+	err  error
+
+	// inner is a nestedError wrapped by err, such as when a caller annotates
+	// the error from a deeper nested decoder. The error text of err already
+	// contains the portion of path recorded by inner.
+	inner *nestedError
+}
+
+// wrapNested returns a new error which adds typ to the beginning of the path
+// of err, returning nil if err is nil. If err wraps a nestedError, such as when
+// a caller's function annotates the error from a deeper nested decoder, the
+// paths are merged.
+func wrapNested(typ uint16, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var nerr *nestedError
+	if !errors.As(err, &nerr) {
+		return &nestedError{path: []uint16{typ}, err: err}
+	}
+
+	path := append([]uint16{typ}, nerr.path...)
+	if nerr == err {
+		// Replace err rather than modifying it, since it may have already
+		// been returned to a caller.
+		return &nestedError{path: path, err: nerr.err, inner: nerr.inner}
+	}
+
+	return &nestedError{path: path, err: err, inner: nerr}
+}
+
+func (e *nestedError) Error() string {
+	// The portion of the path recorded by an inner error is rendered by the
+	// inner error itself, following any annotations which wrap it.
+	path := e.path
+	if e.inner != nil {
+		path = path[:len(path)-len(e.inner.path)]
+	}
+
+	var sb strings.Builder
+	sb.WriteString("attr ")
+	for i, typ := range path {
+		if i > 0 {
+			sb.WriteString(" > ")
+		}
+		sb.WriteString(strconv.Itoa(int(typ)))
+	}
+	sb.WriteString(": ")
+	sb.WriteString(e.err.Error())
+
+	return sb.String()
+}
+
+func (e *nestedError) Unwrap() error { return e.err }
+
 // An AttributeEncoder provides a safe way to encode attributes.
 //
 // It is recommended to use an AttributeEncoder where possible instead of
@@ -479,8 +813,37 @@ type AttributeEncoder struct {
 	// If not set, the native byte order will be used.
 	ByteOrder binary.ByteOrder
 
+	// Pad64 specifies the attribute type used by a netlink family to align
+	// 64-bit values on 8-byte boundaries, as with the kernel's nla_put_64bit
+	// function (for example, IFLA_PAD in route netlink).
+	//
+	// If non-zero, Uint64 and Int64 insert a zero-length attribute of type
+	// Pad64 when necessary so that the 64-bit value begins on an 8-byte
+	// boundary within the message, taking Pad64Offset into account. Pad64 is
+	// not inherited by nested AttributeEncoders.
+	Pad64 uint16
+
+	// Pad64Offset is the offset of the encoded attributes from the start of
+	// the message data, such as 4 for attributes which follow generic
+	// netlink's genlmsghdr. Because the netlink header is 8-byte aligned,
+	// Pad64 uses Pad64Offset to align 64-bit values as the kernel does.
+	//
+	// Pad64Offset is set automatically for nested AttributeEncoders to
+	// account for their position within the parent, assuming the parent's
+	// attributes are encoded in the order in which they were added.
+	Pad64Offset int
+
+	// Order specifies how the attributes are ordered by Encode. If not set,
+	// attributes are encoded in the order in which they were added. Order is
+	// inherited by nested AttributeEncoders.
+	Order AttributeOrder
+
 	attrs []Attribute
 	err   error
+
+	// size is the length in bytes of attrs once encoded, which is used to
+	// align attributes for Pad64.
+	size int
 }
 
 // NewAttributeEncoder creates an AttributeEncoder that encodes Attributes.
@@ -494,7 +857,7 @@ func (ae *AttributeEncoder) Uint8(typ uint16, v uint8) {
 		return
 	}
 
-	ae.attrs = append(ae.attrs, Attribute{
+	ae.add(Attribute{
 		Type: typ,
 		Data: []byte{v},
 	})
@@ -509,7 +872,7 @@ func (ae *AttributeEncoder) Uint16(typ uint16, v uint16) {
 	b := make([]byte, 2)
 	ae.ByteOrder.PutUint16(b, v)
 
-	ae.attrs = append(ae.attrs, Attribute{
+	ae.add(Attribute{
 		Type: typ,
 		Data: b,
 	})
@@ -524,7 +887,7 @@ func (ae *AttributeEncoder) Uint32(typ uint16, v uint32) {
 	b := make([]byte, 4)
 	ae.ByteOrder.PutUint32(b, v)
 
-	ae.attrs = append(ae.attrs, Attribute{
+	ae.add(Attribute{
 		Type: typ,
 		Data: b,
 	})
@@ -536,10 +899,12 @@ func (ae *AttributeEncoder) Uint64(typ uint16, v uint64) {
 		return
 	}
 
+	ae.pad64()
+
 	b := make([]byte, 8)
 	ae.ByteOrder.PutUint64(b, v)
 
-	ae.attrs = append(ae.attrs, Attribute{
+	ae.add(Attribute{
 		Type: typ,
 		Data: b,
 	})
@@ -551,7 +916,7 @@ func (ae *AttributeEncoder) Int8(typ uint16, v int8) {
 		return
 	}
 
-	ae.attrs = append(ae.attrs, Attribute{
+	ae.add(Attribute{
 		Type: typ,
 		Data: []byte{uint8(v)},
 	})
@@ -566,7 +931,7 @@ func (ae *AttributeEncoder) Int16(typ uint16, v int16) {
 	b := make([]byte, 2)
 	ae.ByteOrder.PutUint16(b, uint16(v))
 
-	ae.attrs = append(ae.attrs, Attribute{
+	ae.add(Attribute{
 		Type: typ,
 		Data: b,
 	})
@@ -581,7 +946,7 @@ func (ae *AttributeEncoder) Int32(typ uint16, v int32) {
 	b := make([]byte, 4)
 	ae.ByteOrder.PutUint32(b, uint32(v))
 
-	ae.attrs = append(ae.attrs, Attribute{
+	ae.add(Attribute{
 		Type: typ,
 		Data: b,
 	})
@@ -593,15 +958,36 @@ func (ae *AttributeEncoder) Int64(typ uint16, v int64) {
 		return
 	}
 
+	ae.pad64()
+
 	b := make([]byte, 8)
 	ae.ByteOrder.PutUint64(b, uint64(v))
 
-	ae.attrs = append(ae.attrs, Attribute{
+	ae.add(Attribute{
 		Type: typ,
 		Data: b,
 	})
 }
 
+// pad64 inserts a padding attribute if ae.Pad64 is set and the next
+// attribute's data would not be aligned on an 8-byte boundary.
+func (ae *AttributeEncoder) pad64() {
+	if ae.Pad64 == 0 {
+		return
+	}
+
+	if (ae.Pad64Offset+ae.size+nlaHeaderLen)%8 != 0 {
+		ae.add(Attribute{Type: ae.Pad64})
+	}
+}
+
+// add appends a to the attributes to be encoded, keeping track of their
+// length in bytes.
+func (ae *AttributeEncoder) add(a Attribute) {
+	ae.attrs = append(ae.attrs, a)
+	ae.size += nlaHeaderLen + nlaAlign(len(a.Data))
+}
+
 // Flag encodes a flag into an Attribute specified by typ.
 func (ae *AttributeEncoder) Flag(typ uint16, v bool) {
 	// Only set flag on no previous error or v == true.
@@ -610,7 +996,7 @@ func (ae *AttributeEncoder) Flag(typ uint16, v bool) {
 	}
 
 	// Flags have no length or data fields.
-	ae.attrs = append(ae.attrs, Attribute{Type: typ})
+	ae.add(Attribute{Type: typ})
 }
 
 // String encodes string s as a null-terminated string into an Attribute
@@ -621,12 +1007,12 @@ func (ae *AttributeEncoder) String(typ uint16, s string) {
 	}
 
 	// Length checking, thanks ubiquitousbyte on GitHub.
-	if len(s) > math.MaxUint16-nlaHeaderLen {
-		ae.err = errors.New("string is too large to fit in a netlink attribute")
+	if len(s) > maxAttrDataLen {
+		ae.err = &AttributeTooLargeError{Kind: "string", Type: typ, Size: len(s), Limit: maxAttrDataLen}
 		return
 	}
 
-	ae.attrs = append(ae.attrs, Attribute{
+	ae.add(Attribute{
 		Type: typ,
 		Data: nlenc.Bytes(s),
 	})
@@ -638,17 +1024,69 @@ func (ae *AttributeEncoder) Bytes(typ uint16, b []byte) {
 		return
 	}
 
-	if len(b) > math.MaxUint16-nlaHeaderLen {
-		ae.err = errors.New("byte slice is too large to fit in a netlink attribute")
+	if len(b) > maxAttrDataLen {
+		ae.err = &AttributeTooLargeError{Kind: "byte slice", Type: typ, Size: len(b), Limit: maxAttrDataLen}
 		return
 	}
 
-	ae.attrs = append(ae.attrs, Attribute{
+	ae.add(Attribute{
 		Type: typ,
 		Data: b,
 	})
 }
 
+// Attributes encodes each Attribute in attrs as-is, including any flags such
+// as Nested in the Type field. The Length of each Attribute must be zero or
+// match the length of its data plus the attribute header, as with attributes
+// returned by AttributeDecoder.Unknown; otherwise, Encode returns an error.
+//
+// Attributes is useful for re-encoding attributes which were not understood by
+// the caller, such as those returned by AttributeDecoder.Unknown, without any
+// loss of information.
+func (ae *AttributeEncoder) Attributes(attrs ...Attribute) {
+	if ae.err != nil {
+		return
+	}
+
+	for _, a := range attrs {
+		if len(a.Data) > maxAttrDataLen {
+			ae.err = &AttributeTooLargeError{Kind: "attribute data", Type: a.Type, Size: len(a.Data), Limit: maxAttrDataLen}
+			return
+		}
+
+		if l := nlaHeaderLen + len(a.Data); a.Length != 0 && int(a.Length) != l {
+			ae.err = fmt.Errorf("netlink: attribute %d has length %d, but its data requires length %d", a.Type, a.Length, l)
+			return
+		}
+	}
+
+	for _, a := range attrs {
+		ae.add(a)
+	}
+}
+
+// Map encodes each value in m as raw attribute data with the type specified by
+// its key, in ascending order of type. Keys may include flags such as Nested.
+//
+// Map is useful for generic tools such as proxies, fuzzers, and test fixtures
+// which manipulate attributes without knowledge of their schema. Callers which
+// must control the order of attributes should use Attributes instead.
+func (ae *AttributeEncoder) Map(m map[uint16][]byte) {
+	if ae.err != nil {
+		return
+	}
+
+	types := make([]int, 0, len(m))
+	for typ := range m {
+		types = append(types, int(typ))
+	}
+	sort.Ints(types)
+
+	for _, typ := range types {
+		ae.Bytes(uint16(typ), m[uint16(typ)])
+	}
+}
+
 // Do is a general purpose function to encode arbitrary data into an attribute
 // specified by typ.
 //
@@ -666,12 +1104,12 @@ func (ae *AttributeEncoder) Do(typ uint16, fn func() ([]byte, error)) {
 		return
 	}
 
-	if len(b) > math.MaxUint16-nlaHeaderLen {
-		ae.err = errors.New("byte slice produced by Do is too large to fit in a netlink attribute")
+	if len(b) > maxAttrDataLen {
+		ae.err = &AttributeTooLargeError{Kind: "byte slice produced by Do", Type: typ, Size: len(b), Limit: maxAttrDataLen}
 		return
 	}
 
-	ae.attrs = append(ae.attrs, Attribute{
+	ae.add(Attribute{
 		Type: typ,
 		Data: b,
 	})
@@ -681,13 +1119,16 @@ func (ae *AttributeEncoder) Do(typ uint16, fn func() ([]byte, error)) {
 // with the Nested flag. When calling Nested, the Encode method should not be
 // called on the nested AttributeEncoder.
 //
-// The nested AttributeEncoder nae inherits the same ByteOrder setting as the
-// top-level AttributeEncoder ae.
+// The nested AttributeEncoder nae inherits the same ByteOrder and Order
+// settings as the top-level AttributeEncoder ae.
 func (ae *AttributeEncoder) Nested(typ uint16, fn func(nae *AttributeEncoder) error) {
 	// Because we are wrapping Do, there is no need to check ae.err immediately.
 	ae.Do(Nested|typ, func() ([]byte, error) {
 		nae := NewAttributeEncoder()
 		nae.ByteOrder = ae.ByteOrder
+		nae.Order = ae.Order
+		// The nested attributes follow this attribute's header.
+		nae.Pad64Offset = ae.Pad64Offset + ae.size + nlaHeaderLen
 
 		if err := fn(nae); err != nil {
 			return nil, err
@@ -703,5 +1144,50 @@ func (ae *AttributeEncoder) Encode() ([]byte, error) {
 		return nil, ae.err
 	}
 
+	switch ae.Order {
+	case OrderStrict:
+		if err := ae.checkOrder(); err != nil {
+			return nil, err
+		}
+	case OrderSort:
+		if ae.Pad64 != 0 {
+			return nil, errSortPad64
+		}
+
+		attrs := append([]Attribute(nil), ae.attrs...)
+		sort.SliceStable(attrs, func(i, j int) bool {
+			return attrs[i].Type&attrTypeMask < attrs[j].Type&attrTypeMask
+		})
+
+		return MarshalAttributes(attrs)
+	}
+
 	return MarshalAttributes(ae.attrs)
 }
+
+// checkOrder verifies that the attributes are in ascending order of type,
+// ignoring any padding attributes inserted for Pad64.
+func (ae *AttributeEncoder) checkOrder() error {
+	var prev uint16
+	for _, a := range ae.attrs {
+		if ae.Pad64 != 0 && a.Type == ae.Pad64 && len(a.Data) == 0 {
+			continue
+		}
+
+		typ := a.Type & attrTypeMask
+		if typ < prev {
+			return &AttributeOrderError{Type: typ, Previous: prev}
+		}
+
+		prev = typ
+	}
+
+	return nil
+}
+
+// Size returns the length in bytes of the attributes which Encode would
+// produce, without encoding them. Size does not report errors; any error
+// which occurred while adding attributes is returned by Encode.
+func (ae *AttributeEncoder) Size() int {
+	return SizeAttributes(ae.attrs)
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"math"
 	"reflect"
 	"testing"
@@ -228,6 +229,10 @@ func TestMarshalAttributes(t *testing.T) {
 				t.Fatalf("unexpected bytes:\n- want: [%# x]\n-  got: [%# x]",
 					want, got)
 			}
+
+			if want, got := len(b), SizeAttributes(tt.attrs); want != got {
+				t.Fatalf("unexpected size:\n- want: %d\n-  got: %d", want, got)
+			}
 		})
 	}
 }
@@ -462,6 +467,24 @@ func TestAttributeDecoderError(t *testing.T) {
 				ad.Uint64()
 			},
 		},
+		{
+			name:  "counter64",
+			attrs: bad,
+			fn: func(ad *AttributeDecoder) {
+				ad.Counter64()
+				ad.Next()
+				ad.Counter64()
+			},
+		},
+		{
+			name:  "uint auto",
+			attrs: bad,
+			fn: func(ad *AttributeDecoder) {
+				ad.UintAuto()
+				ad.Next()
+				ad.UintAuto()
+			},
+		},
 		{
 			name:  "int8",
 			attrs: bad,
@@ -548,6 +571,106 @@ func TestAttributeDecoderError(t *testing.T) {
 	}
 }
 
+func TestAttributeDecoderNestedErrorPath(t *testing.T) {
+	skipBigEndian(t)
+
+	ae := NewAttributeEncoder()
+	ae.Nested(4, func(nae *AttributeEncoder) error {
+		nae.Nested(1, func(nnae *AttributeEncoder) error {
+			// Doesn't fit a uint32.
+			nnae.Bytes(2, []byte{0xe, 0xad, 0xbe})
+			return nil
+		})
+		return nil
+	})
+
+	b, err := ae.Encode()
+	if err != nil {
+		t.Fatalf("failed to encode attributes: %v", err)
+	}
+
+	ad, err := NewAttributeDecoder(b)
+	if err != nil {
+		t.Fatalf("failed to create attribute decoder: %v", err)
+	}
+
+	errNested := errors.New("nested error")
+
+	var inner error
+	for ad.Next() {
+		ad.Nested(func(nad *AttributeDecoder) error {
+			for nad.Next() {
+				nad.Nested(func(nnad *AttributeDecoder) error {
+					for nnad.Next() {
+						nnad.Uint32()
+					}
+
+					return nil
+				})
+			}
+
+			inner = nad.Err()
+			return nil
+		})
+	}
+
+	want := "attr 4 > 1: netlink: attribute 2 is not a uint32; length: 3"
+	if diff := cmp.Diff(want, ad.Err().Error()); diff != "" {
+		t.Fatalf("unexpected error (-want +got):\n%s", diff)
+	}
+
+	// The error already observed by the caller is not modified.
+	want = "attr 1: netlink: attribute 2 is not a uint32; length: 3"
+	if diff := cmp.Diff(want, inner.Error()); diff != "" {
+		t.Fatalf("unexpected inner error (-want +got):\n%s", diff)
+	}
+
+	// Errors returned by the caller are also wrapped, and can be unwrapped.
+	ad, err = NewAttributeDecoder(b)
+	if err != nil {
+		t.Fatalf("failed to create attribute decoder: %v", err)
+	}
+
+	for ad.Next() {
+		ad.Nested(func(_ *AttributeDecoder) error { return errNested })
+	}
+
+	if err := ad.Err(); !errors.Is(err, errNested) || err.Error() != "attr 4: nested error" {
+		t.Fatalf("unexpected nested error: %v", err)
+	}
+
+	// The path is rendered around the caller's annotation of a nested error.
+	ad, err = NewAttributeDecoder(b)
+	if err != nil {
+		t.Fatalf("failed to create attribute decoder: %v", err)
+	}
+
+	for ad.Next() {
+		ad.Nested(func(nad *AttributeDecoder) error {
+			for nad.Next() {
+				nad.Nested(func(nnad *AttributeDecoder) error {
+					for nnad.Next() {
+						nnad.Uint32()
+					}
+
+					return nil
+				})
+			}
+
+			if err := nad.Err(); err != nil {
+				return fmt.Errorf("decoding link: %w", err)
+			}
+
+			return nil
+		})
+	}
+
+	want = "attr 4: decoding link: attr 1: netlink: attribute 2 is not a uint32; length: 3"
+	if diff := cmp.Diff(want, ad.Err().Error()); diff != "" {
+		t.Fatalf("unexpected annotated error (-want +got):\n%s", diff)
+	}
+}
+
 func TestAttributeDecoderOK(t *testing.T) {
 	skipBigEndian(t)
 
@@ -977,6 +1100,18 @@ func TestAttributeEncoderError(t *testing.T) {
 				})
 			},
 		},
+		{
+			name: "map length",
+			fn: func(ae *AttributeEncoder) {
+				ae.Map(map[uint16][]byte{1: make([]byte, math.MaxUint16)})
+			},
+		},
+		{
+			name: "attributes length",
+			fn: func(ae *AttributeEncoder) {
+				ae.Attributes(Attribute{Type: 1, Data: make([]byte, math.MaxUint16)})
+			},
+		},
 		{
 			name: "do function",
 			fn: func(ae *AttributeEncoder) {
@@ -996,6 +1131,22 @@ func TestAttributeEncoderError(t *testing.T) {
 			if err == nil {
 				t.Fatal("expected an error, but none occurred")
 			}
+
+			var terr *AttributeTooLargeError
+			if !errors.As(err, &terr) {
+				return
+			}
+
+			want := &AttributeTooLargeError{
+				Kind:  terr.Kind,
+				Type:  1,
+				Size:  math.MaxUint16,
+				Limit: math.MaxUint16 - nlaHeaderLen,
+			}
+
+			if diff := cmp.Diff(want, terr); diff != "" {
+				t.Fatalf("unexpected error (-want +got):\n%s", diff)
+			}
 		})
 	}
 }
@@ -1068,6 +1219,29 @@ func TestAttributeEncoderOK(t *testing.T) {
 				ae.Bytes(1, []byte{0xde, 0xad})
 			},
 		},
+		{
+			name: "map",
+			attrs: []Attribute{
+				{
+					Type: 1,
+					Data: []byte{0xde, 0xad},
+				},
+				{
+					Type: 2,
+				},
+				{
+					Type: Nested | 3,
+					Data: []byte{0xbe, 0xef},
+				},
+			},
+			fn: func(ae *AttributeEncoder) {
+				ae.Map(map[uint16][]byte{
+					Nested | 3: {0xbe, 0xef},
+					2:          nil,
+					1:          {0xde, 0xad},
+				})
+			},
+		},
 		{
 			name: "do",
 			attrs: []Attribute{
@@ -1166,6 +1340,10 @@ func TestAttributeEncoderOK(t *testing.T) {
 			if diff := cmp.Diff(got, b); diff != "" {
 				t.Fatalf("unexpected attribute encoding (-want +got):\n%s", diff)
 			}
+
+			if diff := cmp.Diff(len(got), ae.Size()); diff != "" {
+				t.Fatalf("unexpected attribute size (-want +got):\n%s", diff)
+			}
 		})
 	}
 }
@@ -1184,3 +1362,474 @@ func aeEndianTest(order binary.ByteOrder) func(ae *AttributeEncoder) {
 		ae.Int64(8, int64(8))
 	}
 }
+
+func TestAttributeEncoderDecoderPad64(t *testing.T) {
+	skipBigEndian(t)
+
+	const (
+		typU8  = 1
+		typU64 = 2
+		typI64 = 3
+		typPad = 4
+	)
+
+	ae := NewAttributeEncoder()
+	ae.Pad64 = typPad
+	ae.Uint8(typU8, 1)
+	// Offset 8: value at offset 12 requires padding.
+	ae.Uint64(typU64, 2)
+	// Offset 24: value at offset 28 requires padding.
+	ae.Int64(typI64, -1)
+
+	b, err := ae.Encode()
+	if err != nil {
+		t.Fatalf("failed to encode attributes: %v", err)
+	}
+
+	want := []Attribute{
+		{Length: 5, Type: typU8, Data: []byte{1}},
+		{Length: 4, Type: typPad, Data: []byte{}},
+		{Length: 12, Type: typU64, Data: []byte{2, 0, 0, 0, 0, 0, 0, 0}},
+		{Length: 4, Type: typPad, Data: []byte{}},
+		{Length: 12, Type: typI64, Data: bytes.Repeat([]byte{0xff}, 8)},
+	}
+
+	attrs, err := UnmarshalAttributes(b)
+	if err != nil {
+		t.Fatalf("failed to unmarshal attributes: %v", err)
+	}
+
+	if diff := cmp.Diff(want, attrs); diff != "" {
+		t.Fatalf("unexpected attributes (-want +got):\n%s", diff)
+	}
+
+	// Each 64-bit value must begin on an 8-byte boundary.
+	if diff := cmp.Diff(want[2].Data, b[16:24]); diff != "" {
+		t.Fatalf("unexpected uint64 value bytes (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(want[4].Data, b[32:40]); diff != "" {
+		t.Fatalf("unexpected int64 value bytes (-want +got):\n%s", diff)
+	}
+
+	ad, err := NewAttributeDecoder(b)
+	if err != nil {
+		t.Fatalf("failed to create attribute decoder: %v", err)
+	}
+	ad.Pad64 = typPad
+
+	var types []uint16
+	for ad.Next() {
+		types = append(types, ad.Type())
+	}
+	if err := ad.Err(); err != nil {
+		t.Fatalf("failed to decode attributes: %v", err)
+	}
+
+	if diff := cmp.Diff([]uint16{typU8, typU64, typI64}, types); diff != "" {
+		t.Fatalf("unexpected attribute types (-want +got):\n%s", diff)
+	}
+}
+
+func TestAttributeEncoderPad64Offset(t *testing.T) {
+	skipBigEndian(t)
+
+	const (
+		typU64    = 1
+		typPad    = 2
+		typNested = 3
+	)
+
+	// The attributes follow a 4 byte family header, such as genlmsghdr.
+	ae := NewAttributeEncoder()
+	ae.Pad64 = typPad
+	ae.Pad64Offset = 4
+	// Offset 4: value at offset 8 requires no padding.
+	ae.Uint64(typU64, 1)
+	ae.Nested(typNested, func(nae *AttributeEncoder) error {
+		// Offset 20: value at offset 24 requires no padding, although it
+		// would relative to the start of the nested attributes.
+		nae.Pad64 = typPad
+		nae.Uint64(typU64, 2)
+		return nil
+	})
+
+	b, err := ae.Encode()
+	if err != nil {
+		t.Fatalf("failed to encode attributes: %v", err)
+	}
+
+	// Prepend the family header to check alignment within the message data.
+	data := append([]byte{0xff, 0xff, 0xff, 0xff}, b...)
+
+	want := []Attribute{
+		{Length: 12, Type: typU64, Data: []byte{1, 0, 0, 0, 0, 0, 0, 0}},
+		{Length: 16, Type: Nested | typNested, Data: mustMarshalAttributes([]Attribute{
+			{Type: typU64, Data: []byte{2, 0, 0, 0, 0, 0, 0, 0}},
+		})},
+	}
+
+	attrs, err := UnmarshalAttributes(data[4:])
+	if err != nil {
+		t.Fatalf("failed to unmarshal attributes: %v", err)
+	}
+
+	if diff := cmp.Diff(want, attrs); diff != "" {
+		t.Fatalf("unexpected attributes (-want +got):\n%s", diff)
+	}
+
+	// Each 64-bit value must begin on an 8-byte boundary.
+	if diff := cmp.Diff(want[0].Data, data[8:16]); diff != "" {
+		t.Fatalf("unexpected uint64 value bytes (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]byte{2, 0, 0, 0, 0, 0, 0, 0}, data[24:32]); diff != "" {
+		t.Fatalf("unexpected nested uint64 value bytes (-want +got):\n%s", diff)
+	}
+}
+
+func TestAttributeDecoderCounter64(t *testing.T) {
+	b, err := MarshalAttributes([]Attribute{
+		{Type: 1, Data: []byte{0xff, 0xff, 0xff, 0xff}},
+		{Type: 2, Data: []byte{0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00}},
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal attributes: %v", err)
+	}
+
+	ad, err := NewAttributeDecoder(b)
+	if err != nil {
+		t.Fatalf("failed to create attribute decoder: %v", err)
+	}
+	ad.ByteOrder = binary.BigEndian
+
+	var got []uint64
+	for ad.Next() {
+		got = append(got, ad.Counter64())
+	}
+	if err := ad.Err(); err != nil {
+		t.Fatalf("failed to decode attributes: %v", err)
+	}
+
+	if diff := cmp.Diff([]uint64{0xffffffff, 0x100000000}, got); diff != "" {
+		t.Fatalf("unexpected counters (-want +got):\n%s", diff)
+	}
+}
+
+func TestAttributeDecoderUintAuto(t *testing.T) {
+	b, err := MarshalAttributes([]Attribute{
+		{Type: 1, Data: []byte{0xff}},
+		{Type: 2, Data: []byte{0x01, 0x00}},
+		{Type: 3 | NetByteOrder, Data: []byte{0x00, 0x01}},
+		{Type: 4, Data: []byte{0x01, 0x00, 0x00, 0x00}},
+		{Type: 5 | NetByteOrder, Data: []byte{0x00, 0x00, 0x00, 0x01}},
+		{Type: 6, Data: []byte{0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}},
+		{Type: 7 | NetByteOrder, Data: []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01}},
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal attributes: %v", err)
+	}
+
+	ad, err := NewAttributeDecoder(b)
+	if err != nil {
+		t.Fatalf("failed to create attribute decoder: %v", err)
+	}
+	ad.ByteOrder = binary.LittleEndian
+
+	var got []uint64
+	for ad.Next() {
+		got = append(got, ad.UintAuto())
+	}
+	if err := ad.Err(); err != nil {
+		t.Fatalf("failed to decode attributes: %v", err)
+	}
+
+	if diff := cmp.Diff([]uint64{0xff, 1, 1, 1, 1, 1, 1}, got); diff != "" {
+		t.Fatalf("unexpected values (-want +got):\n%s", diff)
+	}
+}
+
+func TestSortAttributes(t *testing.T) {
+	attrs := []Attribute{
+		{Type: 3, Data: []byte{0}},
+		{Type: 1 | Nested, Data: []byte{1}},
+		{Type: 2},
+		{Type: 1, Data: []byte{2}},
+	}
+
+	SortAttributes(attrs)
+
+	// Flags are ignored and attributes with equal types retain their order.
+	want := []Attribute{
+		{Type: 1 | Nested, Data: []byte{1}},
+		{Type: 1, Data: []byte{2}},
+		{Type: 2},
+		{Type: 3, Data: []byte{0}},
+	}
+
+	if diff := cmp.Diff(want, attrs); diff != "" {
+		t.Fatalf("unexpected attributes (-want +got):\n%s", diff)
+	}
+}
+
+func TestNormalizeAttributes(t *testing.T) {
+	// The nested attributes have stale lengths and excess trailing padding.
+	nested := []byte{
+		0x05, 0x00, 0x01, 0x00, 0xff, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+	}
+
+	got, err := NormalizeAttributes([]Attribute{
+		{Length: 100, Type: 1, Data: []byte{}},
+		{Type: 2 | Nested, Data: nested},
+		{Type: 3, Data: []byte{0xff}},
+	})
+	if err != nil {
+		t.Fatalf("failed to normalize attributes: %v", err)
+	}
+
+	want := []Attribute{
+		{Length: 4, Type: 1},
+		{
+			Length: 12,
+			Type:   2 | Nested,
+			Data:   []byte{0x05, 0x00, 0x01, 0x00, 0xff, 0x00, 0x00, 0x00},
+		},
+		{Length: 5, Type: 3, Data: []byte{0xff}},
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("unexpected attributes (-want +got):\n%s", diff)
+	}
+
+	if _, err := NormalizeAttributes([]Attribute{{Type: Nested, Data: []byte{0xff}}}); err == nil {
+		t.Fatal("expected an error for malformed nested attributes, but none occurred")
+	}
+}
+
+func TestAttributeDecoderCollectUnknown(t *testing.T) {
+	skipBigEndian(t)
+
+	attrs := []Attribute{
+		{Length: 5, Type: 1, Data: []byte{1}},
+		{Length: 6, Type: 2, Data: []byte{0xde, 0xad}},
+		{Length: 4, Type: Nested | 3, Data: []byte{}},
+		{Length: 8, Type: 4, Data: []byte{4, 0, 0, 0}},
+		{Length: 5, Type: 5, Data: []byte{5}},
+	}
+
+	b, err := MarshalAttributes(attrs)
+	if err != nil {
+		t.Fatalf("failed to marshal attributes: %v", err)
+	}
+
+	ad, err := NewAttributeDecoder(b)
+	if err != nil {
+		t.Fatalf("failed to create attribute decoder: %v", err)
+	}
+	ad.CollectUnknown = true
+
+	for ad.Next() {
+		// Only types 1 and 4 are understood by the caller.
+		switch ad.Type() {
+		case 1:
+			_ = ad.Uint8()
+		case 4:
+			_ = ad.Uint32()
+		}
+	}
+	if err := ad.Err(); err != nil {
+		t.Fatalf("failed to decode attributes: %v", err)
+	}
+
+	want := []Attribute{attrs[1], attrs[2], attrs[4]}
+	if diff := cmp.Diff(want, ad.Unknown()); diff != "" {
+		t.Fatalf("unexpected unknown attributes (-want +got):\n%s", diff)
+	}
+}
+
+func TestAttributeEncoderAttributesRoundTrip(t *testing.T) {
+	skipBigEndian(t)
+
+	b, err := MarshalAttributes([]Attribute{
+		{Type: 1, Data: []byte{1}},
+		{Type: Nested | 2, Data: []byte{0x05, 0x00, 0x01, 0x00, 0xff, 0x00, 0x00, 0x00}},
+		{Type: NetByteOrder | 3, Data: []byte{0x00, 0x01}},
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal attributes: %v", err)
+	}
+
+	ad, err := NewAttributeDecoder(b)
+	if err != nil {
+		t.Fatalf("failed to create attribute decoder: %v", err)
+	}
+	ad.CollectUnknown = true
+
+	for ad.Next() {
+	}
+	if err := ad.Err(); err != nil {
+		t.Fatalf("failed to decode attributes: %v", err)
+	}
+
+	ae := NewAttributeEncoder()
+	ae.Attributes(ad.Unknown()...)
+
+	out, err := ae.Encode()
+	if err != nil {
+		t.Fatalf("failed to encode attributes: %v", err)
+	}
+
+	if diff := cmp.Diff(b, out); diff != "" {
+		t.Fatalf("unexpected re-encoded attributes (-want +got):\n%s", diff)
+	}
+}
+
+func TestAttributeEncoderAttributesLength(t *testing.T) {
+	skipBigEndian(t)
+
+	tests := []struct {
+		name string
+		a    Attribute
+		ok   bool
+	}{
+		{
+			name: "zero",
+			a:    Attribute{Type: 1, Data: []byte{0xff}},
+			ok:   true,
+		},
+		{
+			name: "exact",
+			a:    Attribute{Length: 5, Type: 1, Data: []byte{0xff}},
+			ok:   true,
+		},
+		{
+			name: "short",
+			a:    Attribute{Length: 4, Type: 1, Data: []byte{0xff}},
+		},
+		{
+			name: "long",
+			a:    Attribute{Length: 8, Type: 1, Data: []byte{0xff}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ae := NewAttributeEncoder()
+			ae.Attributes(tt.a)
+
+			_, err := ae.Encode()
+			if tt.ok && err != nil {
+				t.Fatalf("failed to encode attributes: %v", err)
+			}
+			if !tt.ok && err == nil {
+				t.Fatal("expected an error, but none occurred")
+			}
+		})
+	}
+}
+
+// mustMarshalAttributes marshals attrs for use as nested attribute data.
+func mustMarshalAttributes(attrs []Attribute) []byte {
+	b, err := MarshalAttributes(attrs)
+	if err != nil {
+		panicf("failed to marshal attributes: %v", err)
+	}
+
+	return b
+}
+
+func TestAttributeEncoderOrder(t *testing.T) {
+	skipBigEndian(t)
+
+	// add adds attributes out of order, with a repeated type and a nested
+	// attribute which is also out of order.
+	add := func(ae *AttributeEncoder) {
+		ae.Uint8(2, 1)
+		ae.Uint8(1, 1)
+		ae.Nested(3, func(nae *AttributeEncoder) error {
+			nae.Uint8(2, 2)
+			nae.Uint8(1, 2)
+			return nil
+		})
+		ae.Uint8(2, 3)
+	}
+
+	tests := []struct {
+		name  string
+		order AttributeOrder
+		attrs []Attribute
+		err   error
+	}{
+		{
+			name:  "as is",
+			order: OrderAsIs,
+			attrs: []Attribute{
+				{Type: 2, Data: []byte{1}},
+				{Type: 1, Data: []byte{1}},
+				{Type: Nested | 3, Data: mustMarshalAttributes([]Attribute{
+					{Type: 2, Data: []byte{2}},
+					{Type: 1, Data: []byte{2}},
+				})},
+				{Type: 2, Data: []byte{3}},
+			},
+		},
+		{
+			name:  "strict",
+			order: OrderStrict,
+			err:   &AttributeOrderError{Type: 1, Previous: 2},
+		},
+		{
+			name:  "sort",
+			order: OrderSort,
+			attrs: []Attribute{
+				{Type: 1, Data: []byte{1}},
+				{Type: 2, Data: []byte{1}},
+				{Type: 2, Data: []byte{3}},
+				{Type: Nested | 3, Data: mustMarshalAttributes([]Attribute{
+					{Type: 1, Data: []byte{2}},
+					{Type: 2, Data: []byte{2}},
+				})},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ae := NewAttributeEncoder()
+			ae.Order = tt.order
+			add(ae)
+
+			b, err := ae.Encode()
+			if diff := cmp.Diff(tt.err, err, cmp.Comparer(func(x, y error) bool {
+				return x.Error() == y.Error()
+			})); diff != "" {
+				t.Fatalf("unexpected error (-want +got):\n%s", diff)
+			}
+			if err != nil {
+				return
+			}
+
+			if diff := cmp.Diff(mustMarshalAttributes(tt.attrs), b); diff != "" {
+				t.Fatalf("unexpected attributes (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAttributeEncoderOrderPad64(t *testing.T) {
+	ae := NewAttributeEncoder()
+	ae.Pad64 = 10
+	ae.Order = OrderStrict
+
+	// Padding attributes are ignored when checking order.
+	ae.Uint8(1, 1)
+	ae.Uint64(2, 1)
+	ae.Uint64(3, 1)
+	if _, err := ae.Encode(); err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+
+	ae.Order = OrderSort
+	if _, err := ae.Encode(); !errors.Is(err, errSortPad64) {
+		t.Fatalf("expected sort with Pad64 error, but got: %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package netlink
+
+import (
+	"context"
+	"time"
+)
+
+// A Clock provides the current time and timers to a Conn. A Clock can be set
+// using Config.Clock to test time-dependent behavior, such as
+// Config.NoReplyTimeout, deterministically.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// After waits for duration d to elapse and then sends the current time
+	// on the returned channel.
+	After(d time.Duration) <-chan time.Time
+}
+
+// now returns the current time according to the Conn's Clock.
+func (c *Conn) now() time.Time {
+	if c.clock == nil {
+		return time.Now()
+	}
+
+	return c.clock.Now()
+}
+
+// after is time.After according to the Conn's Clock.
+func (c *Conn) after(d time.Duration) <-chan time.Time {
+	if c.clock == nil {
+		return time.After(d)
+	}
+
+	return c.clock.After(d)
+}
+
+// replyTimer returns a child context of parent which is canceled when d
+// elapses according to the Conn's Clock, for Sockets which do not support
+// deadlines. The returned function reports whether the timer fired, and must
+// be called to release resources once the operation is complete.
+func (c *Conn) replyTimer(parent context.Context, d time.Duration) (context.Context, func() bool) {
+	ctx, cancel := context.WithCancel(parent)
+	fired := make(chan struct{})
+
+	expire := func() {
+		close(fired)
+		cancel()
+	}
+
+	timer := c.after(d)
+	select {
+	case <-timer:
+		// Expire immediately so the Socket is not called at all.
+		expire()
+	default:
+		go func() {
+			select {
+			case <-timer:
+				expire()
+			case <-ctx.Done():
+			}
+		}()
+	}
+
+	return ctx, func() bool {
+		cancel()
+
+		select {
+		case <-fired:
+			return true
+		default:
+			return false
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package netlink_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nltest"
+)
+
+func TestConnClockNoReplyTimeout(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1, 0)}
+
+	restore := nltest.Intercept(func(req []netlink.Message) ([]netlink.Message, error) {
+		if len(req) == 0 {
+			t.Fatal("the socket should not receive after the timer fires")
+		}
+
+		// No reply to the request.
+		return nil, nil
+	})
+	defer restore()
+
+	// Dial through the nltest interceptor so the Config options apply.
+	c, err := netlink.Dial(0, &netlink.Config{
+		NoReplyTimeout: time.Second,
+		Clock:          clock,
+	})
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer c.Close()
+
+	var tx netlink.Transaction
+	c.SetObserver(&netlink.Observer{
+		Execute: func(t netlink.Transaction) { tx = t },
+	})
+
+	_, err = c.Execute(netlink.Message{Header: netlink.Header{Flags: netlink.Request}})
+	nerr, ok := err.(net.Error)
+	if !ok || !nerr.Timeout() {
+		t.Fatalf("expected timeout error, but got: %v", err)
+	}
+
+	if diff := cmp.Diff(clock.now, tx.Start); diff != "" {
+		t.Fatalf("unexpected transaction start (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(clock.now, tx.End); diff != "" {
+		t.Fatalf("unexpected transaction end (-want +got):\n%s", diff)
+	}
+}
+
+func TestConnClockDeadline(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1, 0)}
+	sock := &deadlineSocket{}
+
+	prev := netlink.SetDialInterceptor(func(_ int, _ *netlink.Config) (netlink.Socket, uint32, error) {
+		return sock, nltest.PID, nil
+	})
+	defer netlink.SetDialInterceptor(prev)
+
+	c, err := netlink.Dial(0, &netlink.Config{
+		NoReplyTimeout: time.Second,
+		Clock:          clock,
+	})
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer c.Close()
+
+	_, err = c.Execute(netlink.Message{Header: netlink.Header{Flags: netlink.Request}})
+	nerr, ok := err.(net.Error)
+	if !ok || !nerr.Timeout() {
+		t.Fatalf("expected timeout error, but got: %v", err)
+	}
+
+	// The Clock's time must never be used for the Socket's read deadline, so
+	// the timeout is measured using the Clock instead.
+	if len(sock.deadlines) != 0 {
+		t.Fatalf("unexpected read deadlines: %v", sock.deadlines)
+	}
+}
+
+func TestConnClockDumpTimeout(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1, 0)}
+
+	restore := nltest.Intercept(func(req []netlink.Message) ([]netlink.Message, error) {
+		if len(req) == 0 {
+			t.Fatal("the socket should not receive after the timer fires")
+		}
+
+		// Begin a multi-part message but never finish it. nltest returns the
+		// final message in a separate datagram, which is never received.
+		h := req[0].Header
+		h.Flags = netlink.Multi
+
+		return []netlink.Message{
+			{Header: h, Data: []byte{0xff, 0xff, 0xff, 0xff}},
+			{Header: h},
+		}, nil
+	})
+	defer restore()
+
+	c, err := netlink.Dial(0, &netlink.Config{
+		DumpTimeout: time.Second,
+		Clock:       clock,
+	})
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer c.Close()
+
+	msgs, err := c.Execute(netlink.Message{
+		Header: netlink.Header{Flags: netlink.Request | netlink.Dump},
+	})
+	if !errors.Is(err, netlink.ErrDumpTimeout) {
+		t.Fatalf("expected dump timeout error, but got: %v", err)
+	}
+
+	// The partial results are returned along with the error.
+	if diff := cmp.Diff([]byte{0xff, 0xff, 0xff, 0xff}, msgs[0].Data); len(msgs) != 1 || diff != "" {
+		t.Fatalf("unexpected partial messages (-want +got):\n%s", diff)
+	}
+}
+
+// fakeClock is a netlink.Clock which always reports the same time and whose
+// timers fire immediately.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) After(_ time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+// deadlineSocket is a netlink.Socket which records read deadlines and replies
+// to each request with an empty message.
+type deadlineSocket struct {
+	req       netlink.Message
+	deadlines []time.Time
+}
+
+func (s *deadlineSocket) Close() error                           { return nil }
+func (s *deadlineSocket) SendMessages(_ []netlink.Message) error { panic("unimplemented") }
+func (s *deadlineSocket) Send(m netlink.Message) error           { s.req = m; return nil }
+func (s *deadlineSocket) SetDeadline(_ time.Time) error          { panic("unimplemented") }
+func (s *deadlineSocket) SetWriteDeadline(_ time.Time) error     { panic("unimplemented") }
+func (s *deadlineSocket) Receive() ([]netlink.Message, error) {
+	return []netlink.Message{{Header: s.req.Header}}, nil
+}
+func (s *deadlineSocket) SetReadDeadline(t time.Time) error {
+	s.deadlines = append(s.deadlines, t)
+	return nil
+}
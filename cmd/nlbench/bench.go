@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mdlayher/netlink"
+)
+
+// A scenario is a canned benchmark which runs against the local kernel.
+type scenario struct {
+	name string
+	run  func(cfg config) (result, error)
+}
+
+// scenarios are all of the available scenarios, in the order they are
+// printed.
+var scenarios = []scenario{
+	{name: "ack", run: runAck},
+	{name: "dump", run: runDump},
+	{name: "flood", run: runFlood},
+}
+
+// lookup returns the scenario with the specified name.
+func lookup(name string) (scenario, bool) {
+	for _, s := range scenarios {
+		if s.name == name {
+			return s, true
+		}
+	}
+
+	return scenario{}, false
+}
+
+// config contains parameters which apply to each scenario.
+type config struct {
+	duration   time.Duration
+	readBuffer int
+	size       int
+}
+
+// environment describes the system on which the scenarios were run.
+type environment struct {
+	kernel     string
+	goos       string
+	goarch     string
+	gomaxprocs int
+}
+
+// A result is the outcome of a single scenario.
+type result struct {
+	name string
+
+	// ops is the number of operations performed, such as requests sent or
+	// datagrams received, and lat is the latency of each operation when
+	// measured.
+	ops int
+	lat []time.Duration
+
+	// msgs and bytes are the number of messages and bytes received, and
+	// overruns is the number of times the kernel discarded messages.
+	msgs, bytes uint64
+	overruns    uint64
+
+	elapsed time.Duration
+}
+
+// Constants from linux/rtnetlink.h, which are spelled out so nlbench builds
+// on all platforms.
+const (
+	rtmGetRoute = 26
+	sizeofRtmsg = 12
+)
+
+// runAck measures the latency of generic netlink requests which are answered
+// only by an acknowledgement. The kernel acknowledges netlink control
+// messages such as Noop without passing them to a family, so this measures the
+// overhead of netlink itself.
+func runAck(cfg config) (result, error) {
+	c, err := netlink.Dial(int(netlink.FamilyGeneric), nil)
+	if err != nil {
+		return result{}, err
+	}
+	defer c.Close()
+
+	req := netlink.Message{
+		Header: netlink.Header{
+			Type:  netlink.Noop,
+			Flags: netlink.Request | netlink.Acknowledge,
+		},
+	}
+
+	return runRequests("ack", c, req, cfg)
+}
+
+// runDump measures the latency and throughput of dumps of the full routing
+// table.
+func runDump(cfg config) (result, error) {
+	c, err := netlink.Dial(int(netlink.FamilyRoute), nil)
+	if err != nil {
+		return result{}, err
+	}
+	defer c.Close()
+
+	req := netlink.Message{
+		Header: netlink.Header{
+			Type:  rtmGetRoute,
+			Flags: netlink.Request | netlink.Dump,
+		},
+		// An empty rtmsg with AF_UNSPEC family requests all routes.
+		Data: make([]byte, sizeofRtmsg),
+	}
+
+	return runRequests("dump", c, req, cfg)
+}
+
+// runRequests executes req using c repeatedly for cfg.duration.
+func runRequests(name string, c *netlink.Conn, req netlink.Message, cfg config) (result, error) {
+	r := result{name: name}
+
+	start := time.Now()
+	for time.Since(start) < cfg.duration {
+		ostart := time.Now()
+		if _, err := c.Execute(req); err != nil {
+			return result{}, err
+		}
+
+		r.ops++
+		r.lat = append(r.lat, time.Since(ostart))
+	}
+	r.elapsed = time.Since(start)
+
+	s := c.Stats()
+	r.msgs, r.bytes, r.overruns = s.MessagesReceived, s.BytesReceived, s.Overruns
+	return r, nil
+}
+
+// percentile returns the pth percentile of lat, where p is in the range
+// (0, 1], or 0 if lat is empty.
+func percentile(lat []time.Duration, p float64) time.Duration {
+	if len(lat) == 0 {
+		return 0
+	}
+
+	s := make([]time.Duration, len(lat))
+	copy(s, lat)
+	sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+
+	i := int(math.Ceil(p*float64(len(s)))) - 1
+	if i < 0 {
+		i = 0
+	}
+
+	return s[i]
+}
+
+// printResults writes a table of results to w.
+func printResults(w io.Writer, env environment, results []result) error {
+	_, err := fmt.Fprintf(w, "kernel: %s, platform: %s/%s, GOMAXPROCS: %d\n\n",
+		env.kernel, env.goos, env.goarch, env.gomaxprocs)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "scenario\tops\tops/s\tmsgs/s\tMiB/s\tp50\tp99\toverruns\t")
+
+	for _, r := range results {
+		secs := r.elapsed.Seconds()
+		if secs == 0 {
+			secs = math.SmallestNonzeroFloat64
+		}
+
+		p50, p99 := "-", "-"
+		if len(r.lat) > 0 {
+			p50 = percentile(r.lat, 0.50).String()
+			p99 = percentile(r.lat, 0.99).String()
+		}
+
+		fmt.Fprintf(tw, "%s\t%d\t%.0f\t%.0f\t%.2f\t%s\t%s\t%d\t\n",
+			r.name,
+			r.ops,
+			float64(r.ops)/secs,
+			float64(r.msgs)/secs,
+			float64(r.bytes)/secs/(1<<20),
+			p50,
+			p99,
+			r.overruns,
+		)
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,90 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/mdlayher/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// floodGroup is the user socket multicast group used by the flood scenario.
+const floodGroup = 1
+
+// runFlood measures how quickly a Conn can ingest messages which another
+// socket sends to a multicast group as quickly as possible. Each operation is
+// a received datagram, and overruns indicate that the receive buffer is too
+// small to absorb the flood.
+func runFlood(cfg config) (result, error) {
+	c, err := netlink.Dial(int(netlink.FamilyUserSock), &netlink.Config{
+		JoinGroups: []uint32{floodGroup},
+		ReadBuffer: cfg.readBuffer,
+		// Keep receiving after an overrun, which is counted by Stats.
+		OnOverrun: func() {},
+	})
+	if err != nil {
+		return result{}, err
+	}
+	defer c.Close()
+
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_USERSOCK)
+	if err != nil {
+		return result{}, os.NewSyscallError("socket", err)
+	}
+	defer unix.Close(fd)
+
+	b, err := netlink.Message{
+		Header: netlink.Header{
+			Length: uint32(netlink.HeaderLen + cfg.size),
+			Type:   0x10,
+		},
+		Data: make([]byte, cfg.size),
+	}.MarshalBinary()
+	if err != nil {
+		return result{}, err
+	}
+
+	start := time.Now()
+	deadline := start.Add(cfg.duration)
+	if err := c.SetReadDeadline(deadline); err != nil {
+		return result{}, err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		to := &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: 1 << (floodGroup - 1)}
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			// Errors such as ENOBUFS are expected during a flood.
+			_ = unix.Sendto(fd, b, 0, to)
+		}
+	}()
+
+	r := result{name: "flood"}
+	for {
+		if _, err := c.Receive(); err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				break
+			}
+
+			return result{}, err
+		}
+
+		r.ops++
+	}
+	r.elapsed = time.Since(start)
+
+	s := c.Stats()
+	r.msgs, r.bytes, r.overruns = s.MessagesReceived, s.BytesReceived, s.Overruns
+	return r, nil
+}
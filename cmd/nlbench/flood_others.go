@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// runFlood is not supported on this platform.
+func runFlood(_ config) (result, error) {
+	return result{}, fmt.Errorf("flood is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,82 @@
+// Command nlbench runs canned throughput and latency benchmarks against the
+// local kernel's netlink implementation and prints the results in a table,
+// so results can be compared across machines and kernel versions. nlbench can
+// help to size socket buffers and to verify performance regressions.
+//
+// Available scenarios are:
+//
+//	ack    generic netlink request and acknowledgement ping-pong
+//	dump   route netlink dumps of the full routing table
+//	flood  ingestion of a flood of multicast messages (Linux only)
+//
+// By default, each scenario runs for 2 seconds. As an example, to run the ack
+// and flood scenarios for 5 seconds each with a 4 MiB receive buffer:
+//
+//	nlbench -scenarios ack,flood -duration 5s -rbuf 4194304
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+)
+
+func main() {
+	var (
+		names    = flag.String("scenarios", "ack,dump,flood", "comma-separated list of scenarios to run")
+		duration = flag.Duration("duration", 2*time.Second, "amount of time to run each scenario")
+		rbuf     = flag.Int("rbuf", 0, "receive buffer size in bytes for the flood scenario, or 0 for the default")
+		size     = flag.Int("size", 256, "data size in bytes of each message sent by the flood scenario")
+	)
+	flag.Parse()
+
+	var run []scenario
+	for _, name := range strings.Split(*names, ",") {
+		s, ok := lookup(name)
+		if !ok {
+			log.Fatalf("nlbench: unknown scenario %q", name)
+		}
+
+		run = append(run, s)
+	}
+
+	cfg := config{
+		duration:   *duration,
+		readBuffer: *rbuf,
+		size:       *size,
+	}
+
+	env := environment{
+		kernel:     kernelRelease(),
+		goos:       runtime.GOOS,
+		goarch:     runtime.GOARCH,
+		gomaxprocs: runtime.GOMAXPROCS(0),
+	}
+
+	var results []result
+	for _, s := range run {
+		r, err := s.run(cfg)
+		if err != nil {
+			log.Fatalf("nlbench: %s: %v", s.name, err)
+		}
+
+		results = append(results, r)
+	}
+
+	if err := printResults(os.Stdout, env, results); err != nil {
+		log.Fatalf("nlbench: failed to print results: %v", err)
+	}
+}
+
+// kernelRelease returns the release of the running kernel, or "unknown".
+func kernelRelease() string {
+	b, err := os.ReadFile("/proc/sys/kernel/osrelease")
+	if err != nil {
+		return "unknown"
+	}
+
+	return strings.TrimSpace(string(b))
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPercentile(t *testing.T) {
+	var lat []time.Duration
+	for i := 100; i > 0; i-- {
+		lat = append(lat, time.Duration(i)*time.Millisecond)
+	}
+
+	tests := []struct {
+		p    float64
+		want time.Duration
+	}{
+		{p: 0.01, want: 1 * time.Millisecond},
+		{p: 0.50, want: 50 * time.Millisecond},
+		{p: 0.99, want: 99 * time.Millisecond},
+		{p: 1, want: 100 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		if diff := cmp.Diff(tt.want, percentile(lat, tt.p)); diff != "" {
+			t.Fatalf("unexpected percentile %v (-want +got):\n%s", tt.p, diff)
+		}
+	}
+
+	// The input must not be reordered.
+	if lat[0] != 100*time.Millisecond {
+		t.Fatal("percentile modified its input")
+	}
+
+	if got := percentile(nil, 0.5); got != 0 {
+		t.Fatalf("expected 0 for no latencies, but got: %v", got)
+	}
+}
+
+func TestPrintResults(t *testing.T) {
+	env := environment{
+		kernel:     "6.1.0",
+		goos:       "linux",
+		goarch:     "amd64",
+		gomaxprocs: 4,
+	}
+
+	results := []result{
+		{
+			name:    "ack",
+			ops:     200,
+			lat:     []time.Duration{time.Millisecond, 2 * time.Millisecond},
+			msgs:    200,
+			bytes:   2 << 20,
+			elapsed: 2 * time.Second,
+		},
+		{
+			name:     "flood",
+			ops:      10,
+			msgs:     10,
+			overruns: 3,
+			elapsed:  time.Second,
+		},
+	}
+
+	var b bytes.Buffer
+	if err := printResults(&b, env, results); err != nil {
+		t.Fatalf("failed to print results: %v", err)
+	}
+
+	want := strings.Join([]string{
+		"kernel: 6.1.0, platform: linux/amd64, GOMAXPROCS: 4",
+		"",
+		"  scenario  ops  ops/s  msgs/s  MiB/s  p50  p99  overruns",
+		"       ack  200    100     100   1.00  1ms  2ms         0",
+		"     flood   10     10      10   0.00    -    -         3",
+		"",
+	}, "\n")
+
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Fatalf("unexpected output (-want +got):\n%s", diff)
+	}
+}
+
+func TestScenarios(t *testing.T) {
+	for _, name := range []string{"ack", "dump", "flood"} {
+		t.Run(name, func(t *testing.T) {
+			s, ok := lookup(name)
+			if !ok {
+				t.Fatalf("unknown scenario %q", name)
+			}
+
+			r, err := s.run(config{duration: 100 * time.Millisecond, size: 16})
+			if err != nil {
+				t.Skipf("skipping, failed to run scenario: %v", err)
+			}
+
+			if r.name != name || r.ops == 0 || r.elapsed == 0 {
+				t.Fatalf("unexpected result: %+v", r)
+			}
+		})
+	}
+
+	if _, ok := lookup("foo"); ok {
+		t.Fatal("expected unknown scenario")
+	}
+}
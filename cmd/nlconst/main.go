@@ -0,0 +1,371 @@
+// Command nlconst generates Go constants and a String method from the enums
+// in Linux kernel UAPI C headers, so that netlink family constants used by Go
+// packages can be kept in sync with the kernel without copying them by hand.
+//
+// As an example, to generate generic netlink controller attributes from
+// linux/genetlink.h:
+//
+//	nlconst -prefix CTRL_ATTR_ -type ctrlAttr -pkg genetlink -o attrs.go /usr/include/linux/genetlink.h
+//
+// Only enums whose members share exactly the specified name prefix are
+// emitted, so the prefix CTRL_ATTR_ selects the enum containing
+// CTRL_ATTR_FAMILY_ID but not the nested enum containing CTRL_ATTR_OP_ID.
+// Members beginning with "__" (such as __CTRL_ATTR_MAX) are omitted. Comments
+// which precede a member, or follow it on the same line, are emitted as the
+// doc comment of the corresponding Go constant. nlconst only
+// understands a curated subset of C: enum members may be assigned integer
+// literals, previously declared members, or a member plus or minus an integer
+// literal.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+func main() {
+	var (
+		prefix = flag.String("prefix", "", "required: only emit enum members with this name prefix, such as CTRL_ATTR_")
+		typ    = flag.String("type", "", "required: the name of the generated Go type, such as ctrlAttr")
+		kind   = flag.String("kind", "uint16", "the underlying integer type of the generated Go type")
+		pkg    = flag.String("pkg", "", "required: the package name of the generated file")
+		out    = flag.String("o", "", "the output file; stdout if not set")
+	)
+
+	flag.Parse()
+	log.SetFlags(0)
+
+	if *prefix == "" || *typ == "" || *pkg == "" || flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var consts []constant
+	for _, f := range flag.Args() {
+		src, err := os.ReadFile(f)
+		if err != nil {
+			log.Fatalf("nlconst: failed to read header: %v", err)
+		}
+
+		cs, err := parse(string(src), *prefix)
+		if err != nil {
+			log.Fatalf("nlconst: failed to parse %q: %v", f, err)
+		}
+
+		consts = append(consts, cs...)
+	}
+
+	if len(consts) == 0 {
+		log.Fatalf("nlconst: no enum members found with prefix %q", *prefix)
+	}
+
+	b, err := generate(config{
+		Args:    os.Args[1:],
+		Package: *pkg,
+		Type:    *typ,
+		Kind:    *kind,
+		Prefix:  *prefix,
+	}, consts)
+	if err != nil {
+		log.Fatalf("nlconst: failed to generate code: %v", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatalf("nlconst: failed to create output file: %v", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if _, err := w.Write(b); err != nil {
+		log.Fatalf("nlconst: failed to write output: %v", err)
+	}
+}
+
+// A constant is a single C enum member.
+type constant struct {
+	Name  string
+	Value int64
+	// Doc is the text of the comments attached to the member, if any.
+	Doc string
+}
+
+var (
+	reEnum    = regexp.MustCompile(`(?s)\benum\b\s*\w*\s*\{(.*?)\}`)
+	reIdent   = regexp.MustCompile(`^[A-Za-z_]\w*$`)
+	reBinary  = regexp.MustCompile(`^\(?\s*([A-Za-z_]\w*)\s*([+-])\s*(\w+)\s*\)?$`)
+	reComment = regexp.MustCompile(`(?s)/\*.*?\*/|//[^\n]*`)
+	reMarker  = regexp.MustCompile("\x00(\\d+)\x00")
+)
+
+// parse parses each enum in the C source src and returns the members of the
+// enums whose members share exactly prefix, in declaration order.
+func parse(src, prefix string) ([]constant, error) {
+	// Replace each comment with a marker which refers to its text, so that
+	// comments can be attached to enum members after splitting on commas.
+	var comments []string
+	src = reComment.ReplaceAllStringFunc(src, func(c string) string {
+		comments = append(comments, commentText(c))
+		return fmt.Sprintf("\x00%d\x00", len(comments)-1)
+	})
+
+	var out []constant
+	for _, m := range reEnum.FindAllStringSubmatch(src, -1) {
+		// Enum member values are scoped to a single enum for the purposes of
+		// references between members.
+		values := make(map[string]int64)
+
+		var (
+			members []constant
+			// last is the index of the previous member in members, or -1
+			// if it was omitted.
+			last = -1
+		)
+		next := int64(0)
+		for i, raw := range strings.Split(m[1], ",") {
+			lead, trail := fieldDocs(raw, comments)
+			switch {
+			case i == 0:
+				// There is no previous member.
+				lead = joinDoc(trail, lead)
+			case last != -1:
+				members[last].Doc = joinDoc(members[last].Doc, trail)
+			}
+
+			field := strings.TrimSpace(reMarker.ReplaceAllString(raw, ""))
+			if field == "" || strings.HasPrefix(field, "#") {
+				continue
+			}
+
+			name, expr := field, ""
+			if i := strings.Index(field, "="); i != -1 {
+				name, expr = strings.TrimSpace(field[:i]), strings.TrimSpace(field[i+1:])
+			}
+
+			if !reIdent.MatchString(name) {
+				return nil, fmt.Errorf("invalid enum member name: %q", name)
+			}
+
+			v := next
+			if expr != "" {
+				ev, err := eval(expr, values)
+				if err != nil {
+					return nil, fmt.Errorf("enum member %s: %v", name, err)
+				}
+				v = ev
+			}
+
+			values[name] = v
+			next = v + 1
+
+			last = -1
+			if !strings.HasPrefix(name, "__") {
+				last = len(members)
+				members = append(members, constant{Name: name, Value: v, Doc: lead})
+			}
+		}
+
+		if len(members) > 0 && commonPrefix(members) == prefix {
+			out = append(out, members...)
+		}
+	}
+
+	return out, nil
+}
+
+// commentText returns the text of the C comment c with its delimiters and
+// leading asterisks removed, and whitespace collapsed onto a single line.
+func commentText(c string) string {
+	if strings.HasPrefix(c, "//") {
+		return strings.Join(strings.Fields(c[2:]), " ")
+	}
+
+	var words []string
+	for _, l := range strings.Split(strings.TrimSuffix(c[2:], "*/"), "\n") {
+		words = append(words, strings.Fields(strings.TrimLeft(strings.TrimSpace(l), "*"))...)
+	}
+
+	return strings.Join(words, " ")
+}
+
+// fieldDocs returns the text of the comments within a comma-separated field
+// of an enum. Comments on the first line of the field, before the member
+// itself, trail the previous member. All other comments document the member
+// in the field.
+func fieldDocs(field string, comments []string) (lead, trail string) {
+	// The member begins at the first text which is not a comment.
+	name := strings.IndexFunc(reMarker.ReplaceAllStringFunc(field, func(m string) string {
+		return strings.Repeat(" ", len(m))
+	}), func(r rune) bool { return r != ' ' && r != '\t' && r != '\n' })
+	if name == -1 {
+		name = len(field)
+	}
+
+	for _, loc := range reMarker.FindAllStringSubmatchIndex(field, -1) {
+		n, _ := strconv.Atoi(field[loc[2]:loc[3]])
+		text := comments[n]
+
+		if loc[0] < name && !strings.Contains(field[:loc[0]], "\n") {
+			trail = joinDoc(trail, text)
+			continue
+		}
+
+		lead = joinDoc(lead, text)
+	}
+
+	return lead, trail
+}
+
+// joinDoc joins the non-empty doc comment texts a and b.
+func joinDoc(a, b string) string {
+	switch {
+	case a == "":
+		return b
+	case b == "":
+		return a
+	default:
+		return a + " " + b
+	}
+}
+
+// commonPrefix returns the longest prefix shared by the names of cs, ending
+// with an underscore.
+func commonPrefix(cs []constant) string {
+	p := cs[0].Name
+	for _, c := range cs[1:] {
+		for !strings.HasPrefix(c.Name, p) {
+			p = p[:len(p)-1]
+		}
+	}
+
+	return p[:strings.LastIndex(p, "_")+1]
+}
+
+// eval evaluates a C enum member expression using the previously declared
+// members in values.
+func eval(expr string, values map[string]int64) (int64, error) {
+	if v, ok := values[expr]; ok {
+		return v, nil
+	}
+
+	if v, err := strconv.ParseInt(expr, 0, 64); err == nil {
+		return v, nil
+	}
+
+	m := reBinary.FindStringSubmatch(expr)
+	if m == nil {
+		return 0, fmt.Errorf("unsupported expression: %q", expr)
+	}
+
+	l, ok := values[m[1]]
+	if !ok {
+		return 0, fmt.Errorf("unknown identifier %q in expression: %q", m[1], expr)
+	}
+
+	r, err := strconv.ParseInt(m[3], 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unsupported operand in expression: %q", expr)
+	}
+
+	if m[2] == "-" {
+		return l - r, nil
+	}
+
+	return l + r, nil
+}
+
+// A config contains the options used to generate Go code.
+type config struct {
+	Args                        []string
+	Package, Type, Kind, Prefix string
+}
+
+// generate generates formatted Go source for consts using cfg.
+func generate(cfg config, consts []constant) ([]byte, error) {
+	var b bytes.Buffer
+	pf := func(format string, a ...interface{}) { fmt.Fprintf(&b, format, a...) }
+
+	pf("// Code generated by \"nlconst %s\"; DO NOT EDIT.\n\n", strings.Join(cfg.Args, " "))
+	pf("package %s\n\n", cfg.Package)
+	pf("import \"fmt\"\n\n")
+	pf("// %s is generated from C enum members with prefix %s.\n", cfg.Type, cfg.Prefix)
+	pf("type %s %s\n\n", cfg.Type, cfg.Kind)
+
+	pf("const (\n")
+	for _, c := range consts {
+		if c.Doc != "" {
+			pf("\t// %s\n", c.Doc)
+		}
+		pf("\t%s %s = %d\n", goName(cfg.Type, cfg.Prefix, c.Name), cfg.Type, c.Value)
+	}
+	pf(")\n\n")
+
+	pf("// String returns the C name of a %s value.\n", cfg.Type)
+	pf("func (v %s) String() string {\n", cfg.Type)
+	pf("\tswitch v {\n")
+
+	// C enums may contain aliases; only the first name for each value can
+	// appear in the switch.
+	seen := make(map[int64]bool)
+	for _, c := range consts {
+		if seen[c.Value] {
+			continue
+		}
+		seen[c.Value] = true
+
+		pf("\tcase %s:\n\t\treturn %q\n", goName(cfg.Type, cfg.Prefix, c.Name), c.Name)
+	}
+
+	pf("\tdefault:\n\t\treturn fmt.Sprintf(\"%s(%%d)\", v)\n", cfg.Type)
+	pf("\t}\n}\n")
+
+	return format.Source(b.Bytes())
+}
+
+// initialisms are words which are fully capitalized in Go identifiers.
+var initialisms = map[string]bool{
+	"ID":   true,
+	"IP":   true,
+	"IPV4": true,
+	"IPV6": true,
+	"MAC":  true,
+	"MTU":  true,
+	"PID":  true,
+	"TCP":  true,
+	"UDP":  true,
+	"VLAN": true,
+}
+
+// goName converts a C enum member name into a Go identifier with the
+// specified type name as a prefix.
+func goName(typ, prefix, name string) string {
+	var b strings.Builder
+	b.WriteString(typ)
+
+	for _, w := range strings.Split(strings.TrimPrefix(name, prefix), "_") {
+		if w == "" {
+			continue
+		}
+
+		if initialisms[w] {
+			b.WriteString(w)
+			continue
+		}
+
+		b.WriteString(w[:1])
+		b.WriteString(strings.ToLower(w[1:]))
+	}
+
+	return b.String()
+}
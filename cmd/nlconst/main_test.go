@@ -0,0 +1,111 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const testHeader = `
+/* Controller attributes. */
+enum {
+	CTRL_ATTR_UNSPEC,
+	CTRL_ATTR_FAMILY_ID, // comment
+	/* Name of the family,
+	 * a string. */
+	CTRL_ATTR_FAMILY_NAME,
+	CTRL_ATTR_OP = 8,
+	CTRL_ATTR_OP_ALIAS = CTRL_ATTR_OP,
+	CTRL_ATTR_NEXT = CTRL_ATTR_OP + 0x2,
+	__CTRL_ATTR_MAX,
+};
+
+#define CTRL_ATTR_MAX (__CTRL_ATTR_MAX - 1)
+
+enum {
+	CTRL_ATTR_OP_UNSPEC,
+	CTRL_ATTR_OP_ID,
+};
+
+enum other {
+	OTHER_A = 1,
+};
+`
+
+func TestParse(t *testing.T) {
+	got, err := parse(testHeader, "CTRL_ATTR_")
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	want := []constant{
+		{Name: "CTRL_ATTR_UNSPEC", Value: 0},
+		{Name: "CTRL_ATTR_FAMILY_ID", Value: 1, Doc: "comment"},
+		{Name: "CTRL_ATTR_FAMILY_NAME", Value: 2, Doc: "Name of the family, a string."},
+		{Name: "CTRL_ATTR_OP", Value: 8},
+		{Name: "CTRL_ATTR_OP_ALIAS", Value: 8},
+		{Name: "CTRL_ATTR_NEXT", Value: 10},
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("unexpected constants (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseNested(t *testing.T) {
+	got, err := parse(testHeader, "CTRL_ATTR_OP_")
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	want := []constant{
+		{Name: "CTRL_ATTR_OP_UNSPEC", Value: 0},
+		{Name: "CTRL_ATTR_OP_ID", Value: 1},
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("unexpected constants (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	if _, err := parse("enum { A = B * 2 };", "A"); err == nil {
+		t.Fatal("expected an error, but none occurred")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	consts, err := parse(testHeader, "CTRL_ATTR_")
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	b, err := generate(config{
+		Args:    []string{"-prefix", "CTRL_ATTR_"},
+		Package: "genetlink",
+		Type:    "ctrlAttr",
+		Kind:    "uint16",
+		Prefix:  "CTRL_ATTR_",
+	}, consts)
+	if err != nil {
+		t.Fatalf("failed to generate: %v", err)
+	}
+
+	src := string(b)
+	for _, s := range []string{
+		"package genetlink",
+		"// comment\n\tctrlAttrFamilyID ctrlAttr = 1",
+		"// Name of the family, a string.\n\tctrlAttrFamilyName ctrlAttr = 2",
+		"ctrlAttrOpAlias    ctrlAttr = 8",
+		`return "CTRL_ATTR_OP"`,
+	} {
+		if !strings.Contains(src, s) {
+			t.Fatalf("generated source does not contain %q:\n%s", s, src)
+		}
+	}
+
+	if strings.Contains(src, `return "CTRL_ATTR_OP_ALIAS"`) {
+		t.Fatalf("generated source contains aliased case:\n%s", src)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+
+	"github.com/mdlayher/netlink"
+)
+
+// A checkFunc decodes an input and returns an error if an invariant of the
+// decoder is violated. Inputs which cannot be decoded are not errors.
+type checkFunc func(b []byte) error
+
+// targets are the available checkFuncs, keyed by the name of the -target.
+var targets = map[string]checkFunc{
+	"message":    checkMessage,
+	"attributes": checkAttributes,
+	"decoder":    checkDecoder,
+	"all":        checkAll,
+}
+
+// maxNesting bounds the depth of nested attributes walked by checkDecoder.
+const maxNesting = 16
+
+// safeCheck calls fn with b, converting any panic into an error.
+func safeCheck(fn checkFunc, b []byte) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return fn(b)
+}
+
+// checkAll runs every target against b.
+func checkAll(b []byte) error {
+	for _, fn := range []checkFunc{checkMessage, checkAttributes, checkDecoder} {
+		if err := fn(b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkMessage verifies that a Message survives a marshaling round trip.
+func checkMessage(b []byte) error {
+	var m1 netlink.Message
+	if err := m1.UnmarshalBinary(b); err != nil {
+		return nil
+	}
+
+	b2, err := m1.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %v", err)
+	}
+
+	var m2 netlink.Message
+	if err := m2.UnmarshalBinary(b2); err != nil {
+		return fmt.Errorf("failed to unmarshal marshaled message: %v", err)
+	}
+
+	if !reflect.DeepEqual(m1, m2) {
+		return fmt.Errorf("message changed after round trip:\n%#v\n%#v", m1, m2)
+	}
+
+	b3, err := m2.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to marshal message again: %v", err)
+	}
+	if !bytes.Equal(b2, b3) {
+		return fmt.Errorf("message bytes changed after round trip:\n%x\n%x", b2, b3)
+	}
+
+	return nil
+}
+
+// checkAttributes verifies that Attributes survive a marshaling round trip.
+func checkAttributes(b []byte) error {
+	a1, err := netlink.UnmarshalAttributes(b)
+	if err != nil {
+		return nil
+	}
+
+	b2, err := netlink.MarshalAttributes(a1)
+	if err != nil {
+		return fmt.Errorf("failed to marshal attributes: %v", err)
+	}
+
+	a2, err := netlink.UnmarshalAttributes(b2)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal marshaled attributes: %v", err)
+	}
+
+	if !reflect.DeepEqual(a1, a2) {
+		return fmt.Errorf("attributes changed after round trip:\n%+v\n%+v", a1, a2)
+	}
+
+	b3, err := netlink.MarshalAttributes(a2)
+	if err != nil {
+		return fmt.Errorf("failed to marshal attributes again: %v", err)
+	}
+	if !bytes.Equal(b2, b3) {
+		return fmt.Errorf("attribute bytes changed after round trip:\n%x\n%x", b2, b3)
+	}
+
+	return nil
+}
+
+// checkDecoder walks the attributes in b with an AttributeDecoder, calling
+// each accessor and descending into attributes which may be nested. Only a
+// panic indicates a problem.
+func checkDecoder(b []byte) error {
+	ad, err := netlink.NewAttributeDecoder(b)
+	if err != nil {
+		return nil
+	}
+
+	walk(ad, 0)
+	return nil
+}
+
+// walk decodes each attribute in ad, descending into nested attributes up to
+// maxNesting levels deep.
+func walk(ad *netlink.AttributeDecoder, depth int) {
+	for ad.Next() {
+		switch {
+		case ad.TypeFlags()&netlink.Nested != 0 && depth < maxNesting:
+			ad.Nested(func(nad *netlink.AttributeDecoder) error {
+				walk(nad, depth+1)
+				return nil
+			})
+		default:
+			b := ad.Bytes()
+			_ = ad.String()
+
+			// Avoid setting the decoder's error so the walk can continue.
+			switch len(b) {
+			case 1, 2, 4, 8:
+				_ = ad.UintAuto()
+			}
+		}
+	}
+
+	_ = ad.Err()
+}
@@ -0,0 +1,88 @@
+// Command nlfuzzserve exposes the message and attribute decoders of package
+// netlink to external fuzzers such as AFL++ and honggfuzz, which drive a
+// target program by writing each input to its stdin.
+//
+// By default, nlfuzzserve reads a single input from stdin and runs it through
+// the decoder selected by -target. If decoding succeeds but an invariant such
+// as a lossless round trip is violated, or if a decoder panics, nlfuzzserve
+// aborts with SIGABRT so the fuzzer records the input as a crash:
+//
+//	afl-fuzz -i corpus -o out -- nlfuzzserve -target attributes
+//
+// Available targets are:
+//
+//	message     unmarshal, marshal, and compare a single Message
+//	attributes  unmarshal, marshal, and compare a set of Attributes
+//	decoder     walk nested attributes with an AttributeDecoder
+//	all         run all of the above (default)
+//
+// With -fixtures, nlfuzzserve instead reads the crashing inputs named by its
+// arguments and prints a test case for each input which still fails, suitable
+// for pasting into the table of regression tests in the netlink package's
+// fuzz_test.go:
+//
+//	nlfuzzserve -fixtures out/default/crashes/id:*
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+)
+
+func main() {
+	var (
+		target   = flag.String("target", "all", "decoder to fuzz: message, attributes, decoder, or all")
+		fixtures = flag.Bool("fixtures", false, "print regression test cases for the input files named by the arguments")
+	)
+	flag.Parse()
+
+	fn, ok := targets[*target]
+	if !ok {
+		log.Fatalf("nlfuzzserve: unknown target %q", *target)
+	}
+
+	if *fixtures {
+		if err := printFixtures(os.Stdout, fn, flag.Args()); err != nil {
+			log.Fatalf("nlfuzzserve: %v", err)
+		}
+		return
+	}
+
+	b, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		log.Fatalf("nlfuzzserve: failed to read input: %v", err)
+	}
+
+	// Fuzzers detect crashes by signal rather than by exit status, so make
+	// panics abort the process.
+	debug.SetTraceback("crash")
+
+	if err := fn(b); err != nil {
+		panic(err)
+	}
+}
+
+// printFixtures writes a regression test case to w for each of the files
+// which fails when checked by fn.
+func printFixtures(w io.Writer, fn checkFunc, files []string) error {
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return err
+		}
+
+		if err := safeCheck(fn, b); err == nil {
+			// The input no longer fails.
+			continue
+		}
+
+		fmt.Fprintf(w, "{\n\tname: %q,\n\ts:    %q,\n},\n", filepath.Base(f), b)
+	}
+
+	return nil
+}
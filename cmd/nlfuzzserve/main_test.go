@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mdlayher/netlink"
+)
+
+func TestTargets(t *testing.T) {
+	attrs, err := netlink.MarshalAttributes([]netlink.Attribute{
+		{Type: 1, Data: []byte{0x01, 0x00}},
+		{Type: 2 | netlink.Nested, Data: mustMarshal(t, []netlink.Attribute{
+			{Type: 1, Data: []byte("hello\x00")},
+		})},
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal attributes: %v", err)
+	}
+
+	msg, err := netlink.Message{
+		Header: netlink.Header{Length: uint32(netlink.HeaderLen + len(attrs)), Type: 1},
+		Data:   attrs,
+	}.MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to marshal message: %v", err)
+	}
+
+	inputs := map[string][]byte{
+		"empty":      nil,
+		"short":      {0xff},
+		"attributes": attrs,
+		"message":    msg,
+		"bad nested": {8, 0, 1 | 0x80, 0x80, 0xff, 0xff, 0xff, 0xff},
+	}
+
+	for name, b := range inputs {
+		for target, fn := range targets {
+			if err := safeCheck(fn, b); err != nil {
+				t.Fatalf("%s: unexpected error for %s input: %v", target, name, err)
+			}
+		}
+	}
+}
+
+func TestPrintFixtures(t *testing.T) {
+	dir := t.TempDir()
+	for name, b := range map[string]string{
+		"crash": "\x00bad",
+		"panic": "panic",
+		"fixed": "ok",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(b), 0o644); err != nil {
+			t.Fatalf("failed to write input: %v", err)
+		}
+	}
+
+	fn := func(b []byte) error {
+		switch string(b) {
+		case "\x00bad":
+			return errors.New("bad")
+		case "panic":
+			panic("boom")
+		}
+
+		return nil
+	}
+
+	var buf bytes.Buffer
+	files := []string{
+		filepath.Join(dir, "crash"),
+		filepath.Join(dir, "fixed"),
+		filepath.Join(dir, "panic"),
+	}
+	if err := printFixtures(&buf, fn, files); err != nil {
+		t.Fatalf("failed to print fixtures: %v", err)
+	}
+
+	want := `{
+	name: "crash",
+	s:    "\x00bad",
+},
+{
+	name: "panic",
+	s:    "panic",
+},
+`
+
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Fatalf("unexpected fixtures (-want +got):\n%s", diff)
+	}
+}
+
+func mustMarshal(t *testing.T, attrs []netlink.Attribute) []byte {
+	t.Helper()
+
+	b, err := netlink.MarshalAttributes(attrs)
+	if err != nil {
+		t.Fatalf("failed to marshal attributes: %v", err)
+	}
+
+	return b
+}
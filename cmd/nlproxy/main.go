@@ -0,0 +1,63 @@
+// Command nlproxy is a daemon which forwards netlink requests received on a
+// Unix socket to the kernel, subject to an allowlist of netlink families and
+// message types. nlproxy can be used to grant a container or other sandboxed
+// process scoped access to netlink without granting it direct access to the
+// host's netlink sockets.
+//
+// Each client connection is bound to a single netlink family, which the client
+// specifies by first sending the family number as a 4 byte big endian integer.
+// The client then sends netlink request messages exactly as they would be
+// sent to the kernel. For each request, nlproxy sends the request to the
+// kernel and writes back each of its replies, followed by an acknowledgement
+// message of type error which carries 0 on success, or the negated error
+// number on failure. Requests for message types which are not allowed are
+// acknowledged with EPERM without being sent to the kernel.
+//
+// The allowlist is specified with one or more -allow flags, each naming a
+// family by name or number with an optional comma-separated list of allowed
+// message types. If no types are specified, all message types are allowed for
+// that family. For example, to allow route netlink RTM_GETLINK and
+// RTM_GETADDR requests, and any generic netlink request:
+//
+//	nlproxy -listen /run/nlproxy.sock -allow route:18,22 -allow generic
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+
+	"github.com/mdlayher/netlink"
+)
+
+func main() {
+	var (
+		allow  = make(allowlist)
+		listen = flag.String("listen", "/run/nlproxy.sock", "path of the Unix socket to listen on")
+	)
+	flag.Var(allow, "allow", "allowed netlink `family[:type,...]`, may be repeated")
+	flag.Parse()
+
+	if len(allow) == 0 {
+		log.Fatal("nlproxy: at least one -allow flag must be specified")
+	}
+
+	l, err := net.Listen("unix", *listen)
+	if err != nil {
+		log.Fatalf("nlproxy: failed to listen: %v", err)
+	}
+	defer l.Close()
+
+	log.Printf("nlproxy: listening on %s", l.Addr())
+
+	p := &proxy{
+		allow: allow,
+		dial: func(family netlink.Family) (*netlink.Conn, error) {
+			return netlink.Dial(int(family), nil)
+		},
+	}
+
+	if err := p.serve(l); err != nil {
+		log.Fatalf("nlproxy: failed to serve: %v", err)
+	}
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nlenc"
+)
+
+// maxMessageLen is the maximum length of a request message accepted from a
+// client.
+const maxMessageLen = 1 << 20
+
+// An allowlist maps netlink families to their allowed message types. A nil set
+// of types allows all message types for a family. An allowlist implements
+// flag.Value.
+type allowlist map[netlink.Family]map[netlink.HeaderType]bool
+
+// String implements flag.Value.
+func (a allowlist) String() string {
+	ss := make([]string, 0, len(a))
+	for f, types := range a {
+		s := f.String()
+		if types != nil {
+			ts := make([]string, 0, len(types))
+			for t := range types {
+				ts = append(ts, strconv.Itoa(int(t)))
+			}
+			sort.Strings(ts)
+			s += ":" + strings.Join(ts, ",")
+		}
+
+		ss = append(ss, s)
+	}
+
+	sort.Strings(ss)
+	return strings.Join(ss, " ")
+}
+
+// Set implements flag.Value, parsing an allowed family and its optional
+// message types in the form family[:type,...].
+func (a allowlist) Set(s string) error {
+	name, list, hasTypes := strings.Cut(s, ":")
+
+	family, err := parseFamily(name)
+	if err != nil {
+		return err
+	}
+
+	if !hasTypes {
+		// All message types are allowed.
+		a[family] = nil
+		return nil
+	}
+
+	types, ok := a[family]
+	if ok && types == nil {
+		// All message types are already allowed.
+		return nil
+	}
+	if types == nil {
+		types = make(map[netlink.HeaderType]bool)
+		a[family] = types
+	}
+
+	for _, t := range strings.Split(list, ",") {
+		v, err := strconv.ParseUint(t, 0, 16)
+		if err != nil {
+			return fmt.Errorf("invalid message type %q: %v", t, err)
+		}
+
+		types[netlink.HeaderType(v)] = true
+	}
+
+	return nil
+}
+
+// family reports whether any requests are allowed for family.
+func (a allowlist) family(family netlink.Family) bool {
+	_, ok := a[family]
+	return ok
+}
+
+// allowed reports whether requests of type t are allowed for family.
+func (a allowlist) allowed(family netlink.Family, t netlink.HeaderType) bool {
+	types, ok := a[family]
+	return ok && (types == nil || types[t])
+}
+
+// parseFamily parses a netlink family by name or number.
+func parseFamily(s string) (netlink.Family, error) {
+	if v, err := strconv.ParseUint(s, 0, 8); err == nil {
+		return netlink.Family(v), nil
+	}
+
+	// Family names are only exposed through the String method.
+	for i := 0; i < 32; i++ {
+		if f := netlink.Family(i); f.String() == s {
+			return f, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown netlink family %q", s)
+}
+
+// A proxy forwards requests from clients to netlink.
+type proxy struct {
+	allow allowlist
+	dial  func(family netlink.Family) (*netlink.Conn, error)
+}
+
+// serve accepts client connections from l until l is closed.
+func (p *proxy) serve(l net.Listener) error {
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+
+			return err
+		}
+
+		go func() {
+			defer c.Close()
+
+			if err := p.handle(c); err != nil {
+				log.Printf("nlproxy: client %s: %v", c.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+// handle forwards requests from a single client connection until the client
+// closes the connection.
+func (p *proxy) handle(rw io.ReadWriter) error {
+	var fb [4]byte
+	if _, err := io.ReadFull(rw, fb[:]); err != nil {
+		return fmt.Errorf("failed to read family: %v", err)
+	}
+
+	family := netlink.Family(binary.BigEndian.Uint32(fb[:]))
+	if !p.allow.family(family) {
+		return fmt.Errorf("family %s is not allowed", family)
+	}
+
+	c, err := p.dial(family)
+	if err != nil {
+		return fmt.Errorf("failed to dial family %s: %v", family, err)
+	}
+	defer c.Close()
+
+	var (
+		r = bufio.NewReader(rw)
+		w = bufio.NewWriter(rw)
+	)
+
+	for {
+		req, err := readMessage(r)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+
+			return fmt.Errorf("failed to read request: %v", err)
+		}
+
+		res, errno := p.execute(c, family, req)
+		for _, m := range res {
+			writeMessage(w, m)
+		}
+		writeMessage(w, ackMessage(req, errno))
+
+		if err := w.Flush(); err != nil {
+			return fmt.Errorf("failed to write replies: %v", err)
+		}
+	}
+}
+
+// execute sends req to netlink if it is allowed, returning its replies and an
+// error number for the final acknowledgement.
+func (p *proxy) execute(c *netlink.Conn, family netlink.Family, req netlink.Message) ([]netlink.Message, syscall.Errno) {
+	if !p.allow.allowed(family, req.Header.Type) {
+		return nil, syscall.EPERM
+	}
+	if req.Header.Flags&netlink.Request == 0 {
+		return nil, syscall.EINVAL
+	}
+
+	// Always ask for an acknowledgement so Execute cannot block, and let the
+	// Conn assign the sequence number and PID so clients cannot interfere with
+	// one another.
+	m := req
+	m.Header.Flags |= netlink.Acknowledge
+	m.Header.Length, m.Header.Sequence, m.Header.PID = 0, 0, 0
+
+	res, err := c.Execute(m)
+	if err != nil {
+		var errno syscall.Errno
+		if errors.As(err, &errno) {
+			return nil, errno
+		}
+
+		return nil, syscall.EIO
+	}
+
+	// The acknowledgement is replaced by the one sent by the proxy.
+	out := res[:0]
+	for _, m := range res {
+		if m.Header.Type == netlink.Error {
+			continue
+		}
+
+		m.Header.Sequence = req.Header.Sequence
+		out = append(out, m)
+	}
+
+	return out, 0
+}
+
+// ackMessage produces an acknowledgement for req, carrying the error number
+// errno and the header of req as the kernel does.
+func ackMessage(req netlink.Message, errno syscall.Errno) netlink.Message {
+	b := make([]byte, 4+netlink.HeaderLen)
+	nlenc.PutInt32(b[0:4], -int32(errno))
+	putHeader(b[4:], req.Header)
+
+	return netlink.Message{
+		Header: netlink.Header{
+			Type: netlink.Error,
+			// Only the header of the request is included.
+			Flags:    netlink.Capped,
+			Sequence: req.Header.Sequence,
+			PID:      req.Header.PID,
+		},
+		Data: b,
+	}
+}
+
+// readMessage reads a single netlink message from r.
+func readMessage(r io.Reader) (netlink.Message, error) {
+	hb := make([]byte, netlink.HeaderLen)
+	if _, err := io.ReadFull(r, hb); err != nil {
+		return netlink.Message{}, err
+	}
+
+	l := int(nlenc.Uint32(hb[0:4]))
+	if l < netlink.HeaderLen || l > maxMessageLen {
+		return netlink.Message{}, fmt.Errorf("invalid message length: %d", l)
+	}
+
+	// Messages are padded to a multiple of 4 bytes.
+	b := make([]byte, align(l)-netlink.HeaderLen)
+	if _, err := io.ReadFull(r, b); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+
+		return netlink.Message{}, err
+	}
+
+	return netlink.Message{
+		Header: netlink.Header{
+			Length:   uint32(l),
+			Type:     netlink.HeaderType(nlenc.Uint16(hb[4:6])),
+			Flags:    netlink.HeaderFlags(nlenc.Uint16(hb[6:8])),
+			Sequence: nlenc.Uint32(hb[8:12]),
+			PID:      nlenc.Uint32(hb[12:16]),
+		},
+		Data: b[:l-netlink.HeaderLen],
+	}, nil
+}
+
+// writeMessage writes m to w with an accurate header length and padding.
+func writeMessage(w *bufio.Writer, m netlink.Message) {
+	m.Header.Length = uint32(netlink.HeaderLen + len(m.Data))
+
+	hb := make([]byte, netlink.HeaderLen)
+	putHeader(hb, m.Header)
+
+	// Errors are reported by the final call to Flush.
+	_, _ = w.Write(hb)
+	_, _ = w.Write(m.Data)
+	_, _ = w.Write(make([]byte, align(len(m.Data))-len(m.Data)))
+}
+
+// putHeader encodes h into b.
+func putHeader(b []byte, h netlink.Header) {
+	nlenc.PutUint32(b[0:4], h.Length)
+	nlenc.PutUint16(b[4:6], uint16(h.Type))
+	nlenc.PutUint16(b[6:8], uint16(h.Flags))
+	nlenc.PutUint32(b[8:12], h.Sequence)
+	nlenc.PutUint32(b[12:16], h.PID)
+}
+
+// align rounds n up to the netlink alignment of 4 bytes.
+func align(n int) int {
+	return (n + 3) &^ 3
+}
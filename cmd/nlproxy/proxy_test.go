@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nltest"
+)
+
+func TestAllowlist(t *testing.T) {
+	a := make(allowlist)
+	for _, s := range []string{"route:18,0x16", "generic", "generic:16", "9"} {
+		if err := a.Set(s); err != nil {
+			t.Fatalf("failed to set %q: %v", s, err)
+		}
+	}
+
+	if diff := cmp.Diff("audit generic route:18,22", a.String()); diff != "" {
+		t.Fatalf("unexpected allowlist (-want +got):\n%s", diff)
+	}
+
+	tests := []struct {
+		family netlink.Family
+		t      netlink.HeaderType
+		ok     bool
+	}{
+		{family: netlink.FamilyRoute, t: 18, ok: true},
+		{family: netlink.FamilyRoute, t: 22, ok: true},
+		{family: netlink.FamilyRoute, t: 16},
+		{family: netlink.FamilyGeneric, t: 100, ok: true},
+		{family: netlink.FamilyAudit, t: 1000, ok: true},
+		{family: netlink.FamilyNetfilter, t: 1},
+	}
+
+	for _, tt := range tests {
+		if diff := cmp.Diff(tt.ok, a.allowed(tt.family, tt.t)); diff != "" {
+			t.Fatalf("unexpected result for %s type %d (-want +got):\n%s", tt.family, tt.t, diff)
+		}
+	}
+
+	for _, s := range []string{"foo", "route:bar", "route:65536"} {
+		if err := a.Set(s); err == nil {
+			t.Fatalf("expected an error for %q, but none occurred", s)
+		}
+	}
+}
+
+func TestProxyHandle(t *testing.T) {
+	reply := netlink.Message{
+		Header: netlink.Header{Type: 16},
+		// Unaligned data must be padded.
+		Data: []byte{0x01, 0x02, 0x03},
+	}
+
+	p := &proxy{
+		allow: allowlist{netlink.FamilyRoute: {16: true, 18: true}},
+		dial: func(_ netlink.Family) (*netlink.Conn, error) {
+			return nltest.Dial(func(reqs []netlink.Message) ([]netlink.Message, error) {
+				if reqs[0].Header.Flags&netlink.Acknowledge == 0 {
+					panic("proxy did not request an acknowledgement")
+				}
+
+				if reqs[0].Header.Type == 18 {
+					return nltest.Error(int(syscall.ENOENT), reqs)
+				}
+
+				reply := reply
+				reply.Header.Sequence = reqs[0].Header.Sequence
+				return []netlink.Message{reply}, nil
+			}), nil
+		},
+	}
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	errC := make(chan error, 1)
+	go func() {
+		defer server.Close()
+		errC <- p.handle(server)
+	}()
+
+	var fb [4]byte
+	binary.BigEndian.PutUint32(fb[:], uint32(netlink.FamilyRoute))
+	if _, err := client.Write(fb[:]); err != nil {
+		t.Fatalf("failed to write family: %v", err)
+	}
+
+	var (
+		r = bufio.NewReader(client)
+		w = bufio.NewWriter(client)
+	)
+
+	tests := []struct {
+		name  string
+		req   netlink.Message
+		res   []netlink.Message
+		errno syscall.Errno
+	}{
+		{
+			name: "OK",
+			req: netlink.Message{
+				Header: netlink.Header{Type: 16, Flags: netlink.Request, Sequence: 10},
+				Data:   []byte{0xff},
+			},
+			res: []netlink.Message{{
+				Header: netlink.Header{Length: 19, Type: 16, Sequence: 10},
+				Data:   reply.Data,
+			}},
+		},
+		{
+			name: "kernel error",
+			req: netlink.Message{
+				Header: netlink.Header{Type: 18, Flags: netlink.Request, Sequence: 11},
+			},
+			errno: syscall.ENOENT,
+		},
+		{
+			name: "not allowed",
+			req: netlink.Message{
+				Header: netlink.Header{Type: 20, Flags: netlink.Request, Sequence: 12},
+			},
+			errno: syscall.EPERM,
+		},
+		{
+			name: "not a request",
+			req: netlink.Message{
+				Header: netlink.Header{Type: 16, Sequence: 13},
+			},
+			errno: syscall.EINVAL,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.Header.Length = uint32(netlink.HeaderLen + len(tt.req.Data))
+			writeMessage(w, tt.req)
+			if err := w.Flush(); err != nil {
+				t.Fatalf("failed to write request: %v", err)
+			}
+
+			var res []netlink.Message
+			for {
+				m, err := readMessage(r)
+				if err != nil {
+					t.Fatalf("failed to read reply: %v", err)
+				}
+
+				if m.Header.Type == netlink.Error {
+					if diff := cmp.Diff(ackMessage(tt.req, tt.errno).Data, m.Data); diff != "" {
+						t.Fatalf("unexpected acknowledgement (-want +got):\n%s", diff)
+					}
+
+					break
+				}
+
+				res = append(res, m)
+			}
+
+			if diff := cmp.Diff(tt.res, res); diff != "" {
+				t.Fatalf("unexpected replies (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	client.Close()
+	if err := <-errC; err != nil {
+		t.Fatalf("failed to handle client: %v", err)
+	}
+}
+
+func TestProxyHandleFamilyNotAllowed(t *testing.T) {
+	p := &proxy{
+		allow: allowlist{netlink.FamilyRoute: nil},
+		dial: func(_ netlink.Family) (*netlink.Conn, error) {
+			panic("should not dial")
+		},
+	}
+
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go func() {
+		var fb [4]byte
+		binary.BigEndian.PutUint32(fb[:], uint32(netlink.FamilyGeneric))
+		_, _ = client.Write(fb[:])
+	}()
+
+	if err := p.handle(server); err == nil {
+		t.Fatal("expected an error, but none occurred")
+	}
+}
@@ -0,0 +1,68 @@
+// Command nlrepl is an interactive shell for exploring netlink protocols. It
+// can dial arbitrary netlink families, compose messages and attributes using a
+// small textual syntax, send them, and pretty-print the replies.
+//
+// Commands are read one per line from stdin:
+//
+//	dial <family>             dial a family by name (such as "generic") or number
+//	exec <message>            send a request and print its replies
+//	send <message>            send a message without waiting for replies
+//	recv                      receive and print messages
+//	close                     close the current connection
+//	help                      print this help
+//	quit                      exit nlrepl
+//
+// A message is specified as a header type, header flags, and optional family
+// header and attributes:
+//
+//	<type> <flags> [hdr=<hex>] [<attr>...]
+//
+// Header flags are a comma-separated list such as "request,ack,dump". The
+// optional hdr argument specifies raw bytes for a family-specific header, such
+// as a generic netlink header, which precede any attributes. Each attribute is
+// specified as <type>:<kind>=<value>, where kind is one of u8, u16, u32, u64,
+// str, or hex, or as <type>:flag for a zero-length attribute. Attribute types
+// may include the nested flag as a number, such as 0x8001.
+//
+// As an example, to query the generic netlink controller for its own family:
+//
+//	> dial generic
+//	> exec 16 request hdr=03010000 2:str=nlctrl
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+)
+
+func main() {
+	r := newREPL(os.Stdout)
+	defer r.close()
+
+	if err := r.run(os.Stdin); err != nil {
+		fmt.Fprintf(os.Stderr, "nlrepl: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run reads and executes commands from in until EOF or quit.
+func (r *repl) run(in io.Reader) error {
+	s := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(r.w, "> ")
+		if !s.Scan() {
+			fmt.Fprintln(r.w)
+			return s.Err()
+		}
+
+		if err := r.exec(s.Text()); err != nil {
+			if err == errQuit {
+				return nil
+			}
+
+			fmt.Fprintf(r.w, "error: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nlenc"
+)
+
+// errQuit is returned by exec when the user requests to exit.
+var errQuit = errors.New("quit")
+
+// A repl executes nlrepl commands and writes output to w.
+type repl struct {
+	w io.Writer
+	c *netlink.Conn
+
+	// hdrLen is the length of the family header specified by the most recent
+	// message, used to locate attributes in replies.
+	hdrLen int
+}
+
+// newREPL creates a repl which writes output to w.
+func newREPL(w io.Writer) *repl {
+	return &repl{w: w}
+}
+
+// close closes the repl's Conn, if any.
+func (r *repl) close() {
+	if r.c != nil {
+		_ = r.c.Close()
+		r.c = nil
+	}
+}
+
+// exec executes a single command line.
+func (r *repl) exec(line string) error {
+	args := strings.Fields(line)
+	if len(args) == 0 || strings.HasPrefix(args[0], "#") {
+		return nil
+	}
+
+	cmd, args := args[0], args[1:]
+	switch cmd {
+	case "dial":
+		if len(args) != 1 {
+			return errors.New("usage: dial <family>")
+		}
+
+		family, err := parseFamily(args[0])
+		if err != nil {
+			return err
+		}
+
+		c, err := netlink.Dial(int(family), nil)
+		if err != nil {
+			return err
+		}
+
+		r.close()
+		r.c = c
+		fmt.Fprintf(r.w, "dialed %s\n", family)
+		return nil
+	case "close":
+		r.close()
+		return nil
+	case "exec", "send":
+		if r.c == nil {
+			return errors.New("not connected, use dial first")
+		}
+
+		m, hdrLen, err := parseMessage(args)
+		if err != nil {
+			return err
+		}
+		r.hdrLen = hdrLen
+
+		if cmd == "send" {
+			_, err := r.c.Send(m)
+			return err
+		}
+
+		msgs, err := r.c.Execute(m)
+		if err != nil {
+			return err
+		}
+
+		r.print(msgs)
+		return nil
+	case "recv":
+		if r.c == nil {
+			return errors.New("not connected, use dial first")
+		}
+
+		msgs, err := r.c.Receive()
+		if err != nil {
+			return err
+		}
+
+		r.print(msgs)
+		return nil
+	case "help":
+		fmt.Fprintln(r.w, "commands: dial <family>, exec <message>, send <message>, recv, close, help, quit")
+		fmt.Fprintln(r.w, "message: <type> <flags> [hdr=<hex>] [<type>:<u8|u16|u32|u64|str|hex>=<value> | <type>:flag]...")
+		return nil
+	case "quit", "exit":
+		return errQuit
+	default:
+		return fmt.Errorf("unknown command %q, try help", cmd)
+	}
+}
+
+// print pretty-prints msgs to r.w.
+func (r *repl) print(msgs []netlink.Message) {
+	for _, m := range msgs {
+		h := m.Header
+		fmt.Fprintf(r.w, "message: length: %d, type: %s, flags: %s, seq: %d, pid: %d\n",
+			h.Length, h.Type, h.Flags, h.Sequence, h.PID)
+
+		if len(m.Data) == 0 {
+			continue
+		}
+
+		if h.Type == netlink.Error || h.Type == netlink.Done || len(m.Data) < r.hdrLen {
+			fmt.Fprintf(r.w, "  data: %s\n", hex.EncodeToString(m.Data))
+			continue
+		}
+
+		if r.hdrLen > 0 {
+			fmt.Fprintf(r.w, "  hdr: %s\n", hex.EncodeToString(m.Data[:r.hdrLen]))
+		}
+
+		attrs, err := netlink.UnmarshalAttributes(m.Data[r.hdrLen:])
+		if err != nil {
+			fmt.Fprintf(r.w, "  data: %s\n", hex.EncodeToString(m.Data[r.hdrLen:]))
+			continue
+		}
+
+		printAttributes(r.w, attrs, 1)
+	}
+}
+
+// printAttributes pretty-prints attrs to w at the specified indentation depth,
+// descending into attributes which appear to be nested.
+func printAttributes(w io.Writer, attrs []netlink.Attribute, depth int) {
+	indent := strings.Repeat("  ", depth)
+	for _, a := range attrs {
+		typ := a.Type &^ (netlink.Nested | netlink.NetByteOrder)
+		fmt.Fprintf(w, "%sattr: type: %d, length: %d", indent, typ, a.Length)
+
+		if a.Type&netlink.Nested != 0 {
+			if nested, err := netlink.UnmarshalAttributes(a.Data); err == nil {
+				fmt.Fprintln(w, ", nested:")
+				printAttributes(w, nested, depth+1)
+				continue
+			}
+		}
+
+		fmt.Fprintf(w, ", data: %s\n", formatData(a.Data))
+	}
+}
+
+// formatData formats attribute data as a string if it appears to be a
+// printable null-terminated string, or as an integer and hex otherwise.
+func formatData(b []byte) string {
+	if len(b) > 1 && b[len(b)-1] == 0 && isPrintable(b[:len(b)-1]) {
+		return strconv.Quote(nlenc.String(b))
+	}
+
+	switch len(b) {
+	case 1:
+		return fmt.Sprintf("%d (%s)", nlenc.Uint8(b), hex.EncodeToString(b))
+	case 2:
+		return fmt.Sprintf("%d (%s)", nlenc.Uint16(b), hex.EncodeToString(b))
+	case 4:
+		return fmt.Sprintf("%d (%s)", nlenc.Uint32(b), hex.EncodeToString(b))
+	case 8:
+		return fmt.Sprintf("%d (%s)", nlenc.Uint64(b), hex.EncodeToString(b))
+	default:
+		return hex.EncodeToString(b)
+	}
+}
+
+// isPrintable reports whether b consists entirely of printable characters.
+func isPrintable(b []byte) bool {
+	for _, c := range string(b) {
+		if !unicode.IsPrint(c) {
+			return false
+		}
+	}
+
+	return len(b) > 0
+}
+
+// parseFamily parses a netlink family by name or number.
+func parseFamily(s string) (netlink.Family, error) {
+	if v, err := strconv.ParseUint(s, 0, 8); err == nil {
+		return netlink.Family(v), nil
+	}
+
+	// Family names are only exposed through the String method.
+	for i := 0; i < 32; i++ {
+		if f := netlink.Family(i); f.String() == s {
+			return f, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown netlink family %q", s)
+}
+
+// flagNames maps textual header flag names to their values.
+var flagNames = map[string]netlink.HeaderFlags{
+	"request":     netlink.Request,
+	"multi":       netlink.Multi,
+	"ack":         netlink.Acknowledge,
+	"acknowledge": netlink.Acknowledge,
+	"echo":        netlink.Echo,
+	"root":        netlink.Root,
+	"match":       netlink.Match,
+	"atomic":      netlink.Atomic,
+	"dump":        netlink.Dump,
+	"replace":     netlink.Replace,
+	"excl":        netlink.Excl,
+	"create":      netlink.Create,
+	"append":      netlink.Append,
+}
+
+// parseMessage parses a message from its textual arguments, returning the
+// message and the length of its family header.
+func parseMessage(args []string) (netlink.Message, int, error) {
+	if len(args) < 2 {
+		return netlink.Message{}, 0, errors.New("usage: <type> <flags> [hdr=<hex>] [<attr>...]")
+	}
+
+	typ, err := strconv.ParseUint(args[0], 0, 16)
+	if err != nil {
+		return netlink.Message{}, 0, fmt.Errorf("invalid message type %q", args[0])
+	}
+
+	var flags netlink.HeaderFlags
+	for _, s := range strings.Split(args[1], ",") {
+		f, ok := flagNames[s]
+		if !ok {
+			v, err := strconv.ParseUint(s, 0, 16)
+			if err != nil {
+				return netlink.Message{}, 0, fmt.Errorf("invalid message flag %q", s)
+			}
+			f = netlink.HeaderFlags(v)
+		}
+
+		flags |= f
+	}
+
+	args = args[2:]
+
+	var hdr []byte
+	if len(args) > 0 && strings.HasPrefix(args[0], "hdr=") {
+		hdr, err = hex.DecodeString(strings.TrimPrefix(args[0], "hdr="))
+		if err != nil {
+			return netlink.Message{}, 0, fmt.Errorf("invalid family header: %v", err)
+		}
+
+		args = args[1:]
+	}
+
+	ae := netlink.NewAttributeEncoder()
+	for _, a := range args {
+		if err := parseAttribute(ae, a); err != nil {
+			return netlink.Message{}, 0, err
+		}
+	}
+
+	attrs, err := ae.Encode()
+	if err != nil {
+		return netlink.Message{}, 0, err
+	}
+
+	return netlink.Message{
+		Header: netlink.Header{
+			Type:  netlink.HeaderType(typ),
+			Flags: flags,
+		},
+		Data: append(hdr, attrs...),
+	}, len(hdr), nil
+}
+
+// parseAttribute parses a single textual attribute and encodes it with ae.
+func parseAttribute(ae *netlink.AttributeEncoder, s string) error {
+	ts, rest, ok := strings.Cut(s, ":")
+	if !ok {
+		return fmt.Errorf("invalid attribute %q: missing type", s)
+	}
+
+	t, err := strconv.ParseUint(ts, 0, 16)
+	if err != nil {
+		return fmt.Errorf("invalid attribute %q: bad type", s)
+	}
+	typ := uint16(t)
+
+	if rest == "flag" {
+		ae.Flag(typ, true)
+		return nil
+	}
+
+	kind, v, ok := strings.Cut(rest, "=")
+	if !ok {
+		return fmt.Errorf("invalid attribute %q: missing value", s)
+	}
+
+	var bits int
+	switch kind {
+	case "str":
+		ae.String(typ, v)
+		return nil
+	case "hex":
+		b, err := hex.DecodeString(v)
+		if err != nil {
+			return fmt.Errorf("invalid attribute %q: %v", s, err)
+		}
+
+		ae.Bytes(typ, b)
+		return nil
+	case "u8":
+		bits = 8
+	case "u16":
+		bits = 16
+	case "u32":
+		bits = 32
+	case "u64":
+		bits = 64
+	default:
+		return fmt.Errorf("invalid attribute %q: unknown kind %q", s, kind)
+	}
+
+	n, err := strconv.ParseUint(v, 0, bits)
+	if err != nil {
+		return fmt.Errorf("invalid attribute %q: %v", s, err)
+	}
+
+	switch bits {
+	case 8:
+		ae.Uint8(typ, uint8(n))
+	case 16:
+		ae.Uint16(typ, uint16(n))
+	case 32:
+		ae.Uint32(typ, uint32(n))
+	case 64:
+		ae.Uint64(typ, n)
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nltest"
+)
+
+func TestParseMessage(t *testing.T) {
+	m, hdrLen, err := parseMessage([]string{
+		"16", "request,ack",
+		"hdr=03010000",
+		"1:u16=0x10",
+		"2:str=nlctrl",
+		"3:flag",
+	})
+	if err != nil {
+		t.Fatalf("failed to parse message: %v", err)
+	}
+
+	ae := netlink.NewAttributeEncoder()
+	ae.Uint16(1, 0x10)
+	ae.String(2, "nlctrl")
+	ae.Flag(3, true)
+	attrs, err := ae.Encode()
+	if err != nil {
+		t.Fatalf("failed to encode attributes: %v", err)
+	}
+
+	want := netlink.Message{
+		Header: netlink.Header{
+			Type:  16,
+			Flags: netlink.Request | netlink.Acknowledge,
+		},
+		Data: append([]byte{0x03, 0x01, 0x00, 0x00}, attrs...),
+	}
+
+	if diff := cmp.Diff(want, m); diff != "" {
+		t.Fatalf("unexpected message (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(4, hdrLen); diff != "" {
+		t.Fatalf("unexpected header length (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseMessageErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "short", args: []string{"16"}},
+		{name: "type", args: []string{"foo", "request"}},
+		{name: "flags", args: []string{"16", "foo"}},
+		{name: "hdr", args: []string{"16", "request", "hdr=zz"}},
+		{name: "attribute type", args: []string{"16", "request", "foo:u8=1"}},
+		{name: "attribute kind", args: []string{"16", "request", "1:u7=1"}},
+		{name: "attribute overflow", args: []string{"16", "request", "1:u8=256"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := parseMessage(tt.args); err == nil {
+				t.Fatal("expected an error, but none occurred")
+			}
+		})
+	}
+}
+
+func TestREPLRun(t *testing.T) {
+	restore := nltest.Intercept(func(req []netlink.Message) ([]netlink.Message, error) {
+		// Echo the family header and return a string attribute.
+		ae := netlink.NewAttributeEncoder()
+		ae.String(2, "hello")
+		b, err := ae.Encode()
+		if err != nil {
+			return nil, err
+		}
+
+		res := req[0]
+		res.Data = append(res.Data[:4:4], b...)
+		return []netlink.Message{res}, nil
+	})
+	defer restore()
+
+	var out bytes.Buffer
+	r := newREPL(&out)
+	defer r.close()
+
+	in := strings.NewReader("exec 16 request\ndial generic\nexec 16 request hdr=03010000\nbogus\nquit\n")
+	if err := r.run(in); err != nil {
+		t.Fatalf("failed to run: %v", err)
+	}
+
+	for _, s := range []string{
+		"error: not connected",
+		"dialed generic",
+		"hdr: 03010000",
+		`attr: type: 2, length: 10, data: "hello"`,
+		`error: unknown command "bogus"`,
+	} {
+		if !strings.Contains(out.String(), s) {
+			t.Fatalf("output does not contain %q:\n%s", s, out.String())
+		}
+	}
+}
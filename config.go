@@ -0,0 +1,121 @@
+package netlink
+
+import "time"
+
+// A ConnConfig is a snapshot of the effective configuration of a Conn, as
+// returned by Conn.Config. A ConnConfig is intended for logging and for
+// inclusion in bug reports.
+type ConnConfig struct {
+	// Family is the netlink family of the Conn, or -1 if unknown.
+	Family Family
+
+	// PID is the port ID assigned to the Conn by netlink.
+	PID uint32
+
+	// Groups is a bitmask of the multicast groups 1 through 32 which the Conn
+	// is subscribed to, in the same format as Config.Groups. Groups includes
+	// groups joined by Conn.JoinGroup and Conn.BindGroups.
+	Groups uint32
+
+	// Memberships are the IDs of all of the multicast groups which the Conn
+	// has joined in ascending order, as reported by Conn.Memberships. Unlike
+	// Groups, Memberships includes groups above 32. Memberships is nil if the
+	// operating system cannot report them.
+	Memberships []uint32
+
+	// Options are the ConnOptions which are enabled for the Conn, in
+	// ascending order.
+	Options []ConnOption
+
+	// UnknownOptions are the ConnOptions whose state could not be read from
+	// the operating system, such as on older kernels, in ascending order.
+	// They may or may not be enabled, and are not included in Options.
+	UnknownOptions []ConnOption
+
+	// ReadBuffer and WriteBuffer are the sizes of the operating system's
+	// receive and transmit buffers for the Conn, as reported by the operating
+	// system. Linux doubles the sizes set by Conn.SetReadBuffer and
+	// Conn.SetWriteBuffer to allow for bookkeeping overhead.
+	ReadBuffer  int
+	WriteBuffer int
+
+	// The remaining fields report the Config values applied by Dial. The
+	// Config fields which hold functions or interfaces, such as Clock, are not
+	// reported, nor is the no-op DisableNSLockThread.
+	JoinGroups            []uint32
+	NetNS                 int
+	VerifyThreadNetNS     bool
+	Strict                bool
+	BestEffortOptions     []ConnOption
+	DefaultFlags          HeaderFlags
+	NoReplyTimeout        time.Duration
+	DumpTimeout           time.Duration
+	DumpRetries           int
+	ReportDumpInterrupted bool
+	MaxReplies            int
+	MaxReplyBytes         int
+	SplitSendMessages     bool
+	StrictMarshal         bool
+	ErrorContext          bool
+}
+
+// A configGetter is a Socket which can report its effective configuration.
+type configGetter interface {
+	Socket
+	config() (sockConfig, error)
+}
+
+// sockConfig is the portion of a ConnConfig which is reported by a Socket.
+type sockConfig struct {
+	family           Family
+	groups           uint32
+	memberships      []uint32
+	options, unknown []ConnOption
+	rbuf, wbuf       int
+}
+
+// Config returns a snapshot of the effective configuration of the Conn. If the
+// Conn's Socket cannot report its configuration, such as a Socket passed to
+// NewConn, only the values known to the Conn itself are populated.
+func (c *Conn) Config() (ConnConfig, error) {
+	dc := c.dialConfig
+	cfg := ConnConfig{
+		Family:                c.family,
+		PID:                   c.pid,
+		JoinGroups:            append([]uint32(nil), dc.JoinGroups...),
+		NetNS:                 dc.NetNS,
+		VerifyThreadNetNS:     dc.VerifyThreadNetNS,
+		Strict:                dc.Strict,
+		BestEffortOptions:     append([]ConnOption(nil), dc.BestEffortOptions...),
+		DefaultFlags:          c.flags,
+		NoReplyTimeout:        c.noReplyTimeout,
+		DumpTimeout:           c.dumpTimeout,
+		DumpRetries:           c.dumpRetries,
+		ReportDumpInterrupted: dc.ReportDumpInterrupted,
+		MaxReplies:            c.maxReplies,
+		MaxReplyBytes:         c.maxReplyBytes,
+		SplitSendMessages:     c.splitSend,
+		StrictMarshal:         c.strictMarshal,
+		ErrorContext:          c.errorContext,
+	}
+
+	conn, ok := c.sock.(configGetter)
+	if !ok {
+		return cfg, nil
+	}
+
+	sc, err := conn.config()
+	if err != nil {
+		return ConnConfig{}, newOpError("config", err)
+	}
+
+	cfg.Family = sc.family
+	cfg.Groups = sc.groups
+	cfg.Memberships = sc.memberships
+	cfg.Options = sc.options
+	cfg.UnknownOptions = sc.unknown
+	cfg.ReadBuffer = sc.rbuf
+	cfg.WriteBuffer = sc.wbuf
+
+	return cfg, nil
+}
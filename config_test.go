@@ -0,0 +1,67 @@
+package netlink_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nltest"
+)
+
+func TestConnConfig(t *testing.T) {
+	restore := nltest.Intercept(func(_ []netlink.Message) ([]netlink.Message, error) {
+		return nil, nil
+	})
+	defer restore()
+
+	c, err := netlink.Dial(int(netlink.FamilyGeneric), &netlink.Config{
+		JoinGroups:        []uint32{1},
+		BestEffortOptions: []netlink.ConnOption{netlink.ExtendedAcknowledge},
+		DefaultFlags:      netlink.Acknowledge,
+		DumpTimeout:       time.Second,
+		MaxReplies:        100,
+		StrictMarshal:     true,
+		ErrorContext:      true,
+	})
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer c.Close()
+
+	cfg, err := c.Config()
+	if err != nil {
+		t.Fatalf("failed to get config: %v", err)
+	}
+
+	// nltest cannot report socket configuration, so only the values known to
+	// the Conn are populated. nltest always assigns PID 1.
+	want := netlink.ConnConfig{
+		Family:            netlink.FamilyGeneric,
+		PID:               1,
+		JoinGroups:        []uint32{1},
+		BestEffortOptions: []netlink.ConnOption{netlink.ExtendedAcknowledge},
+		DefaultFlags:      netlink.Acknowledge,
+		DumpTimeout:       time.Second,
+		MaxReplies:        100,
+		StrictMarshal:     true,
+		ErrorContext:      true,
+	}
+
+	if diff := cmp.Diff(want, cfg); diff != "" {
+		t.Fatalf("unexpected config (-want +got):\n%s", diff)
+	}
+}
+
+func TestConnOptionString(t *testing.T) {
+	for o, s := range map[netlink.ConnOption]string{
+		netlink.PacketInfo:      "packet-info",
+		netlink.GetStrictCheck:  "get-strict-check",
+		netlink.PassCredentials: "pass-credentials",
+		100:                     "unknown(100)",
+	} {
+		if diff := cmp.Diff(s, o.String()); diff != "" {
+			t.Fatalf("unexpected string (-want +got):\n%s", diff)
+		}
+	}
+}
@@ -1,7 +1,11 @@
 package netlink
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"math/rand"
+	"os"
 	"sync"
 	"sync/atomic"
 	"syscall"
@@ -23,6 +27,9 @@ import (
 type Conn struct {
 	// Atomics must come first.
 	//
+	// stats contains atomically incremented counters for Conn.Stats.
+	stats connStats
+
 	// seq is an atomically incremented integer used to provide sequence
 	// numbers when Conn.Send is called.
 	seq uint32
@@ -38,8 +45,85 @@ type Conn struct {
 	// pid is the PID assigned by netlink.
 	pid uint32
 
+	// family is the netlink family passed to Dial, or -1 if unknown.
+	family Family
+
+	// flags are the default HeaderFlags applied to every outgoing Message.
+	flags HeaderFlags
+
+	// noReplyTimeout bounds the time Execute will wait for a reply to a
+	// request which does not explicitly ask for one, if non-zero.
+	noReplyTimeout time.Duration
+
+	// dumpTimeout bounds the time spent receiving a single multi-part
+	// message, if non-zero.
+	dumpTimeout time.Duration
+
+	// dumpRetries is the number of times an interrupted dump is retried, if
+	// non-zero.
+	dumpRetries int
+
+	// reportInterrupted specifies whether interrupted dumps are reported
+	// using ErrDumpInterrupted.
+	reportInterrupted bool
+
+	// maxReplies and maxReplyBytes bound the replies returned by a single
+	// receive operation, if non-zero.
+	maxReplies, maxReplyBytes int
+
+	// splitSend specifies whether SendMessages sends each Message as its own
+	// datagram.
+	splitSend bool
+
+	// strictMarshal specifies whether outgoing Messages are checked using
+	// Message.VerifyRequest.
+	strictMarshal bool
+
+	// errorContext specifies whether an OpError returned by Execute retains
+	// the request and reply messages.
+	errorContext bool
+
+	// onOverrun, if not nil, is called when messages are lost due to
+	// ENOBUFS rather than returning an error from receive.
+	onOverrun func()
+
+	// features records the optional capabilities enabled by Dial.
+	features Features
+
+	// dialConfig records the Config passed to Dial, for Conn.Config.
+	dialConfig Config
+
+	// clock provides the current time and timers, or the system clock if nil.
+	clock Clock
+
 	// d provides debugging capabilities for a Conn if not nil.
 	d *debugger
+
+	// obs stores a *Observer which is notified of operations on the Conn.
+	obs atomic.Value
+
+	// gaps tracks state for gap detection on behalf of obs.
+	gaps gapState
+
+	// closed and unusable are set atomically when Close is called and when
+	// the Conn is reported to obs as unusable, respectively.
+	closed, unusable uint32
+
+	// filter stores a *typeFilter which restricts the types of received
+	// messages.
+	filter atomic.Value
+
+	// dupes stores a *dupFilter which discards duplicate received messages.
+	dupes atomic.Value
+
+	// msgs contains the state for Conn.Messages.
+	msgs messagesState
+
+	// inject contains the messages queued by Conn.InjectReceive.
+	inject injectState
+
+	// deadlines contains the deadlines set by the caller.
+	deadlines deadlineState
 }
 
 // A Socket is an operating-system specific implementation of netlink
@@ -56,18 +140,152 @@ type Socket interface {
 }
 
 // Dial dials a connection to netlink, using the specified netlink family.
-// Config specifies optional configuration for Conn. If config is nil, a default
-// configuration will be used.
+// The Family constants in this package may be used to specify family.
+// Config specifies optional configuration for Conn. If config is nil, the
+// configuration set by SetDefaultConfig is used, or a default configuration if
+// none is set.
 func Dial(family int, config *Config) (*Conn, error) {
 	// TODO(mdlayher): plumb in netlink.OpError wrapping?
 
-	// Use OS-specific dial() to create Socket.
-	c, pid, err := dial(family, config)
+	if config == nil {
+		config = defaultDialConfig()
+	}
+
+	// Use a DialInterceptor or OS-specific dial() to create Socket.
+	c, pid, err := interceptDial(family, config)
 	if err != nil {
 		return nil, err
 	}
 
-	return NewConn(c, pid), nil
+	nc := NewConn(c, pid)
+	nc.family = Family(family)
+	if config != nil {
+		nc.dialConfig = *config
+		nc.dialConfig.JoinGroups = append([]uint32(nil), config.JoinGroups...)
+		nc.dialConfig.BestEffortOptions = append([]ConnOption(nil), config.BestEffortOptions...)
+
+		nc.flags = config.DefaultFlags
+		nc.noReplyTimeout = config.NoReplyTimeout
+		nc.dumpTimeout = config.DumpTimeout
+		nc.dumpRetries = config.DumpRetries
+		nc.reportInterrupted = config.ReportDumpInterrupted || config.DumpRetries != 0
+		nc.maxReplies = config.MaxReplies
+		nc.maxReplyBytes = config.MaxReplyBytes
+		nc.splitSend = config.SplitSendMessages
+		nc.strictMarshal = config.StrictMarshal
+		nc.errorContext = config.ErrorContext
+		nc.onOverrun = config.OnOverrun
+		nc.clock = config.Clock
+		if config.Observer != nil {
+			nc.SetObserver(config.Observer)
+		}
+
+		if err := nc.enableBestEffort(config.BestEffortOptions); err != nil {
+			_ = nc.Close()
+			return nil, err
+		}
+	}
+
+	nc.debug(func(d *debugger) {
+		d.debugf(1, "dial: family: %s, pid: %d", Family(family), pid)
+	})
+
+	return nc, nil
+}
+
+// A DialInterceptor is a function which can substitute the Socket and PID used
+// by a call to Dial, such as to redirect Dial to a test Socket. The Config
+// passed to Dial is also passed to the DialInterceptor and its remaining
+// options are applied to the resulting Conn as usual.
+//
+// If the DialInterceptor returns a nil Socket and a nil error, Dial creates a
+// netlink socket as it normally would.
+type DialInterceptor func(family int, config *Config) (Socket, uint32, error)
+
+var (
+	dialMu          sync.RWMutex
+	dialInterceptor DialInterceptor
+)
+
+// SetDialInterceptor registers fn as the process-wide DialInterceptor consulted
+// by every call to Dial, and returns the previously registered DialInterceptor
+// so that it can be restored later. If fn is nil, the DialInterceptor is
+// removed.
+//
+// SetDialInterceptor is intended for tests and sandboxing frameworks which must
+// redirect calls to Dial made by code they do not control. Most applications
+// should not use it.
+func SetDialInterceptor(fn DialInterceptor) DialInterceptor {
+	dialMu.Lock()
+	defer dialMu.Unlock()
+
+	prev := dialInterceptor
+	dialInterceptor = fn
+	return prev
+}
+
+// interceptDial creates a Socket using the DialInterceptor if one is set,
+// falling back to the OS-specific dial().
+func interceptDial(family int, config *Config) (Socket, uint32, error) {
+	dialMu.RLock()
+	fn := dialInterceptor
+	dialMu.RUnlock()
+
+	if fn != nil {
+		sock, pid, err := fn(family, config)
+		if err != nil {
+			return nil, 0, err
+		}
+		if sock != nil {
+			return sock, pid, nil
+		}
+	}
+
+	return dial(family, config)
+}
+
+var (
+	defaultMu     sync.RWMutex
+	defaultConfig *Config
+)
+
+// SetDefaultConfig registers a copy of config as the process-wide default
+// Config used by every call to Dial which passes a nil Config, and returns the
+// previously registered default so that it can be restored later. If config
+// is nil, the default is removed and Dial uses its built-in defaults.
+//
+// SetDefaultConfig allows applications which embed third-party packages that
+// dial netlink internally to enforce baseline tuning, such as buffer sizes,
+// strictness, or an Observer, for every Conn. DialWithOptions applies its
+// options to the default Config, but calls to Dial with a non-nil Config are
+// not affected. SetDefaultConfig should be called during program
+// initialization, before any Conns are dialed.
+func SetDefaultConfig(config *Config) *Config {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+
+	prev := defaultConfig
+	defaultConfig = nil
+	if config != nil {
+		cfg := *config
+		defaultConfig = &cfg
+	}
+
+	return prev
+}
+
+// defaultDialConfig returns a copy of the default Config set by
+// SetDefaultConfig, or nil if none is set.
+func defaultDialConfig() *Config {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+
+	if defaultConfig == nil {
+		return nil
+	}
+
+	cfg := *defaultConfig
+	return &cfg
 }
 
 // NewConn creates a Conn using the specified Socket and PID for netlink
@@ -87,10 +305,11 @@ func NewConn(sock Socket, pid uint32) *Conn {
 	}
 
 	return &Conn{
-		seq:  seq,
-		sock: sock,
-		pid:  pid,
-		d:    d,
+		seq:    seq,
+		sock:   sock,
+		pid:    pid,
+		family: -1,
+		d:      d,
 	}
 }
 
@@ -111,6 +330,10 @@ func (c *Conn) Close() error {
 	//
 	// We rely on the kernel to deal with concurrent operations to the netlink
 	// socket itself.
+	atomic.StoreUint32(&c.closed, 1)
+	c.stopMessages()
+	c.debug(func(d *debugger) { d.flush() })
+
 	return newOpError("close", c.sock.Close())
 }
 
@@ -124,27 +347,247 @@ func (c *Conn) Close() error {
 //
 // See the documentation of Send, Receive, and Validate for details about
 // each function.
+//
+// If the request sets none of the Acknowledge, Echo, or Dump flags, the kernel
+// may not send any reply and Execute will block indefinitely. Use Send for
+// requests which do not expect a reply, or set Config.NoReplyTimeout to bound
+// the amount of time Execute will wait.
 func (c *Conn) Execute(m Message) ([]Message, error) {
+	res, err := c.observeExecute(context.Background(), m, nil)
+	return res, err
+}
+
+// ExecuteContext is like Execute, but obeys cancelation of ctx while sending
+// the request and receiving its replies. If ctx is canceled or its deadline
+// is exceeded, the returned error wraps ctx.Err().
+//
+// If the Conn's Socket does not support cancelation, such as a Socket passed
+// to NewConn, ctx is only checked before each operation begins.
+//
+// If ctx has a deadline, it replaces any deadlines set by SetDeadline,
+// SetReadDeadline, or SetWriteDeadline while the operation is in progress.
+// The Conn's deadlines are restored afterward rather than cleared.
+func (c *Conn) ExecuteContext(ctx context.Context, m Message) ([]Message, error) {
+	return c.observeExecute(ctx, m, nil)
+}
+
+// ExecuteRaw is like Execute, but also returns the raw bytes of each datagram
+// received from netlink while receiving the replies, exactly as they were
+// read from the socket. ExecuteRaw is useful for applications which must hash,
+// store, or forward the exact bytes produced by the kernel.
+//
+// Raw datagrams include any messages which are not returned as replies, such
+// as the final "multi-part done" message or messages removed by
+// SetTypeFilter. The Data fields of the returned Messages may share memory with
+// the raw datagrams. If the Conn's Socket cannot expose raw datagrams, such as
+// a Socket passed to NewConn, each datagram is produced by marshaling the
+// messages received from the Socket.
+func (c *Conn) ExecuteRaw(m Message) ([]Message, [][]byte, error) {
+	rs := &receiveState{raw: true}
+	res, err := c.observeExecute(context.Background(), m, rs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return res, rs.datagrams, nil
+}
+
+// ExecuteArena is like Execute, but allocates the data of the replies from
+// arena where possible, rather than allocating memory for each datagram
+// received from netlink. This reduces the cost of garbage collection for
+// applications which frequently perform large dumps. The data of the replies
+// must no longer be used once arena.Reset is called.
+//
+// If the Conn's Socket cannot allocate from an Arena, such as a Socket passed
+// to NewConn, ExecuteArena is equivalent to Execute.
+func (c *Conn) ExecuteArena(m Message, arena *Arena) ([]Message, error) {
+	return c.observeExecute(context.Background(), m, &receiveState{arena: arena})
+}
+
+// observeExecute implements Execute and notifies the Observer of the
+// resulting Transaction.
+func (c *Conn) observeExecute(ctx context.Context, m Message, rs *receiveState) ([]Message, error) {
+	if o, _ := c.obs.Load().(*Observer); rs == nil && o != nil && o.Execute != nil {
+		// Summarize the replies for the Transaction.
+		rs = &receiveState{}
+	}
+
+	start := c.now()
+	req, res, err := c.execute(ctx, m, rs)
+
+	c.observe(func(o *Observer) {
+		if o.Execute == nil {
+			return
+		}
+
+		t := Transaction{
+			Request: req,
+			Replies: res,
+			Err:     err,
+			Start:   start,
+			End:     c.now(),
+		}
+		if rs != nil {
+			t.Parts, t.Bytes, t.Datagrams = rs.parts, rs.bytes, rs.received
+		}
+
+		o.Execute(t)
+	})
+
+	return res, err
+}
+
+// execute implements Execute, returning the request as it was sent along with
+// any replies.
+func (c *Conn) execute(ctx context.Context, m Message, rs *receiveState) (Message, []Message, error) {
 	// Acquire the write lock and invoke the internal implementations of Send
 	// and Receive which require the lock already be held.
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	req, err := c.lockedSend(m)
+	if !c.reportInterrupted {
+		return c.lockedExecute(ctx, m, rs)
+	}
+
+	if rs == nil {
+		rs = &receiveState{}
+	}
+
+	for i := 0; ; i++ {
+		rs.interrupted = false
+		req, res, err := c.lockedExecute(ctx, m, rs)
+		if !errors.Is(err, ErrDumpInterrupted) {
+			return req, res, err
+		}
+
+		// Replies which were already yielded cannot be taken back, so the
+		// request is never retried in that case.
+		if i >= c.dumpRetries || rs.yield != nil {
+			return req, res, err
+		}
+
+		c.debug(func(d *debugger) {
+			d.debugf(1, "execute: dump interrupted, retrying (%d/%d)", i+1, c.dumpRetries)
+		})
+
+		rs.datagrams, rs.errs = nil, nil
+		rs.parts, rs.bytes, rs.received = 0, 0, 0
+	}
+}
+
+// lockedExecute implements execute, but must be called with c.mu held.
+func (c *Conn) lockedExecute(ctx context.Context, m Message, rs *receiveState) (req Message, res []Message, err error) {
+	if c.errorContext {
+		defer func() { withRequest(err, req) }()
+	}
+
+	req, err = c.lockedSend(ctx, m)
 	if err != nil {
-		return nil, err
+		return m, nil, err
+	}
+	if rs != nil {
+		rs.req = req
+	}
+
+	var timedOut func() bool
+
+	if !expectsReply(req.Header.Flags) {
+		c.debug(func(d *debugger) {
+			d.debugf(1, "execute: request flags %s do not ask for a reply, Execute may block", req.Header.Flags)
+		})
+
+		switch _, ok := c.sock.(deadlineSetter); {
+		case c.noReplyTimeout <= 0:
+		case ok && c.clock == nil:
+			// Deadlines are always measured by the operating system clock.
+			if err := c.setReplyDeadline(time.Now().Add(c.noReplyTimeout)); err != nil {
+				return req, nil, err
+			}
+
+			// Restore the caller's deadline once this request is complete so
+			// it does not affect later operations.
+			defer c.restoreReadDeadline()
+		default:
+			// The Socket does not support deadlines, or the timeout must be
+			// measured using Clock, so stop waiting for a reply when the timer
+			// fires instead.
+			ctx, timedOut = c.replyTimer(ctx, c.noReplyTimeout)
+		}
 	}
 
-	res, err := c.lockedReceive()
+	res, err = c.lockedReceive(ctx, rs)
+	if timedOut != nil && timedOut() && err != nil {
+		return req, nil, newOpError("receive", os.ErrDeadlineExceeded)
+	}
 	if err != nil {
-		return nil, err
+		// Partial results may accompany ErrDumpTimeout.
+		return req, res, err
 	}
 
 	if err := Validate(req, res); err != nil {
-		return nil, err
+		return req, nil, err
 	}
 
-	return res, nil
+	return req, res, nil
+}
+
+// Dump sends a request to netlink and calls fn with each reply as it is
+// received, rather than returning all of the replies at once as Execute does.
+// Dump is intended for very large dumps, such as full routing or connection
+// tracking tables, which can then be processed using a constant amount of
+// memory. The final "multi-part done" message is not passed to fn.
+//
+// fn must not call methods on the Conn. If fn returns an error, Dump stops calling fn but
+// continues to receive and discard the remaining replies so the Conn remains
+// usable, and then returns the error.
+//
+// Dump obeys cancelation of ctx while sending the request and receiving its
+// replies, and otherwise behaves like Execute.
+func (c *Conn) Dump(ctx context.Context, m Message, fn func(m Message) error) error {
+	var (
+		rs   receiveState
+		ferr error
+	)
+
+	rs.yield = func(reply Message) {
+		if ferr != nil {
+			// Discard the remaining replies.
+			return
+		}
+
+		if ferr = Validate(rs.req, []Message{reply}); ferr != nil {
+			return
+		}
+
+		ferr = fn(reply)
+	}
+
+	_, err := c.observeExecute(ctx, m, &rs)
+	if ferr != nil {
+		return ferr
+	}
+
+	return err
+}
+
+// expectsReply reports whether a request with flags f explicitly asks netlink
+// to send a reply.
+func expectsReply(f HeaderFlags) bool {
+	// Note that Dump shares bits with Replace and Excl, so requests which set
+	// those flags will also be treated as expecting a reply.
+	return f&(Acknowledge|Echo|Dump) != 0
+}
+
+// setReplyDeadline sets a read deadline for Execute, if supported by the
+// Socket. It must be called with c.mu held.
+func (c *Conn) setReplyDeadline(t time.Time) error {
+	conn, ok := c.sock.(deadlineSetter)
+	if !ok {
+		// Nothing to do; rely on the Socket to produce a reply.
+		return nil
+	}
+
+	return newOpError("set-read-deadline", conn.SetReadDeadline(t))
 }
 
 // SendMessages sends multiple Messages to netlink. The handling of
@@ -157,25 +600,64 @@ func (c *Conn) SendMessages(msgs []Message) ([]Message, error) {
 
 	for i := range msgs {
 		c.fixMsg(&msgs[i], nlmsgLength(len(msgs[i].Data)))
+
+		if c.strictMarshal {
+			if err := msgs[i].VerifyRequest(); err != nil {
+				return nil, newOpError("send-messages", err)
+			}
+		}
 	}
 
 	c.debug(func(d *debugger) {
 		for _, m := range msgs {
-			d.debugf(1, "send msgs: %+v", m)
+			d.debugMessage(1, "send msgs", c.family, m)
 		}
 	})
 
-	if err := c.sock.SendMessages(msgs); err != nil {
+	if err := c.sockSendMessages(msgs); err != nil {
 		c.debug(func(d *debugger) {
 			d.debugf(1, "send msgs: err: %v", err)
 		})
+		c.checkUnusable(err)
 
-		return nil, newOpError("send-messages", err)
+		err = newOpError("send-messages", err)
+		c.observeError(err)
+		return nil, err
 	}
 
+	countMessages(&c.stats.messagesSent, &c.stats.bytesSent, msgs)
+	c.observeSend(msgs)
+
 	return msgs, nil
 }
 
+// A datagramSender is a Socket which can send multiple Messages as individual
+// datagrams in a single operation.
+type datagramSender interface {
+	Socket
+	sendDatagrams(msgs []Message) error
+}
+
+// sockSendMessages sends msgs using c.sock, either in a single datagram or as
+// individual datagrams if configured to do so.
+func (c *Conn) sockSendMessages(msgs []Message) error {
+	if !c.splitSend {
+		return c.sock.SendMessages(msgs)
+	}
+
+	if ds, ok := c.sock.(datagramSender); ok {
+		return ds.sendDatagrams(msgs)
+	}
+
+	for _, m := range msgs {
+		if err := c.sock.Send(m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // Send sends a single Message to netlink.  In most cases, a Header's Length,
 // Sequence, and PID fields should be set to 0, so they can be populated
 // automatically before the Message is sent.  On success, Send returns a copy
@@ -189,32 +671,61 @@ func (c *Conn) SendMessages(msgs []Message) ([]Message, error) {
 //
 // If Header.PID is 0, it will be automatically populated using a PID
 // assigned by netlink.
+//
+// Any Config.DefaultFlags are added to Header.Flags before sending.
 func (c *Conn) Send(m Message) (Message, error) {
 	// Wait for any concurrent calls to Execute to finish before proceeding.
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	return c.lockedSend(m)
+	return c.lockedSend(context.Background(), m)
+}
+
+// SendContext is like Send, but obeys cancelation of ctx while sending the
+// Message. If ctx is canceled or its deadline is exceeded, the returned error
+// wraps ctx.Err().
+//
+// If ctx has a deadline, it replaces any deadline set by SetDeadline or
+// SetWriteDeadline while the Message is sent. The Conn's write deadline is
+// restored afterward rather than cleared.
+func (c *Conn) SendContext(ctx context.Context, m Message) (Message, error) {
+	// Wait for any concurrent calls to Execute to finish before proceeding.
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.lockedSend(ctx, m)
 }
 
 // lockedSend implements Send, but must be called with c.mu acquired for reading.
 // We rely on the kernel to deal with concurrent reads and writes to the netlink
 // socket itself.
-func (c *Conn) lockedSend(m Message) (Message, error) {
+func (c *Conn) lockedSend(ctx context.Context, m Message) (Message, error) {
 	c.fixMsg(&m, nlmsgLength(len(m.Data)))
 
+	if c.strictMarshal {
+		if err := m.VerifyRequest(); err != nil {
+			return Message{}, newOpError("send", err)
+		}
+	}
+
 	c.debug(func(d *debugger) {
-		d.debugf(1, "send: %+v", m)
+		d.debugMessage(1, "send", c.family, m)
 	})
 
-	if err := c.sock.Send(m); err != nil {
+	if err := c.sockSend(ctx, m); err != nil {
 		c.debug(func(d *debugger) {
 			d.debugf(1, "send: err: %v", err)
 		})
+		c.checkUnusable(err)
 
-		return Message{}, newOpError("send", err)
+		err = newOpError("send", err)
+		c.observeError(err)
+		return Message{}, err
 	}
 
+	countMessages(&c.stats.messagesSent, &c.stats.bytesSent, []Message{m})
+	c.observeSend([]Message{m})
+
 	return m, nil
 }
 
@@ -228,45 +739,289 @@ func (c *Conn) Receive() ([]Message, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	return c.lockedReceive()
+	return c.lockedReceive(context.Background(), &receiveState{inject: true})
+}
+
+// ReceiveContext is like Receive, but obeys cancelation of ctx while waiting
+// for messages. If ctx is canceled or its deadline is exceeded, the returned
+// error wraps ctx.Err().
+//
+// If ctx has a deadline, it replaces any deadline set by SetDeadline or
+// SetReadDeadline while waiting for messages. The Conn's read deadline is
+// restored afterward rather than cleared.
+func (c *Conn) ReceiveContext(ctx context.Context) ([]Message, error) {
+	// Wait for any concurrent calls to Execute to finish before proceeding.
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.lockedReceive(ctx, &receiveState{inject: true})
+}
+
+// ReceiveBuf is like Receive, but receives messages into b where possible
+// rather than allocating memory for each datagram received from netlink, so
+// applications which receive many events can reuse a single buffer. The data
+// of the returned Messages refers to b and must no longer be used once b is
+// reused. If a datagram does not fit in the remaining space of b, memory is
+// allocated for it instead.
+//
+// If the Conn's Socket cannot receive into a buffer, such as a Socket passed
+// to NewConn, ReceiveBuf is equivalent to Receive.
+func (c *Conn) ReceiveBuf(b []byte) ([]Message, error) {
+	// Wait for any concurrent calls to Execute to finish before proceeding.
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.lockedReceive(context.Background(), &receiveState{arena: bufferArena(b), inject: true})
+}
+
+// A scatterReceiver is a Socket which can receive a datagram into separate
+// buffers for its header and payload.
+type scatterReceiver interface {
+	Socket
+	receiveScatter(h *Header, payload []byte) (int, error)
+}
+
+// ReceiveScatter receives a single datagram from netlink, placing the header
+// of its first message directly in the returned Header and the remainder of
+// the datagram in payload, without allocating memory. The returned slice
+// refers to payload and must no longer be used once payload is reused.
+// ReceiveScatter allows applications which only inspect headers, such as to
+// count or filter events, to reuse fixed-size pooled buffers and avoid
+// touching payload memory at all.
+//
+// The payload may contain further messages when the datagram carries more
+// than one, which can be parsed by the caller. If the datagram does not fit
+// in payload, its remainder is discarded and the returned error wraps a
+// *TruncatedError. A payload of os.Getpagesize() bytes is large enough for
+// any notification, but datagrams of dump replies may be larger.
+//
+// ReceiveScatter returns an error if the first message in the datagram is a
+// netlink error message, but otherwise performs no validation of the
+// messages. Received messages are not passed through filters such as
+// SetTypeFilter, counted in Stats, or reported to an Observer, and injected
+// messages are not returned. ReceiveScatter is not supported by a Socket
+// passed to NewConn.
+func (c *Conn) ReceiveScatter(payload []byte) (Header, []byte, error) {
+	sr, ok := c.sock.(scatterReceiver)
+	if !ok {
+		return Header{}, nil, notSupported("receive-scatter")
+	}
+
+	// Wait for any concurrent calls to Execute to finish before proceeding.
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var h Header
+	n, err := sr.receiveScatter(&h, payload)
+	c.checkUnusable(err)
+	if err != nil && !errors.Is(err, ErrTruncated) {
+		return Header{}, nil, newOpError("receive", err)
+	}
+
+	payload = payload[:n]
+	if h.Type == Error || h.Type == Done {
+		// Only consider the first message when checking for an error.
+		m := Message{Header: h, Data: payload}
+		if l := int(h.Length) - HeaderLen; l >= 0 && l < len(m.Data) {
+			m.Data = m.Data[:l]
+		}
+
+		if err := checkMessage(m); err != nil {
+			atomic.AddUint64(&c.stats.errors, 1)
+			return h, payload, err
+		}
+	}
+
+	return h, payload, newOpError("receive", err)
+}
+
+// A MessageInfo carries metadata about a received Message which is not part of
+// the Message itself, such as metadata reported by the kernel alongside the
+// datagram which contained the Message.
+type MessageInfo struct {
+	// Group is the ID of the multicast group on which the Message was
+	// received, or 0 if the Message was sent directly to the Conn. Group is
+	// only reported when the PacketInfo ConnOption is enabled.
+	Group uint32
+
+	// NSID is the ID of the network namespace from which the Message
+	// originated, from the point of view of the Conn's network namespace, or
+	// NetNSIDNotAssigned if the Message originated in the Conn's own network
+	// namespace. NSID is only reported when the ListenAllNSID ConnOption is
+	// enabled. See NetNSID and SetNetNSID to map IDs to namespaces.
+	NSID int32
+
+	// Credentials are the credentials of the process which sent the Message,
+	// or nil if none were reported. Credentials are only reported when the
+	// PassCredentials ConnOption is enabled.
+	Credentials *Credentials
+}
+
+// Credentials are the credentials of the sender of a Message, as reported by
+// the SCM_CREDENTIALS control message. Messages sent by the kernel carry zero
+// values for each field.
+type Credentials struct {
+	PID uint32
+	UID uint32
+	GID uint32
+}
+
+// ReceiveWithInfo is like Receive, but also returns a MessageInfo for each of
+// the Messages at the same index, such as to determine which multicast group
+// each Message was received on.
+//
+// If the Conn's Socket cannot report metadata, such as a Socket passed to
+// NewConn, each MessageInfo has a zero Group, an NSID of NetNSIDNotAssigned,
+// and nil Credentials.
+func (c *Conn) ReceiveWithInfo() ([]Message, []MessageInfo, error) {
+	// Wait for any concurrent calls to Execute to finish before proceeding.
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	rs := &receiveState{info: true, inject: true}
+	msgs, err := c.lockedReceive(context.Background(), rs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return msgs, rs.infos, nil
+}
+
+// ReceiveLenient is like Receive, but does not fail when individual messages
+// cannot be decoded. Each malformed message is returned as a placeholder
+// Message containing whatever header and data could be recovered, and the
+// returned slice of DecodeErrors holds the reason at the same index. Entries
+// for well-formed messages are nil.
+//
+// ReceiveLenient is useful for applications which must process the remainder
+// of a large dump even if a single message is malformed. Messages which
+// indicate a netlink error still cause that error to be returned.
+func (c *Conn) ReceiveLenient() ([]Message, []*DecodeError, error) {
+	// Wait for any concurrent calls to Execute to finish before proceeding.
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	rs := &receiveState{lenient: true, inject: true}
+	msgs, err := c.lockedReceive(context.Background(), rs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return msgs, rs.errs, nil
 }
 
 // lockedReceive implements Receive, but must be called with c.mu acquired for reading.
 // We rely on the kernel to deal with concurrent reads and writes to the netlink
 // socket itself.
-func (c *Conn) lockedReceive() ([]Message, error) {
-	msgs, err := c.receive()
+func (c *Conn) lockedReceive(ctx context.Context, rs *receiveState) ([]Message, error) {
+	if rs == nil && c.reportInterrupted {
+		// Track whether a dump is interrupted.
+		rs = &receiveState{}
+	}
+
+	msgs, err := c.receive(ctx, rs)
 	if err != nil {
 		c.debug(func(d *debugger) {
 			d.debugf(1, "recv: err: %v", err)
 		})
+		c.observeError(err)
 
-		return nil, err
+		// Partial results may accompany ErrDumpTimeout.
+		return msgs, err
 	}
 
 	c.debug(func(d *debugger) {
 		for _, m := range msgs {
-			d.debugf(1, "recv: %+v", m)
+			d.debugMessage(1, "recv", c.family, m)
 		}
 	})
 
+	msgs = trimDone(msgs, rs)
+	if rs != nil && rs.interrupted && c.reportInterrupted {
+		return msgs, newOpError("receive", ErrDumpInterrupted)
+	}
+
+	return msgs, nil
+}
+
+// trimDone trims the final message with multi-part done indicator from msgs,
+// if present.
+func trimDone(msgs []Message, rs *receiveState) []Message {
 	// When using nltest, it's possible for zero messages to be returned by receive.
 	if len(msgs) == 0 {
-		return msgs, nil
+		return msgs
 	}
 
-	// Trim the final message with multi-part done indicator if
-	// present.
-	if m := msgs[len(msgs)-1]; m.Header.Flags&Multi != 0 && m.Header.Type == Done {
-		return msgs[:len(msgs)-1], nil
+	m := msgs[len(msgs)-1]
+	if m.Header.Flags&Multi == 0 || m.Header.Type != Done {
+		return msgs
 	}
 
-	return msgs, nil
+	if rs != nil && rs.lenient {
+		// Placeholders for malformed messages are never trimmed.
+		n := len(rs.errs) - 1
+		if rs.errs[n] != nil {
+			return msgs
+		}
+
+		rs.errs = rs.errs[:n]
+	}
+	if rs != nil && rs.info {
+		rs.infos = rs.infos[:len(rs.infos)-1]
+	}
+
+	return msgs[:len(msgs)-1]
+}
+
+// A receiveState carries optional state through an internal receive
+// operation. A nil *receiveState is valid and uses the default behavior.
+type receiveState struct {
+	// raw specifies whether the raw bytes of each received datagram should be
+	// collected in datagrams.
+	raw       bool
+	datagrams [][]byte
+
+	// lenient specifies whether malformed messages should be returned as
+	// placeholders with a corresponding DecodeError in errs, rather than
+	// causing receive to fail. errs has the same length as the Messages
+	// returned by receive.
+	lenient bool
+	errs    []*DecodeError
+
+	// info specifies whether metadata about each received message should be
+	// collected in infos, which has the same length as the Messages returned
+	// by receive. last is the metadata of the most recently received
+	// datagram.
+	info  bool
+	infos []MessageInfo
+	last  MessageInfo
+
+	// arena, if not nil, is used to allocate received datagrams.
+	arena *Arena
+
+	// yield, if not nil, is called with each message as it is received rather
+	// than collecting the messages to return from receive. req is the request
+	// whose replies are being received, set by execute.
+	yield func(m Message)
+	req   Message
+
+	// interrupted is set when a received message carries the DumpInterrupted
+	// flag.
+	interrupted bool
+
+	// inject specifies whether messages queued by InjectReceive may be
+	// returned in place of received messages.
+	inject bool
+
+	// parts and bytes are the number and total length of the accepted
+	// messages, excluding any final "multi-part done" message, and received
+	// is the number of datagrams received.
+	parts, bytes, received int
 }
 
 // receive is the internal implementation of Conn.Receive, which can be called
 // recursively to handle multi-part messages.
-func (c *Conn) receive() ([]Message, error) {
+func (c *Conn) receive(ctx context.Context, rs *receiveState) ([]Message, error) {
 	// NB: All non-nil errors returned from this function *must* be of type
 	// OpError in order to maintain the appropriate contract with callers of
 	// this package.
@@ -274,20 +1029,133 @@ func (c *Conn) receive() ([]Message, error) {
 	// This contract also applies to functions called within this function,
 	// such as checkMessage.
 
-	var res []Message
+	var (
+		lenient = rs != nil && rs.lenient
+		info    = rs != nil && rs.info
+		inject  = rs != nil && rs.inject
+	)
+
+	var yield func(m Message)
+	if rs != nil {
+		yield = rs.yield
+	}
+
+	// dumpCtx and dumpTimedOut enforce the dump timeout, if any, once the
+	// first datagram of a multi-part message has arrived.
+	var (
+		dumpCtx      = ctx
+		dumpTimedOut func() bool
+	)
+	defer func() {
+		if dumpTimedOut != nil {
+			dumpTimedOut()
+		}
+	}()
+
+	// size is the total length of the messages in res, and tooLarge is set
+	// once the replies exceed the configured limits.
+	var (
+		res      []Message
+		accepted int
+		size     int
+		tooLarge bool
+	)
+
+	// idle is cleared once a multi-part message is in progress, after which
+	// injected messages must wait for the next receive operation.
+	idle := true
 	for {
-		msgs, err := c.sock.Receive()
+		rctx, woken := dumpCtx, func() bool { return false }
+		if inject && idle {
+			if msgs, ok := c.inject.take(rs); ok {
+				return msgs, nil
+			}
+
+			var ok bool
+			rctx, woken, ok = c.inject.wait(dumpCtx)
+			if !ok {
+				// Messages were injected since checking above.
+				continue
+			}
+		}
+
+		msgs, bad, b, err := c.sockReceive(rctx, rs)
+		if woken() {
+			// Undo the interruption of the receive operation, if any, before
+			// returning the injected messages.
+			c.resumeRead()
+			if err != nil && ctx.Err() == nil {
+				continue
+			}
+		}
+
+		c.detectGaps(msgs, err)
+		c.checkUnusable(err)
 		if err != nil {
+			if isOverrun(err) {
+				atomic.AddUint64(&c.stats.overruns, 1)
+
+				// Unless a multi-part message was interrupted, let the
+				// application resynchronize and keep waiting for messages.
+				if c.onOverrun != nil && dumpTimedOut == nil && accepted == 0 {
+					c.debug(func(d *debugger) {
+						d.debugf(1, "recv: overrun, continuing")
+					})
+
+					c.onOverrun()
+					continue
+				}
+			}
+			if dumpTimedOut != nil && dumpTimedOut() {
+				return res, newOpError("receive", ErrDumpTimeout)
+			}
+
 			return nil, newOpError("receive", err)
 		}
 
+		if rs != nil {
+			rs.received++
+			if rs.raw {
+				rs.datagrams = append(rs.datagrams, b)
+			}
+		}
+
+		countMessages(&c.stats.messagesReceived, &c.stats.bytesReceived, msgs)
+		c.observeReceive(msgs)
+
 		// If this message is multi-part, we will need to continue looping to
 		// drain all the messages from the socket.
 		var multi bool
 
-		for _, m := range msgs {
+		for i, m := range msgs {
+			if bad != nil && bad[i] != nil {
+				// Placeholder for a message which could not be framed.
+				continue
+			}
+
 			if err := checkMessage(m); err != nil {
-				return nil, err
+				var oerr *OpError
+				if !lenient || !errors.As(err, &oerr) || oerr.Err != errShortErrorMessage {
+					atomic.AddUint64(&c.stats.errors, 1)
+					if c.errorContext {
+						withReply(err, m)
+					}
+
+					return nil, err
+				}
+
+				// The message is malformed rather than indicating an error.
+				if bad == nil {
+					bad = make([]*DecodeError, len(msgs))
+				}
+				bad[i] = &DecodeError{Header: m.Header, Data: m.Data, Err: oerr.Err}
+				continue
+			}
+
+			c.checkWarning(m)
+
+			if rs != nil && m.Header.Flags&DumpInterrupted != 0 {
+				rs.interrupted = true
 			}
 
 			// Does this message indicate a multi-part message?
@@ -299,15 +1167,256 @@ func (c *Conn) receive() ([]Message, error) {
 			// Does this message indicate the last message in a series of
 			// multi-part messages from a single read?
 			multi = m.Header.Type != Done
+			if !multi {
+				atomic.AddUint64(&c.stats.dumpsCompleted, 1)
+			}
 		}
 
-		res = append(res, msgs...)
+		f, d := c.typeFilter(), c.dupFilter()
+		var now time.Time
+		if d != nil {
+			now = c.now()
+		}
+
+		n := accepted
+		for i, m := range msgs {
+			var derr *DecodeError
+			if bad != nil {
+				derr = bad[i]
+			}
+
+			if derr == nil && !f.accept(m) {
+				continue
+			}
+			if derr == nil && !d.accept(m, now) {
+				atomic.AddUint64(&c.stats.duplicates, 1)
+				continue
+			}
+
+			accepted++
+			if rs != nil && (m.Header.Flags&Multi == 0 || m.Header.Type != Done) {
+				rs.parts++
+				rs.bytes += nlmsgLength(len(m.Data))
+			}
+
+			if yield != nil {
+				// The final "multi-part done" message is never yielded.
+				if m.Header.Flags&Multi == 0 || m.Header.Type != Done {
+					yield(m)
+				}
+				continue
+			}
+
+			if tooLarge {
+				// Discard the remainder of the replies.
+				continue
+			}
+			if m.Header.Flags&Multi == 0 || m.Header.Type != Done {
+				size += nlmsgLength(len(m.Data))
+				if c.maxReplies > 0 && len(res) >= c.maxReplies || c.maxReplyBytes > 0 && size > c.maxReplyBytes {
+					tooLarge = true
+					res = nil
+					if lenient {
+						rs.errs = nil
+					}
+					if info {
+						rs.infos = nil
+					}
+					continue
+				}
+			}
+
+			res = append(res, m)
+			if lenient {
+				rs.errs = append(rs.errs, derr)
+			}
+			if info {
+				rs.infos = append(rs.infos, rs.last)
+			}
+		}
 
 		if !multi {
+			if accepted == 0 && n == 0 && len(msgs) > 0 {
+				// All messages were filtered, keep waiting for more.
+				continue
+			}
+			if tooLarge {
+				return nil, newOpError("receive", ErrReplyTooLarge)
+			}
+
 			// No more messages coming.
 			return res, nil
 		}
+
+		idle = false
+		if c.dumpTimeout > 0 && dumpTimedOut == nil {
+			dumpCtx, dumpTimedOut = c.replyTimer(ctx, c.dumpTimeout)
+		}
+	}
+}
+
+// checkWarning records and reports any extended acknowledgement warning
+// carried by a successful acknowledgement m.
+func (c *Conn) checkWarning(m Message) {
+	w, ok := parseWarning(m)
+	if !ok {
+		return
+	}
+
+	atomic.AddUint64(&c.stats.warnings, 1)
+
+	c.debug(func(d *debugger) {
+		d.debugf(1, "receive: warning: %q", w.Message)
+	})
+
+	c.observe(func(o *Observer) {
+		if o.Warning == nil {
+			return
+		}
+
+		o.Warning(w)
+	})
+}
+
+// parseWarning parses the extended acknowledgement warning carried by a
+// successful acknowledgement m, reporting false if m carries none.
+func parseWarning(m Message) (Warning, bool) {
+	a, ok, _ := parseAck(m)
+	if !ok || a.Errno != 0 || a.Message == "" {
+		return Warning{}, false
+	}
+
+	return Warning{
+		Message: a.Message,
+		Offset:  a.Offset,
+		Ack:     m,
+	}, true
+}
+
+// A contextSender is a Socket that supports context cancelation while sending
+// messages.
+type contextSender interface {
+	Socket
+	sendContext(ctx context.Context, m Message) error
+}
+
+// sockSend sends m using c.sock, obeying cancelation of ctx if the Socket
+// supports it.
+func (c *Conn) sockSend(ctx context.Context, m Message) error {
+	if cs, ok := c.sock.(contextSender); ok {
+		if ctx.Done() != nil {
+			// The Socket clears the write deadline when it arms one for ctx.
+			defer c.restoreWriteDeadline()
+		}
+
+		return cs.sendContext(ctx, m)
+	}
+
+	// The Socket cannot be interrupted, but we can at least avoid calling it
+	// once ctx is canceled.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return c.sock.Send(m)
+}
+
+// A contextReceiver is a Socket that supports context cancelation while
+// receiving messages.
+type contextReceiver interface {
+	Socket
+	receiveContext(ctx context.Context) ([]Message, error)
+}
+
+// A rawReceiver is a contextReceiver which can also return the raw bytes of
+// each received datagram, allocated from an optional Arena, and populate
+// an optional MessageInfo with metadata about the datagram.
+type rawReceiver interface {
+	contextReceiver
+	receiveDatagram(ctx context.Context, arena *Arena, info *MessageInfo) ([]byte, error)
+}
+
+// sockReceive receives messages from c.sock, obeying cancelation of ctx if
+// the Socket supports it. If rs requests raw datagrams, sockReceive also
+// returns the raw bytes of the received datagram. If rs is lenient, a message
+// which cannot be framed is returned as a placeholder Message with a
+// corresponding DecodeError.
+func (c *Conn) sockReceive(ctx context.Context, rs *receiveState) ([]Message, []*DecodeError, []byte, error) {
+	var (
+		raw     = rs != nil && rs.raw
+		lenient = rs != nil && rs.lenient
+		arena   *Arena
+	)
+	if rs != nil {
+		arena = rs.arena
+	}
+
+	var info *MessageInfo
+	if rs != nil && rs.info {
+		rs.last = MessageInfo{NSID: NetNSIDNotAssigned}
+		info = &rs.last
+	}
+
+	if _, ok := c.sock.(contextReceiver); ok && ctx.Done() != nil {
+		// The Socket clears the read deadline when it arms one for ctx.
+		defer c.restoreReadDeadline()
+	}
+
+	if rr, ok := c.sock.(rawReceiver); ok && (raw || lenient || arena != nil || info != nil) {
+		b, err := rr.receiveDatagram(ctx, arena, info)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		msgs, derr := parseDatagram(b)
+		if derr == nil {
+			return msgs, nil, b, nil
+		}
+		if !lenient {
+			return nil, nil, nil, derr.Err
+		}
+
+		// Append a placeholder for the remainder of the datagram.
+		bad := make([]*DecodeError, len(msgs)+1)
+		bad[len(msgs)] = derr
+		msgs = append(msgs, Message{Header: derr.Header, Data: derr.Data})
+
+		return msgs, bad, b, nil
+	}
+
+	var (
+		msgs []Message
+		err  error
+	)
+
+	if cr, ok := c.sock.(contextReceiver); ok {
+		msgs, err = cr.receiveContext(ctx)
+	} else {
+		// The Socket cannot be interrupted, but we can at least avoid calling
+		// it once ctx is canceled.
+		if err := ctx.Err(); err != nil {
+			return nil, nil, nil, err
+		}
+
+		msgs, err = c.sock.Receive()
+	}
+	if err != nil || !raw {
+		return msgs, nil, nil, err
 	}
+
+	// The Socket cannot expose its raw datagrams, so produce them instead.
+	var b []byte
+	for _, m := range msgs {
+		// Sockets such as those used in tests may not report accurate lengths.
+		m.Header.Length = uint32(nlmsgAlign(nlmsgLength(len(m.Data))))
+
+		b, err = m.AppendBinary(b)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	return msgs, nil, b, nil
 }
 
 // A groupJoinLeaver is a Socket that supports joining and leaving
@@ -338,6 +1447,69 @@ func (c *Conn) LeaveGroup(group uint32) error {
 	return newOpError("leave-group", conn.LeaveGroup(group))
 }
 
+// A membershipLister is a Socket that can report the netlink multicast groups
+// it has joined.
+type membershipLister interface {
+	Socket
+	memberships() ([]uint32, error)
+}
+
+// Memberships returns the IDs of the netlink multicast groups which the Conn
+// has joined in ascending order, including groups joined using Config.Groups,
+// Config.JoinGroups, JoinGroup, and BindGroups. Memberships is useful for
+// verifying group subscriptions when debugging missed notifications.
+func (c *Conn) Memberships() ([]uint32, error) {
+	conn, ok := c.sock.(membershipLister)
+	if !ok {
+		return nil, notSupported("memberships")
+	}
+
+	groups, err := conn.memberships()
+	if err != nil {
+		return nil, newOpError("memberships", err)
+	}
+
+	return groups, nil
+}
+
+// A groupBinder is a Socket that supports re-binding to a mask of netlink
+// multicast groups.
+type groupBinder interface {
+	Socket
+	bindGroups(groups uint32) error
+}
+
+// BindGroups subscribes the Conn to each of the netlink multicast groups
+// specified in the bitmask groups, in the same format as Config.Groups. This
+// allows an application to Dial a Conn before it knows which groups it must
+// subscribe to. Existing group memberships are retained.
+//
+// Where supported, the Conn is re-bound using the combined group mask.
+// Otherwise, each group is joined individually as if by calling JoinGroup.
+func (c *Conn) BindGroups(groups uint32) error {
+	if conn, ok := c.sock.(groupBinder); ok {
+		return newOpError("bind-groups", conn.bindGroups(groups))
+	}
+
+	conn, ok := c.sock.(groupJoinLeaver)
+	if !ok {
+		return notSupported("bind-groups")
+	}
+
+	// Bit N of the mask corresponds to group ID N+1.
+	for i := uint32(0); i < 32; i++ {
+		if groups&(1<<i) == 0 {
+			continue
+		}
+
+		if err := conn.JoinGroup(i + 1); err != nil {
+			return newOpError("bind-groups", err)
+		}
+	}
+
+	return nil
+}
+
 // A bpfSetter is a Socket that supports setting and removing BPF filters.
 type bpfSetter interface {
 	Socket
@@ -373,6 +1545,16 @@ type deadlineSetter interface {
 	SetWriteDeadline(time.Time) error
 }
 
+// A deadlineState records the deadlines set by the caller, so they can be
+// restored after the Conn overrides them.
+type deadlineState struct {
+	mu          sync.Mutex
+	read, write time.Time
+	// interrupted is set while a receive operation is being interrupted by
+	// InjectReceive, during which the read deadline must not be applied.
+	interrupted bool
+}
+
 // SetDeadline sets the read and write deadlines associated with the connection.
 func (c *Conn) SetDeadline(t time.Time) error {
 	conn, ok := c.sock.(deadlineSetter)
@@ -380,6 +1562,14 @@ func (c *Conn) SetDeadline(t time.Time) error {
 		return notSupported("set-deadline")
 	}
 
+	c.deadlines.mu.Lock()
+	defer c.deadlines.mu.Unlock()
+
+	c.deadlines.read, c.deadlines.write = t, t
+	if c.deadlines.interrupted {
+		return newOpError("set-deadline", conn.SetWriteDeadline(t))
+	}
+
 	return newOpError("set-deadline", conn.SetDeadline(t))
 }
 
@@ -390,6 +1580,14 @@ func (c *Conn) SetReadDeadline(t time.Time) error {
 		return notSupported("set-read-deadline")
 	}
 
+	c.deadlines.mu.Lock()
+	defer c.deadlines.mu.Unlock()
+
+	c.deadlines.read = t
+	if c.deadlines.interrupted {
+		return nil
+	}
+
 	return newOpError("set-read-deadline", conn.SetReadDeadline(t))
 }
 
@@ -400,9 +1598,75 @@ func (c *Conn) SetWriteDeadline(t time.Time) error {
 		return notSupported("set-write-deadline")
 	}
 
+	c.deadlines.mu.Lock()
+	defer c.deadlines.mu.Unlock()
+
+	c.deadlines.write = t
 	return newOpError("set-write-deadline", conn.SetWriteDeadline(t))
 }
 
+// interruptRead interrupts a blocked receive operation by setting a read
+// deadline in the past, if supported. The read deadline set by the caller is
+// reapplied by resumeRead.
+func (c *Conn) interruptRead() {
+	conn, ok := c.sock.(deadlineSetter)
+	if !ok {
+		return
+	}
+
+	c.deadlines.mu.Lock()
+	defer c.deadlines.mu.Unlock()
+
+	c.deadlines.interrupted = true
+	_ = conn.SetReadDeadline(time.Unix(0, 1))
+}
+
+// resumeRead reapplies the read deadline set by the caller once a receive
+// operation interrupted by interruptRead is complete.
+func (c *Conn) resumeRead() {
+	conn, ok := c.sock.(deadlineSetter)
+	if !ok {
+		return
+	}
+
+	c.deadlines.mu.Lock()
+	defer c.deadlines.mu.Unlock()
+
+	c.deadlines.interrupted = false
+	_ = conn.SetReadDeadline(c.deadlines.read)
+}
+
+// restoreReadDeadline reapplies the read deadline set by the caller after it
+// was replaced by the Conn or cleared by a Socket which obeys cancelation,
+// unless a receive operation is being interrupted.
+func (c *Conn) restoreReadDeadline() {
+	conn, ok := c.sock.(deadlineSetter)
+	if !ok {
+		return
+	}
+
+	c.deadlines.mu.Lock()
+	defer c.deadlines.mu.Unlock()
+
+	if !c.deadlines.interrupted {
+		_ = conn.SetReadDeadline(c.deadlines.read)
+	}
+}
+
+// restoreWriteDeadline reapplies the write deadline set by the caller after it
+// was cleared by a Socket which obeys cancelation.
+func (c *Conn) restoreWriteDeadline() {
+	conn, ok := c.sock.(deadlineSetter)
+	if !ok {
+		return
+	}
+
+	c.deadlines.mu.Lock()
+	defer c.deadlines.mu.Unlock()
+
+	_ = conn.SetWriteDeadline(c.deadlines.write)
+}
+
 // A ConnOption is a boolean option that may be set for a Conn.
 type ConnOption int
 
@@ -416,8 +1680,37 @@ const (
 	CapAcknowledge
 	ExtendedAcknowledge
 	GetStrictCheck
+
+	// PassCredentials is equivalent to the SO_PASSCRED socket option, and
+	// reports the credentials of the sender of each Message in
+	// MessageInfo.Credentials.
+	PassCredentials
 )
 
+// String returns the string representation of a ConnOption.
+func (o ConnOption) String() string {
+	switch o {
+	case PacketInfo:
+		return "packet-info"
+	case BroadcastError:
+		return "broadcast-error"
+	case NoENOBUFS:
+		return "no-enobufs"
+	case ListenAllNSID:
+		return "listen-all-nsid"
+	case CapAcknowledge:
+		return "cap-acknowledge"
+	case ExtendedAcknowledge:
+		return "extended-acknowledge"
+	case GetStrictCheck:
+		return "get-strict-check"
+	case PassCredentials:
+		return "pass-credentials"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(o))
+	}
+}
+
 // An optionSetter is a Socket that supports setting netlink options.
 type optionSetter interface {
 	Socket
@@ -492,8 +1785,39 @@ func (c *Conn) SyscallConn() (syscall.RawConn, error) {
 	return sc.SyscallConn()
 }
 
+// A filer is a Socket which can duplicate its file descriptor.
+type filer interface {
+	Socket
+	file() (*os.File, error)
+}
+
+// File returns a copy of the underlying netlink socket as an *os.File, such
+// as to pass the socket to another process or to add it to an external event
+// loop. It is the caller's responsibility to close the File when finished.
+// Closing the Conn does not affect the File, and vice versa.
+//
+// The returned File's file descriptor is a duplicate of the Conn's, so
+// changes to socket state made using either one, such as joined multicast
+// groups and socket options, affect both. Reading from or writing to the File
+// while the Conn is in use will interfere with the Conn's operations.
+func (c *Conn) File() (*os.File, error) {
+	f, ok := c.sock.(filer)
+	if !ok {
+		return nil, notSupported("file")
+	}
+
+	file, err := f.file()
+	if err != nil {
+		return nil, newOpError("file", err)
+	}
+
+	return file, nil
+}
+
 // fixMsg updates the fields of m using the logic specified in Send.
 func (c *Conn) fixMsg(m *Message, ml int) {
+	m.Header.Flags |= c.flags
+
 	if m.Header.Length == 0 {
 		m.Header.Length = uint32(nlmsgAlign(ml))
 	}
@@ -540,9 +1864,17 @@ func Validate(request Message, replies []Message) error {
 // Config contains options for a Conn.
 type Config struct {
 	// Groups is a bitmask which specifies multicast groups. If set to 0,
-	// no multicast group subscriptions will be made.
+	// no multicast group subscriptions will be made. Groups can also be
+	// subscribed to after Dial using Conn.BindGroups.
 	Groups uint32
 
+	// JoinGroups specifies multicast groups by ID which are joined during
+	// Dial, as if Conn.JoinGroup were called for each, before any messages
+	// for those groups can be missed. Unlike Groups, JoinGroups can specify
+	// groups with IDs greater than 32, which are common for generic netlink
+	// families.
+	JoinGroups []uint32
+
 	// NetNS specifies the network namespace the Conn will operate in.
 	//
 	// If set (non-zero), Conn will enter the specified network namespace and
@@ -565,15 +1897,37 @@ type Config struct {
 	// DisableNSLockThread is a no-op.
 	//
 	// Deprecated: internal changes have made this option obsolete and it has no
-	// effect. Do not use.
+	// effect. Do not use. Applications which change the network namespace of
+	// the calling thread must call runtime.LockOSThread themselves; see
+	// VerifyThreadNetNS.
 	DisableNSLockThread bool
 
+	// VerifyThreadNetNS guards against creating a Conn in the wrong network
+	// namespace when NetNS is not set, and should only be set when the
+	// calling thread has entered a network namespace other than the one the
+	// process started in.
+	//
+	// Applications which enter a network namespace with setns(2) on the
+	// calling thread must call runtime.LockOSThread first. Otherwise, the
+	// calling goroutine may migrate to another thread at any time and Dial will
+	// silently create a Conn in that thread's network namespace instead.
+	//
+	// If set, Dial verifies that the calling thread is in a network namespace
+	// other than the one the process started in, and returns an error if not, which
+	// typically means runtime.LockOSThread was not called. Neither this check
+	// nor Dial require privileges; if the caller has CAP_NET_ADMIN, Dial also
+	// verifies that the socket itself was created in the thread's network
+	// namespace. It has no effect if NetNS is set or if network namespaces
+	// are disabled.
+	VerifyThreadNetNS bool
+
 	// PID specifies the port ID used to bind the netlink socket. If set to 0,
 	// the kernel will assign a port ID on the caller's behalf.
 	//
 	// Most callers should leave this field set to 0. This option is intended
 	// for advanced use cases where the kernel expects a fixed unicast address
-	// destination for netlink messages.
+	// destination for netlink messages. If another socket of the same family
+	// has already bound the port ID, Dial returns a *PortInUseError.
 	PID uint32
 
 	// Strict applies a more strict default set of options to the Conn,
@@ -590,4 +1944,139 @@ type Config struct {
 	// When possible, setting Strict to true is recommended for applications
 	// running on modern Linux kernels.
 	Strict bool
+
+	// BestEffortOptions specifies ConnOptions which are enabled by Dial if
+	// they are supported. Unlike Strict, an option which is not supported by
+	// the kernel does not cause Dial to return an error, so applications which
+	// target a range of kernel versions can opt in to newer options. The
+	// outcome for each option is reported by Conn.Features.
+	BestEffortOptions []ConnOption
+
+	// DefaultFlags specifies HeaderFlags which are added to the flags of every
+	// Message sent by the Conn, such as Request|Acknowledge. This allows call
+	// sites to specify only the flags which are specific to an operation.
+	//
+	// Flags cannot be removed on a per-message basis, so only flags which are
+	// appropriate for every request should be set here.
+	DefaultFlags HeaderFlags
+
+	// NoReplyTimeout, if non-zero, specifies the maximum amount of time that
+	// Execute will wait for a reply to a request which sets none of the
+	// Acknowledge, Echo, or Dump flags. Such requests may never receive a
+	// reply, which would otherwise cause Execute to block indefinitely.
+	//
+	// The timeout is implemented using a read deadline which temporarily
+	// replaces any read deadline previously set on the Conn until Execute
+	// returns. If Clock is set or the Conn's Socket does not support
+	// deadlines, the timeout is measured using Clock instead and stops Execute
+	// from waiting for further replies.
+	NoReplyTimeout time.Duration
+
+	// DumpTimeout, if non-zero, specifies the maximum amount of time that
+	// Execute and Receive will spend receiving a single multi-part message,
+	// measured from the arrival of its first datagram. This bounds the total
+	// time spent on a dump, independently of any read deadline which applies
+	// to each individual datagram.
+	//
+	// When DumpTimeout elapses, the messages received so far are returned
+	// along with an error which wraps ErrDumpTimeout.
+	DumpTimeout time.Duration
+
+	// DumpRetries, if non-zero, enables detection of dumps which were
+	// interrupted by a change in kernel state, as indicated by the
+	// DumpInterrupted flag, and which are therefore inconsistent. Execute
+	// transparently re-issues an interrupted dump request up to DumpRetries
+	// times. If DumpRetries is negative, requests are not re-issued.
+	//
+	// If the dump is still interrupted after all retries, the replies of the
+	// final attempt are returned along with an error which wraps
+	// ErrDumpInterrupted. Dump does not re-issue requests because the replies
+	// have already been passed to its callback, but also returns the error.
+	//
+	// Receive cannot re-issue requests, so it returns the error whenever
+	// DumpRetries is non-zero and a multi-part message is interrupted.
+	//
+	// If DumpRetries is zero, interrupted dumps are returned without an error
+	// unless ReportDumpInterrupted is set.
+	DumpRetries int
+
+	// ReportDumpInterrupted, if true, causes Execute, Dump, and Receive to
+	// return the replies of an interrupted dump along with an error which
+	// wraps ErrDumpInterrupted, without re-issuing the request. It is implied
+	// by a non-zero DumpRetries, which also re-issues interrupted requests.
+	ReportDumpInterrupted bool
+
+	// MaxReplies and MaxReplyBytes, if non-zero, limit the number of messages
+	// and the total length of the messages in bytes, including headers,
+	// which Execute and Receive will return for a single request. This
+	// protects applications which pass untrusted dump requests to the kernel
+	// from unbounded memory usage.
+	//
+	// When either limit is exceeded, the replies received so far are
+	// discarded and the remaining replies are received and discarded, so the
+	// Conn remains usable, before an error which wraps ErrReplyTooLarge is
+	// returned. The limits do not apply to Dump, which does not retain
+	// replies.
+	MaxReplies    int
+	MaxReplyBytes int
+
+	// SplitSendMessages specifies whether SendMessages sends each Message as
+	// its own datagram, rather than concatenating all of the Messages into a
+	// single datagram. Some netlink families process a datagram containing
+	// multiple messages differently from the same messages sent individually.
+	//
+	// On Linux, all of the datagrams are sent using a single sendmmsg system
+	// call where possible.
+	SplitSendMessages bool
+
+	// StrictMarshal specifies whether each Message sent by the Conn is first
+	// checked using Message.VerifyRequest, after applying DefaultFlags and
+	// computing its length. Malformed requests are rejected with a
+	// descriptive error before they are sent, rather than by the kernel with
+	// a less descriptive error or not at all.
+	StrictMarshal bool
+
+	// ErrorContext specifies whether an *OpError returned by Execute retains
+	// the request which was sent in its Request field, and whether an
+	// *OpError returned by Execute or Receive retains the reply which carried
+	// the error, if any, in its Reply field. Error reports then include the
+	// full context of the failed operation. The messages are retained for as
+	// long as the error, including any memory referenced by their Data.
+	ErrorContext bool
+
+	// OnOverrun, if not nil, is called when the kernel reports that messages
+	// were discarded because the socket receive buffer was full (ENOBUFS),
+	// typically because a multicast listener fell behind. Rather than
+	// returning an error, Receive calls OnOverrun and continues to wait for
+	// messages, so the Conn remains usable. The application should then
+	// resynchronize its state, such as by performing a dump using another
+	// Conn.
+	//
+	// OnOverrun is called synchronously by the goroutine which is receiving,
+	// and must not send or receive messages using the same Conn. If messages
+	// are lost while receiving a multi-part message, the error is returned as
+	// usual because the multi-part message is incomplete.
+	OnOverrun func()
+
+	// Clock, if not nil, provides the current time and timers used by the
+	// Conn, such as for measuring timeouts and the timestamps of Transactions
+	// reported to an Observer. If nil, the system clock is used. Deadlines set
+	// on the Conn's Socket are always measured by the system clock.
+	//
+	// Clock is primarily useful for testing time-dependent behavior with a
+	// DialInterceptor such as nltest.Intercept.
+	Clock Clock
+
+	// Observer, if not nil, is set as the Conn's Observer as if SetObserver
+	// were called immediately after Dial.
+	Observer *Observer
+
+	// ReadBuffer and WriteBuffer, if non-zero, specify the sizes of the
+	// operating system's receive and transmit buffers for the Conn, as if
+	// SetReadBuffer and SetWriteBuffer were called immediately after Dial.
+	//
+	// Applications which receive bursts of multicast messages may need a
+	// larger ReadBuffer to avoid ENOBUFS errors.
+	ReadBuffer  int
+	WriteBuffer int
 }
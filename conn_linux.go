@@ -5,11 +5,18 @@ package netlink
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
 	"syscall"
 	"time"
 	"unsafe"
 
+	"github.com/mdlayher/netlink/nlenc"
 	"github.com/mdlayher/socket"
 	"golang.org/x/net/bpf"
 	"golang.org/x/sys/unix"
@@ -17,6 +24,10 @@ import (
 
 var _ Socket = &conn{}
 
+// errInvalidControlMessage is returned when a control message received
+// alongside a datagram is too short to be decoded.
+var errInvalidControlMessage = errors.New("netlink: invalid control message")
+
 // A conn is the Linux implementation of a netlink sockets connection.
 type conn struct {
 	s *socket.Conn
@@ -29,6 +40,17 @@ func dial(family int, config *Config) (*conn, uint32, error) {
 		config = &Config{}
 	}
 
+	// Verify that the calling thread is still in the network namespace it
+	// entered, noting it for a later check of the socket itself.
+	var netns uint64
+	if config.VerifyThreadNetNS && config.NetNS == 0 {
+		ino, err := checkThreadNetNS()
+		if err != nil {
+			return nil, 0, err
+		}
+		netns = ino
+	}
+
 	// Prepare the netlink socket.
 	s, err := socket.Socket(
 		unix.AF_NETLINK,
@@ -41,11 +63,112 @@ func dial(family int, config *Config) (*conn, uint32, error) {
 		return nil, 0, err
 	}
 
-	return newConn(s, config)
+	if netns != 0 {
+		if err := verifyNetNS(s, netns); err != nil {
+			_ = s.Close()
+			return nil, 0, err
+		}
+	}
+
+	return newConn(s, family, config)
+}
+
+// errThreadNetNS is returned by Dial when Config.VerifyThreadNetNS is set and
+// the calling thread is not in a network namespace of its own.
+var errThreadNetNS = errors.New("netlink: calling thread is not in the network namespace it entered; runtime.LockOSThread must be called before changing a thread's network namespace")
+
+// initNetNS is the inode number of the network namespace the process started
+// in, noted during package initialization before the caller could enter a
+// network namespace. /proc/self/ns/net can't be used instead because it refers
+// to the main thread, which may itself have entered a network namespace.
+var initNetNS, _ = threadNetNS()
+
+// threadNetNS returns the inode number of the calling thread's network
+// namespace, or 0 if network namespaces are disabled.
+func threadNetNS() (uint64, error) {
+	return nsInode(fmt.Sprintf("/proc/self/task/%d/ns/net", unix.Gettid()))
+}
+
+// checkThreadNetNS verifies that the calling thread resides in a network
+// namespace other than the one the process started in, and returns the inode
+// number of the thread's network namespace, or 0 if network namespaces are
+// disabled.
+func checkThreadNetNS() (uint64, error) {
+	thread, err := threadNetNS()
+	if err != nil || thread == 0 || initNetNS == 0 {
+		return 0, err
+	}
+
+	if thread == initNetNS {
+		// The goroutine likely migrated from the thread which entered the
+		// network namespace.
+		return 0, errThreadNetNS
+	}
+
+	return thread, nil
+}
+
+// nsInode returns the inode number of the namespace file at path, or 0 if
+// namespaces are disabled.
+func nsInode(path string) (uint64, error) {
+	var st unix.Stat_t
+	err := unix.Stat(path, &st)
+	switch {
+	case errors.Is(err, unix.ENOENT):
+		// Network namespaces are not enabled on this system.
+		return 0, nil
+	case err != nil:
+		return 0, os.NewSyscallError("stat", err)
+	}
+
+	return st.Ino, nil
+}
+
+// verifyNetNS verifies that the socket s resides in the network namespace with
+// inode number ino, which catches a migration between checkThreadNetNS and the
+// creation of s. The check is skipped if the caller lacks the privileges to
+// query the socket's network namespace.
+func verifyNetNS(s *socket.Conn, ino uint64) error {
+	rc, err := s.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var (
+		st   unix.Stat_t
+		serr error
+	)
+
+	err = rc.Control(func(fd uintptr) {
+		nsfd, err := unix.IoctlRetInt(int(fd), unix.SIOCGSKNS)
+		if err != nil {
+			serr = os.NewSyscallError("ioctl", err)
+			return
+		}
+		defer unix.Close(nsfd)
+
+		serr = os.NewSyscallError("fstat", unix.Fstat(nsfd, &st))
+	})
+	if err != nil {
+		return err
+	}
+	switch {
+	case errors.Is(serr, unix.EPERM), errors.Is(serr, unix.ENOTTY):
+		// Unable to verify without CAP_NET_ADMIN, or unsupported.
+		return nil
+	case serr != nil:
+		return serr
+	}
+
+	if st.Ino != ino {
+		return errThreadNetNS
+	}
+
+	return nil
 }
 
 // newConn binds a connection to netlink using the input *socket.Conn.
-func newConn(s *socket.Conn, config *Config) (*conn, uint32, error) {
+func newConn(s *socket.Conn, family int, config *Config) (*conn, uint32, error) {
 	if config == nil {
 		config = &Config{}
 	}
@@ -61,6 +184,10 @@ func newConn(s *socket.Conn, config *Config) (*conn, uint32, error) {
 
 	if err := s.Bind(addr); err != nil {
 		_ = s.Close()
+		if config.PID != 0 && errors.Is(err, unix.EADDRINUSE) {
+			return nil, 0, portInUse(family, config, err)
+		}
+
 		return nil, 0, err
 	}
 
@@ -87,82 +214,447 @@ func newConn(s *socket.Conn, config *Config) (*conn, uint32, error) {
 		}
 	}
 
+	for _, g := range config.JoinGroups {
+		if err := c.JoinGroup(g); err != nil {
+			_ = c.Close()
+			return nil, 0, err
+		}
+	}
+
+	if config.ReadBuffer != 0 {
+		if err := c.SetReadBuffer(config.ReadBuffer); err != nil {
+			_ = c.Close()
+			return nil, 0, err
+		}
+	}
+
+	if config.WriteBuffer != 0 {
+		if err := c.SetWriteBuffer(config.WriteBuffer); err != nil {
+			_ = c.Close()
+			return nil, 0, err
+		}
+	}
+
 	return c, sa.(*unix.SockaddrNetlink).Pid, nil
 }
 
+// sock_diag constants from linux/sock_diag.h and linux/netlink_diag.h.
+const (
+	sockDiagByFamily = 20
+
+	sizeofNetlinkDiagReq = 20
+	sizeofNetlinkDiagMsg = 28
+)
+
+// portInUse produces a *PortInUseError for a socket of the specified family
+// which could not be bound to config.PID, identifying the process which holds
+// the port if possible.
+func portInUse(family int, config *Config, err error) error {
+	// The owner is informational, so failure to find it is not an error.
+	pid, _ := portOwner(family, config.PID, config.NetNS)
+	return &PortInUseError{
+		PortID: config.PID,
+		PID:    pid,
+		Err:    err,
+	}
+}
+
+// portOwner uses sock_diag to find the process which holds the netlink socket
+// of the specified family bound to port within the network namespace netns,
+// or the current network namespace if netns is 0. It returns 0 if no such
+// process is found.
+func portOwner(family int, port uint32, netns int) (int, error) {
+	// Bypass any DialInterceptor, which only applies to the caller's sockets.
+	s, pid, err := dial(unix.NETLINK_SOCK_DIAG, &Config{NetNS: netns})
+	if err != nil {
+		return 0, err
+	}
+	c := NewConn(s, pid)
+	defer c.Close()
+
+	// A netlink_diag_req for all sockets of the family, with no extra
+	// information requested.
+	req := make([]byte, sizeofNetlinkDiagReq)
+	req[0] = unix.AF_NETLINK
+	req[1] = uint8(family)
+
+	msgs, err := c.Execute(Message{
+		Header: Header{
+			Type:  sockDiagByFamily,
+			Flags: Request | Dump,
+		},
+		Data: req,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, m := range msgs {
+		// Match the netlink_diag_msg portid, and look up its inode.
+		if len(m.Data) < sizeofNetlinkDiagMsg || nlenc.Uint32(m.Data[4:8]) != port {
+			continue
+		}
+
+		return socketOwner(nlenc.Uint32(m.Data[16:20]))
+	}
+
+	return 0, nil
+}
+
+// socketOwner scans the file descriptors of all visible processes for the
+// socket with inode number ino, and returns the PID of the first process which
+// holds it, or 0 if none does.
+func socketOwner(ino uint32) (int, error) {
+	dirs, err := os.ReadDir("/proc")
+	if err != nil {
+		return 0, err
+	}
+
+	want := fmt.Sprintf("socket:[%d]", ino)
+	for _, d := range dirs {
+		pid, err := strconv.Atoi(d.Name())
+		if err != nil {
+			// Not a process directory.
+			continue
+		}
+
+		fddir := filepath.Join("/proc", d.Name(), "fd")
+		fds, err := os.ReadDir(fddir)
+		if err != nil {
+			// The process exited or its file descriptors are not visible.
+			continue
+		}
+
+		for _, fd := range fds {
+			if link, err := os.Readlink(filepath.Join(fddir, fd.Name())); err == nil && link == want {
+				return pid, nil
+			}
+		}
+	}
+
+	return 0, nil
+}
+
 // SendMessages serializes multiple Messages and sends them to netlink.
 func (c *conn) SendMessages(messages []Message) error {
-	var buf []byte
+	var n int
 	for _, m := range messages {
-		b, err := m.MarshalBinary()
+		n += nlmsgAlign(int(m.Header.Length))
+	}
+
+	buf := make([]byte, 0, n)
+	for _, m := range messages {
+		var err error
+		buf, err = m.AppendBinary(buf)
 		if err != nil {
 			return err
 		}
-
-		buf = append(buf, b...)
 	}
 
 	sa := &unix.SockaddrNetlink{Family: unix.AF_NETLINK}
+	start := traceStart()
 	_, err := c.s.Sendmsg(context.Background(), buf, nil, sa, 0)
+	if TraceEnabled {
+		traceSince(TraceSend, len(buf), start)
+	}
 	return err
 }
 
+// An mmsghdr is struct mmsghdr from sys/socket.h, which is not provided by
+// package unix.
+type mmsghdr struct {
+	hdr unix.Msghdr
+	len uint32
+}
+
+// sendDatagrams sends each of messages to netlink as its own datagram using
+// sendmmsg.
+func (c *conn) sendDatagrams(messages []Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	var (
+		sa   = unix.RawSockaddrNetlink{Family: unix.AF_NETLINK}
+		bufs = make([][]byte, len(messages))
+		iovs = make([]unix.Iovec, len(messages))
+		hdrs = make([]mmsghdr, len(messages))
+	)
+
+	for i, m := range messages {
+		b, err := m.MarshalBinary()
+		if err != nil {
+			return err
+		}
+
+		bufs[i] = b
+		iovs[i].Base = &b[0]
+		iovs[i].SetLen(len(b))
+
+		hdrs[i].hdr.Name = (*byte)(unsafe.Pointer(&sa))
+		hdrs[i].hdr.Namelen = unix.SizeofSockaddrNetlink
+		hdrs[i].hdr.Iov = &iovs[i]
+		hdrs[i].hdr.SetIovlen(1)
+	}
+
+	rc, err := c.s.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	// sendmmsg may send fewer datagrams than requested, so continue until
+	// all of them are sent.
+	start := traceStart()
+	for off := 0; off < len(hdrs); {
+		var (
+			n    uintptr
+			serr error
+		)
+
+		err := rc.Write(func(fd uintptr) bool {
+			for {
+				var errno unix.Errno
+				n, _, errno = unix.Syscall6(
+					unix.SYS_SENDMMSG,
+					fd,
+					uintptr(unsafe.Pointer(&hdrs[off])),
+					uintptr(len(hdrs)-off),
+					0, 0, 0,
+				)
+
+				switch errno {
+				case 0:
+					return true
+				case unix.EINTR:
+					continue
+				case unix.EAGAIN:
+					// Wait until the socket is writable.
+					return false
+				default:
+					serr = os.NewSyscallError("sendmmsg", errno)
+					return true
+				}
+			}
+		})
+		if err != nil {
+			return err
+		}
+		if serr != nil {
+			return serr
+		}
+
+		off += int(n)
+	}
+
+	if TraceEnabled {
+		var n int
+		for _, b := range bufs {
+			n += len(b)
+		}
+
+		traceSince(TraceSend, n, start)
+	}
+
+	// Keep the buffers referenced by the headers alive until sendmmsg is
+	// complete.
+	runtime.KeepAlive(bufs)
+	runtime.KeepAlive(&sa)
+	return nil
+}
+
 // Send sends a single Message to netlink.
-func (c *conn) Send(m Message) error {
+func (c *conn) Send(m Message) error { return c.sendContext(context.Background(), m) }
+
+// sendContext implements Send, but obeys cancelation of ctx.
+func (c *conn) sendContext(ctx context.Context, m Message) error {
 	b, err := m.MarshalBinary()
 	if err != nil {
 		return err
 	}
 
 	sa := &unix.SockaddrNetlink{Family: unix.AF_NETLINK}
-	_, err = c.s.Sendmsg(context.Background(), b, nil, sa, 0)
+	start := traceStart()
+	_, err = c.s.Sendmsg(ctx, b, nil, sa, 0)
+	if TraceEnabled {
+		traceSince(TraceSend, len(b), start)
+	}
+
 	return err
 }
 
 // Receive receives one or more Messages from netlink.
-func (c *conn) Receive() ([]Message, error) {
-	b := make([]byte, os.Getpagesize())
-	for {
-		// Peek at the buffer to see how many bytes are available.
-		//
-		// TODO(mdlayher): deal with OOB message data if available, such as
-		// when PacketInfo ConnOption is true.
-		n, _, _, _, err := c.s.Recvmsg(context.Background(), b, nil, unix.MSG_PEEK)
-		if err != nil {
+func (c *conn) Receive() ([]Message, error) { return c.receiveContext(context.Background()) }
+
+// receiveContext implements Receive, but obeys cancelation of ctx.
+func (c *conn) receiveContext(ctx context.Context) ([]Message, error) {
+	b, err := c.receiveDatagram(ctx, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseMessages(b)
+}
+
+// oobLen is the size of the buffer used to receive control messages, which is
+// large enough for all of the control messages the kernel may attach to a
+// netlink datagram: packet info, namespace ID, and credentials.
+var oobLen = unix.CmsgSpace(4)*2 + unix.CmsgSpace(unix.SizeofUcred)
+
+// receiveDatagram receives the raw bytes of a single datagram, which may
+// contain one or more messages, while obeying cancelation of ctx. The datagram
+// is allocated from arena. If info is not nil, it is populated using the
+// control messages which accompany the datagram.
+func (c *conn) receiveDatagram(ctx context.Context, arena *Arena, info *MessageInfo) ([]byte, error) {
+	b := arena.alloc(os.Getpagesize())
+
+	// Peek at the next datagram to learn its full length. With MSG_TRUNC, the
+	// kernel reports the length of the datagram even if it exceeds len(b).
+	n, _, _, _, err := c.s.Recvmsg(ctx, b, nil, unix.MSG_PEEK|unix.MSG_TRUNC)
+	if err != nil {
+		return nil, err
+	}
+
+	if n > len(b) {
+		// Not enough space, allocate exactly as much as is needed.
+		arena.trim(b, 0)
+		b = arena.alloc(n)
+	}
+
+	var oob []byte
+	if info != nil {
+		oob = make([]byte, oobLen)
+	}
+
+	// Read out all available messages. The datagram may differ from the one
+	// peeked at if another receive operation raced with this one, so report
+	// truncation rather than parsing a partial datagram.
+	start := traceStart()
+	n, oobn, _, _, err := c.s.Recvmsg(ctx, b, oob, unix.MSG_TRUNC)
+	if TraceEnabled {
+		traceSince(TraceReceive, n, start)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if n > len(b) {
+		arena.trim(b, 0)
+		return nil, &TruncatedError{Length: n, Received: len(b)}
+	}
+
+	if info != nil {
+		if err := parseInfo(oob[:oobn], info); err != nil {
 			return nil, err
 		}
+	}
 
-		// Break when we can read all messages
-		if n < len(b) {
-			break
-		}
+	return arena.trim(b, n), nil
+}
 
-		// Double in size if not enough bytes
-		b = make([]byte, len(b)*2)
+// receiveScatter receives a single datagram, placing its first HeaderLen
+// bytes directly in h and the remainder in payload. It reports the number of
+// bytes placed in payload, and a *TruncatedError if the remainder of the
+// datagram did not fit.
+func (c *conn) receiveScatter(h *Header, payload []byte) (int, error) {
+	rc, err := c.s.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+
+	// Header has the same layout as struct nlmsghdr, so the kernel can fill it
+	// in without an intermediate copy.
+	bufs := [][]byte{
+		(*[HeaderLen]byte)(unsafe.Pointer(h))[:],
+		payload,
 	}
 
-	// Read out all available messages
-	n, _, _, _, err := c.s.Recvmsg(context.Background(), b, nil, 0)
+	var (
+		n    int
+		rerr error
+	)
+
+	start := traceStart()
+	err = rc.Read(func(fd uintptr) bool {
+		for {
+			n, _, _, _, rerr = unix.RecvmsgBuffers(int(fd), bufs, nil, unix.MSG_TRUNC)
+			switch rerr {
+			case unix.EINTR:
+				continue
+			case unix.EAGAIN:
+				// Wait until the socket is readable.
+				return false
+			default:
+				return true
+			}
+		}
+	})
+	if TraceEnabled {
+		traceSince(TraceReceive, n, start)
+	}
 	if err != nil {
-		return nil, err
+		return 0, err
+	}
+	if rerr != nil {
+		return 0, os.NewSyscallError("recvmsg", rerr)
+	}
+	if n < HeaderLen {
+		return 0, errShortMessage
+	}
+
+	// With MSG_TRUNC, the kernel reports the full length of the datagram even
+	// if it exceeds the space available.
+	if n > HeaderLen+len(payload) {
+		return len(payload), &TruncatedError{Length: n, Received: HeaderLen + len(payload)}
 	}
 
-	raw, err := syscall.ParseNetlinkMessage(b[:nlmsgAlign(n)])
+	return n - HeaderLen, nil
+}
+
+// parseInfo populates info using the control messages in oob.
+func parseInfo(oob []byte, info *MessageInfo) error {
+	scms, err := unix.ParseSocketControlMessage(oob)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	msgs := make([]Message, 0, len(raw))
-	for _, r := range raw {
-		m := Message{
-			Header: sysToHeader(r.Header),
-			Data:   r.Data,
+	for _, scm := range scms {
+		if scm.Header.Level == unix.SOL_SOCKET && scm.Header.Type == unix.SCM_CREDENTIALS {
+			cred, err := unix.ParseUnixCredentials(&scm)
+			if err != nil {
+				return errInvalidControlMessage
+			}
+
+			info.Credentials = &Credentials{
+				PID: uint32(cred.Pid),
+				UID: cred.Uid,
+				GID: cred.Gid,
+			}
+			continue
+		}
+
+		if scm.Header.Level != unix.SOL_NETLINK {
+			continue
 		}
 
-		msgs = append(msgs, m)
+		switch scm.Header.Type {
+		case unix.NETLINK_PKTINFO:
+			// struct nl_pktinfo.
+			if len(scm.Data) < 4 {
+				return errInvalidControlMessage
+			}
+
+			info.Group = nlenc.Uint32(scm.Data[:4])
+		case unix.NETLINK_LISTEN_ALL_NSID:
+			// The kernel only reports an ID for messages which originated in
+			// another network namespace.
+			if len(scm.Data) < 4 {
+				return errInvalidControlMessage
+			}
+
+			info.NSID = nlenc.Int32(scm.Data[:4])
+		}
 	}
 
-	return msgs, nil
+	return nil
 }
 
 // Close closes the connection.
@@ -178,6 +670,83 @@ func (c *conn) LeaveGroup(group uint32) error {
 	return c.s.SetsockoptInt(unix.SOL_NETLINK, unix.NETLINK_DROP_MEMBERSHIP, int(group))
 }
 
+// bindGroups re-binds a conn to the union of its current multicast groups and
+// groups.
+func (c *conn) bindGroups(groups uint32) error {
+	sa, err := c.s.Getsockname()
+	if err != nil {
+		return err
+	}
+
+	// The kernel requires that the PID is unchanged when re-binding.
+	nsa := sa.(*unix.SockaddrNetlink)
+	return c.s.Bind(&unix.SockaddrNetlink{
+		Family: unix.AF_NETLINK,
+		Groups: nsa.Groups | groups,
+		Pid:    nsa.Pid,
+	})
+}
+
+// memberships lists the multicast groups joined by a conn.
+func (c *conn) memberships() ([]uint32, error) {
+	rc, err := c.s.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+
+	// The kernel reports a bitmask of groups as 32-bit words, and the number
+	// of bytes required to report all of them. Start with room for enough
+	// groups for most families and grow as needed.
+	words := make([]uint32, 4)
+	for {
+		var (
+			l    = uint32(len(words) * 4)
+			serr error
+		)
+
+		err := rc.Control(func(fd uintptr) {
+			_, _, errno := unix.Syscall6(
+				unix.SYS_GETSOCKOPT,
+				fd,
+				unix.SOL_NETLINK,
+				unix.NETLINK_LIST_MEMBERSHIPS,
+				uintptr(unsafe.Pointer(&words[0])),
+				uintptr(unsafe.Pointer(&l)),
+				0,
+			)
+			if errno != 0 {
+				serr = os.NewSyscallError("getsockopt", errno)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+		if serr != nil {
+			return nil, serr
+		}
+
+		if n := int(l+3) / 4; n > len(words) {
+			words = make([]uint32, n)
+			continue
+		}
+
+		words = words[:int(l+3)/4]
+		break
+	}
+
+	// Bit N of word W corresponds to group ID W*32+N+1.
+	var groups []uint32
+	for w, v := range words {
+		for i := uint32(0); i < 32; i++ {
+			if v&(1<<i) != 0 {
+				groups = append(groups, uint32(w)*32+i+1)
+			}
+		}
+	}
+
+	return groups, nil
+}
+
 // SetBPF attaches an assembled BPF program to a conn.
 func (c *conn) SetBPF(filter []bpf.RawInstruction) error { return c.s.SetBPF(filter) }
 
@@ -186,7 +755,7 @@ func (c *conn) RemoveBPF() error { return c.s.RemoveBPF() }
 
 // SetOption enables or disables a netlink socket option for the Conn.
 func (c *conn) SetOption(option ConnOption, enable bool) error {
-	o, ok := linuxOption(option)
+	level, o, ok := linuxOption(option)
 	if !ok {
 		// Return the typical Linux error for an unknown ConnOption.
 		return os.NewSyscallError("setsockopt", unix.ENOPROTOOPT)
@@ -197,7 +766,7 @@ func (c *conn) SetOption(option ConnOption, enable bool) error {
 		v = 1
 	}
 
-	return c.s.SetsockoptInt(unix.SOL_NETLINK, o, v)
+	return c.s.SetsockoptInt(level, o, v)
 }
 
 func (c *conn) SetDeadline(t time.Time) error      { return c.s.SetDeadline(t) }
@@ -215,37 +784,165 @@ func (c *conn) SetWriteBuffer(bytes int) error { return c.s.SetWriteBuffer(bytes
 // SyscallConn returns a raw network connection.
 func (c *conn) SyscallConn() (syscall.RawConn, error) { return c.s.SyscallConn() }
 
-// linuxOption converts a ConnOption to its Linux value.
-func linuxOption(o ConnOption) (int, bool) {
+// file duplicates the socket's file descriptor and returns it as an *os.File.
+func (c *conn) file() (*os.File, error) {
+	rc, err := c.s.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		fd    int
+		dfErr error
+	)
+	if err := rc.Control(func(sfd uintptr) {
+		fd, dfErr = unix.FcntlInt(sfd, unix.F_DUPFD_CLOEXEC, 0)
+	}); err != nil {
+		return nil, err
+	}
+	if dfErr != nil {
+		return nil, os.NewSyscallError("fcntl", dfErr)
+	}
+
+	return os.NewFile(uintptr(fd), "netlink"), nil
+}
+
+// config reports the effective configuration of a conn.
+func (c *conn) config() (sockConfig, error) {
+	proto, err := c.s.GetsockoptInt(unix.SOL_SOCKET, unix.SO_PROTOCOL)
+	if err != nil {
+		return sockConfig{}, err
+	}
+
+	sa, err := c.s.Getsockname()
+	if err != nil {
+		return sockConfig{}, err
+	}
+
+	sc := sockConfig{
+		family: Family(proto),
+		groups: sa.(*unix.SockaddrNetlink).Groups,
+	}
+
+	for o := PacketInfo; o <= PassCredentials; o++ {
+		level, opt, _ := linuxOption(o)
+		v, err := c.s.GetsockoptInt(level, opt)
+		if err != nil {
+			// Older kernels may not support reading every option.
+			if errors.Is(err, unix.ENOPROTOOPT) {
+				sc.unknown = append(sc.unknown, o)
+				continue
+			}
+
+			return sockConfig{}, err
+		}
+		if v != 0 {
+			sc.options = append(sc.options, o)
+		}
+	}
+
+	// NETLINK_LIST_MEMBERSHIPS requires Linux 4.2+.
+	sc.memberships, err = c.memberships()
+	if err != nil && !errors.Is(err, unix.ENOPROTOOPT) {
+		return sockConfig{}, err
+	}
+
+	if sc.rbuf, err = c.s.GetsockoptInt(unix.SOL_SOCKET, unix.SO_RCVBUF); err != nil {
+		return sockConfig{}, err
+	}
+	if sc.wbuf, err = c.s.GetsockoptInt(unix.SOL_SOCKET, unix.SO_SNDBUF); err != nil {
+		return sockConfig{}, err
+	}
+
+	return sc, nil
+}
+
+// linuxOption converts a ConnOption to its Linux socket option level and
+// value.
+func linuxOption(o ConnOption) (int, int, bool) {
 	switch o {
 	case PacketInfo:
-		return unix.NETLINK_PKTINFO, true
+		return unix.SOL_NETLINK, unix.NETLINK_PKTINFO, true
 	case BroadcastError:
-		return unix.NETLINK_BROADCAST_ERROR, true
+		return unix.SOL_NETLINK, unix.NETLINK_BROADCAST_ERROR, true
 	case NoENOBUFS:
-		return unix.NETLINK_NO_ENOBUFS, true
+		return unix.SOL_NETLINK, unix.NETLINK_NO_ENOBUFS, true
 	case ListenAllNSID:
-		return unix.NETLINK_LISTEN_ALL_NSID, true
+		return unix.SOL_NETLINK, unix.NETLINK_LISTEN_ALL_NSID, true
 	case CapAcknowledge:
-		return unix.NETLINK_CAP_ACK, true
+		return unix.SOL_NETLINK, unix.NETLINK_CAP_ACK, true
 	case ExtendedAcknowledge:
-		return unix.NETLINK_EXT_ACK, true
+		return unix.SOL_NETLINK, unix.NETLINK_EXT_ACK, true
 	case GetStrictCheck:
-		return unix.NETLINK_GET_STRICT_CHK, true
+		return unix.SOL_NETLINK, unix.NETLINK_GET_STRICT_CHK, true
+	case PassCredentials:
+		return unix.SOL_SOCKET, unix.SO_PASSCRED, true
 	default:
-		return 0, false
+		return 0, 0, false
 	}
 }
 
+// sysHeaderLayout reports whether the memory layout of syscall.NlMsghdr is
+// exactly the same as Header, so sysToHeader can use an unsafe cast.
+var sysHeaderLayout = func() bool {
+	var (
+		r syscall.NlMsghdr
+		h Header
+	)
+
+	return unsafe.Sizeof(r) == unsafe.Sizeof(h) &&
+		unsafe.Offsetof(r.Type) == unsafe.Offsetof(h.Type) &&
+		unsafe.Offsetof(r.Flags) == unsafe.Offsetof(h.Flags) &&
+		unsafe.Offsetof(r.Seq) == unsafe.Offsetof(h.Sequence) &&
+		unsafe.Offsetof(r.Pid) == unsafe.Offsetof(h.PID)
+}()
+
 // sysToHeader converts a syscall.NlMsghdr to a Header.
 func sysToHeader(r syscall.NlMsghdr) Header {
+	if !sysHeaderLayout {
+		return sysToHeaderFields(r)
+	}
+
 	// NB: the memory layout of Header and syscall.NlMsgHdr must be
 	// exactly the same for this unsafe cast to work
 	return *(*Header)(unsafe.Pointer(&r))
 }
 
+// sysToHeaderFields converts a syscall.NlMsghdr to a Header field by field,
+// for platforms where the layouts of the two types differ.
+func sysToHeaderFields(r syscall.NlMsghdr) Header {
+	return Header{
+		Length:   r.Len,
+		Type:     HeaderType(r.Type),
+		Flags:    HeaderFlags(r.Flags),
+		Sequence: r.Seq,
+		PID:      r.Pid,
+	}
+}
+
 // newError converts an error number from netlink into the appropriate
 // system call error for Linux.
 func newError(errno int) error {
 	return syscall.Errno(errno)
 }
+
+// isOverrun reports whether err indicates that the kernel discarded messages
+// due to a full socket receive buffer.
+func isOverrun(err error) bool {
+	return errors.Is(err, unix.ENOBUFS)
+}
+
+// isUnsupportedOption reports whether err indicates that the kernel does not
+// support a ConnOption.
+func isUnsupportedOption(err error) bool {
+	return errors.Is(err, unix.ENOPROTOOPT)
+}
+
+// isUnusable reports whether err indicates that a socket can no longer be
+// used.
+func isUnusable(err error) bool {
+	return errors.Is(err, unix.EBADF) ||
+		errors.Is(err, unix.ENOTSOCK) ||
+		errors.Is(err, os.ErrClosed) ||
+		errors.Is(err, net.ErrClosed)
+}
@@ -4,13 +4,15 @@
 package netlink_test
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"math/rand"
 	"net"
 	"os"
-	"os/exec"
 	"os/user"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -19,6 +21,7 @@ import (
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/mdlayher/netlink"
 	"github.com/mdlayher/netlink/nlenc"
+	"github.com/mdlayher/netlink/nltestenv"
 	"golang.org/x/net/bpf"
 	"golang.org/x/sys/unix"
 )
@@ -355,7 +358,7 @@ func TestIntegrationConnSetBuffersSyscallConn(t *testing.T) {
 		},
 		{
 			name:  "privileged",
-			check: skipUnprivileged,
+			check: func(t *testing.T) { nltestenv.SkipUnprivileged(t) },
 		},
 	}
 
@@ -425,6 +428,69 @@ func TestIntegrationConnSetBuffersSyscallConn(t *testing.T) {
 	}
 }
 
+func TestIntegrationConnConfigBuffers(t *testing.T) {
+	t.Parallel()
+
+	// Small enough to be permitted by default operating system limits.
+	const set = 16384
+
+	c, err := netlink.Dial(unix.NETLINK_GENERIC, &netlink.Config{
+		ReadBuffer:  set,
+		WriteBuffer: set,
+	})
+	if err != nil {
+		t.Fatalf("failed to dial netlink: %v", err)
+	}
+	defer c.Close()
+
+	rc, err := c.SyscallConn()
+	if err != nil {
+		t.Fatalf("failed to get syscall conn: %v", err)
+	}
+
+	for _, opt := range []int{unix.SO_RCVBUF, unix.SO_SNDBUF} {
+		var (
+			value int
+			serr  error
+		)
+
+		err := rc.Control(func(fd uintptr) {
+			value, serr = unix.GetsockoptInt(int(fd), unix.SOL_SOCKET, opt)
+		})
+		if err != nil {
+			t.Fatalf("failed to call control: %v", err)
+		}
+		if serr != nil {
+			t.Fatalf("failed to call getsockopt: %v", serr)
+		}
+
+		// The kernel doubles the value set by setsockopt.
+		if diff := cmp.Diff(set*2, value); diff != "" {
+			t.Fatalf("unexpected buffer size for option %d (-want +got):\n%s", opt, diff)
+		}
+	}
+}
+
+func TestIntegrationDialProfile(t *testing.T) {
+	t.Parallel()
+
+	c, err := netlink.DialProfile(netlink.ProfileGenericRequest)
+	if err != nil {
+		if errors.Is(err, unix.ENOPROTOOPT) {
+			t.Skipf("skipping, strict options not supported by this kernel: %v", err)
+		}
+
+		t.Fatalf("failed to dial netlink: %v", err)
+	}
+	defer c.Close()
+
+	if _, err := c.Execute(netlink.Message{
+		Header: netlink.Header{Flags: netlink.Request | netlink.Acknowledge},
+	}); err != nil {
+		t.Fatalf("failed to execute: %v", err)
+	}
+}
+
 func TestIntegrationConnSetBPFEmpty(t *testing.T) {
 	c, err := netlink.Dial(unix.NETLINK_GENERIC, nil)
 	if err != nil {
@@ -570,71 +636,65 @@ func testBPFProgram(allowSequence uint32) []bpf.Instruction {
 	}
 }
 
-func TestIntegrationConnExplicitPID(t *testing.T) {
+func TestIntegrationConnSetTypeFilter(t *testing.T) {
 	t.Parallel()
 
-	// Compute a random uint32 PID and explicitly bind using it. We expect this
-	// PID will be used in messages that are sent to and received from the
-	// kernel.
-	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
-	pid := rng.Uint32()
-
-	c, err := netlink.Dial(unix.NETLINK_GENERIC, &netlink.Config{PID: pid})
+	c, err := netlink.Dial(unix.NETLINK_GENERIC, nil)
 	if err != nil {
-		t.Fatalf("failed to dial netlink: %v", err)
+		t.Fatalf("failed to dial: %v", err)
 	}
 	defer c.Close()
 
+	// Query the generic netlink controller for its own family, which produces
+	// a reply of type GENL_ID_CTRL.
+	attrs, err := netlink.MarshalAttributes([]netlink.Attribute{{
+		Type: unix.CTRL_ATTR_FAMILY_NAME,
+		Data: nlenc.Bytes("nlctrl"),
+	}})
+	if err != nil {
+		t.Fatalf("failed to marshal attributes: %v", err)
+	}
+
 	req := netlink.Message{
 		Header: netlink.Header{
-			Flags: netlink.Request | netlink.Acknowledge,
+			Type:  unix.GENL_ID_CTRL,
+			Flags: netlink.Request,
 		},
+		Data: append([]byte{unix.CTRL_CMD_GETFAMILY, 1, 0, 0}, attrs...),
 	}
 
-	msg, err := c.Send(req)
-	if err != nil {
-		t.Fatalf("failed to send message: %v", err)
+	if err := c.SetTypeFilter(unix.GENL_ID_CTRL); err != nil {
+		t.Fatalf("failed to set type filter: %v", err)
 	}
 
-	msgs, err := c.Receive()
-	if err != nil {
-		t.Fatalf("failed to receive messages: %v", err)
+	if _, err := c.Execute(req); err != nil {
+		t.Fatalf("failed to execute with matching filter: %v", err)
 	}
 
-	// Verify both the request and response messages contain the same PID.
-	for _, m := range append([]netlink.Message{msg}, msgs...) {
-		if diff := cmp.Diff(pid, m.Header.PID); diff != "" {
-			t.Fatalf("unexpected message PID (-want +got):\n%s", diff)
-		}
+	// The reply is discarded by the kernel, so the request times out.
+	if err := c.SetTypeFilter(unix.GENL_ID_CTRL + 1); err != nil {
+		t.Fatalf("failed to set type filter: %v", err)
+	}
+	if err := c.SetReadDeadline(time.Now().Add(100 * time.Millisecond)); err != nil {
+		t.Fatalf("failed to set deadline: %v", err)
 	}
-}
-
-func TestIntegrationConnNetNSUnprivileged(t *testing.T) {
-	t.Parallel()
-
-	skipPrivileged(t)
 
-	// Created in CI build environment.
-	const ns = "unpriv0"
-	f, err := os.Open("/var/run/netns/" + ns)
-	if err != nil {
-		if os.IsNotExist(err) {
-			t.Skipf("skipping, expected %s namespace to exist", ns)
-		}
+	_, err = c.Execute(req)
+	mustBeTimeoutNetError(t, err)
 
-		t.Fatalf("failed to open namespace file: %v", err)
+	if err := c.SetTypeFilter(); err != nil {
+		t.Fatalf("failed to remove type filter: %v", err)
+	}
+	if err := c.SetReadDeadline(time.Time{}); err != nil {
+		t.Fatalf("failed to reset deadline: %v", err)
 	}
-	defer f.Close()
 
-	_, err = netlink.Dial(unix.NETLINK_ROUTE, &netlink.Config{
-		NetNS: int(f.Fd()),
-	})
-	if !os.IsPermission(err) {
-		t.Fatalf("expected permission denied, but got: %v", err)
+	if _, err := c.Execute(req); err != nil {
+		t.Fatalf("failed to execute without filter: %v", err)
 	}
 }
 
-func TestIntegrationConnSendTimeout(t *testing.T) {
+func TestIntegrationConnExecuteRaw(t *testing.T) {
 	t.Parallel()
 
 	c, err := netlink.Dial(unix.NETLINK_GENERIC, nil)
@@ -643,19 +703,41 @@ func TestIntegrationConnSendTimeout(t *testing.T) {
 	}
 	defer c.Close()
 
-	if err := c.SetWriteDeadline(time.Unix(0, 1)); err != nil {
-		t.Fatalf("failed to set deadline: %v", err)
+	attrs, err := netlink.MarshalAttributes([]netlink.Attribute{{
+		Type: unix.CTRL_ATTR_FAMILY_NAME,
+		Data: nlenc.Bytes("nlctrl"),
+	}})
+	if err != nil {
+		t.Fatalf("failed to marshal attributes: %v", err)
 	}
 
-	_, err = c.Send(netlink.Message{
+	msgs, raw, err := c.ExecuteRaw(netlink.Message{
 		Header: netlink.Header{
-			Flags: netlink.Request | netlink.Acknowledge,
+			Type:  unix.GENL_ID_CTRL,
+			Flags: netlink.Request,
 		},
+		Data: append([]byte{unix.CTRL_CMD_GETFAMILY, 1, 0, 0}, attrs...),
 	})
-	mustBeTimeoutNetError(t, err)
+	if err != nil {
+		t.Fatalf("failed to execute: %v", err)
+	}
+
+	if len(msgs) != 1 || len(raw) != 1 {
+		t.Fatalf("unexpected number of messages and datagrams: %d, %d", len(msgs), len(raw))
+	}
+
+	// The raw datagram must be byte-for-byte identical to the marshaled reply.
+	b, err := msgs[0].MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to marshal reply: %v", err)
+	}
+
+	if diff := cmp.Diff(b, raw[0]); diff != "" {
+		t.Fatalf("unexpected raw datagram (-want +got):\n%s", diff)
+	}
 }
 
-func TestIntegrationConnReceiveTimeout(t *testing.T) {
+func TestIntegrationConnDump(t *testing.T) {
 	t.Parallel()
 
 	c, err := netlink.Dial(unix.NETLINK_GENERIC, nil)
@@ -664,134 +746,781 @@ func TestIntegrationConnReceiveTimeout(t *testing.T) {
 	}
 	defer c.Close()
 
-	if err := c.SetReadDeadline(time.Unix(0, 1)); err != nil {
-		t.Fatalf("failed to set deadline: %v", err)
+	req := netlink.Message{
+		Header: netlink.Header{
+			Type:  unix.GENL_ID_CTRL,
+			Flags: netlink.Request | netlink.Dump,
+		},
+		Data: []byte{unix.CTRL_CMD_GETFAMILY, 1, 0, 0},
 	}
 
-	_, err = c.Receive()
-	mustBeTimeoutNetError(t, err)
+	want, err := c.Execute(req)
+	if err != nil {
+		t.Fatalf("failed to execute: %v", err)
+	}
+
+	var got []netlink.Message
+	err = c.Dump(context.Background(), req, func(m netlink.Message) error {
+		got = append(got, m)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to dump: %v", err)
+	}
+
+	// Sequence numbers differ between requests.
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(netlink.Header{}, "Sequence")); diff != "" {
+		t.Fatalf("unexpected replies (-want +got):\n%s", diff)
+	}
+
+	// Stopping early must drain the remaining replies.
+	errStop := errors.New("stop")
+	err = c.Dump(context.Background(), req, func(_ netlink.Message) error {
+		return errStop
+	})
+	if !errors.Is(err, errStop) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := c.Execute(netlink.Message{
+		Header: netlink.Header{Flags: netlink.Request | netlink.Acknowledge},
+	}); err != nil {
+		t.Fatalf("failed to execute after dump: %v", err)
+	}
 }
 
-func TestIntegrationConnExecuteTimeout(t *testing.T) {
+func TestIntegrationMux(t *testing.T) {
 	t.Parallel()
 
 	c, err := netlink.Dial(unix.NETLINK_GENERIC, nil)
 	if err != nil {
 		t.Fatalf("failed to dial: %v", err)
 	}
-	defer c.Close()
 
-	if err := c.SetDeadline(time.Unix(0, 1)); err != nil {
-		t.Fatalf("failed to set deadline: %v", err)
+	m := netlink.NewMux(c)
+	defer m.Close()
+
+	ae := netlink.NewAttributeEncoder()
+	ae.String(unix.CTRL_ATTR_FAMILY_NAME, "nlctrl")
+	attrs, err := ae.Encode()
+	if err != nil {
+		t.Fatalf("failed to encode attributes: %v", err)
 	}
 
-	req := netlink.Message{
+	get := netlink.Message{
 		Header: netlink.Header{
+			Type:  unix.GENL_ID_CTRL,
 			Flags: netlink.Request | netlink.Acknowledge,
 		},
+		Data: append([]byte{unix.CTRL_CMD_GETFAMILY, 1, 0, 0}, attrs...),
 	}
 
-	_, err = c.Execute(req)
-	if err == nil {
-		t.Fatal("expected an error, but none occurred")
+	dump := netlink.Message{
+		Header: netlink.Header{
+			Type:  unix.GENL_ID_CTRL,
+			Flags: netlink.Request | netlink.Dump,
+		},
+		Data: []byte{unix.CTRL_CMD_GETFAMILY, 1, 0, 0},
 	}
 
-	mustBeTimeoutNetError(t, err)
+	const n = 8
+
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 10; j++ {
+				// The reply to the request and then an acknowledgement.
+				msgs, err := m.Execute(context.Background(), get)
+				if err != nil {
+					t.Errorf("failed to execute: %v", err)
+					return
+				}
+				if len(msgs) != 2 {
+					t.Errorf("unexpected number of replies: %d", len(msgs))
+					return
+				}
+
+				msgs, err = m.Execute(context.Background(), dump)
+				if err != nil {
+					t.Errorf("failed to dump: %v", err)
+					return
+				}
+				if len(msgs) == 0 {
+					t.Error("no families were dumped")
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
 }
 
-func TestOpErrorUnwrapLinux(t *testing.T) {
-	tests := []struct {
-		name   string
-		err    error
-		target error
-		ok     bool
-	}{
-		{
-			name:   "ENOBUFS",
-			err:    unix.ENOBUFS,
-			target: os.ErrNotExist,
-		},
-		{
-			name: "OpError ENOBUFS",
-			err: &netlink.OpError{
-				Op:  "receive",
-				Err: unix.ENOBUFS,
-			},
-			target: os.ErrNotExist,
-		},
-		{
-			name: "OpError os.SyscallError ENOBUFS",
-			err: &netlink.OpError{
-				Op:  "receive",
-				Err: os.NewSyscallError("recvmsg", unix.ENOBUFS),
-			},
-			target: os.ErrNotExist,
-		},
-		{
-			name:   "ENOENT",
-			err:    unix.ENOENT,
-			target: os.ErrNotExist,
-			ok:     true,
-		},
-		{
-			name: "OpError ENOENT",
-			err: &netlink.OpError{
-				Op:  "receive",
-				Err: unix.ENOENT,
-			},
-			target: os.ErrNotExist,
-			ok:     true,
-		},
-		{
-			name: "OpError os.SyscallError ENOENT",
-			err: &netlink.OpError{
-				Op:  "receive",
-				Err: os.NewSyscallError("recvmsg", unix.ENOENT),
-			},
-			target: os.ErrNotExist,
-			ok:     true,
-		},
-		{
-			name: "OpError os.SyscallError EEXIST",
-			err: &netlink.OpError{
-				Op:  "receive",
-				Err: os.NewSyscallError("recvmsg", unix.EEXIST),
-			},
-			target: os.ErrExist,
-			ok:     true,
+func TestIntegrationConnExecuteArena(t *testing.T) {
+	t.Parallel()
+
+	c, err := netlink.Dial(unix.NETLINK_GENERIC, nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer c.Close()
+
+	req := netlink.Message{
+		Header: netlink.Header{
+			Type:  unix.GENL_ID_CTRL,
+			Flags: netlink.Request | netlink.Dump,
 		},
+		Data: []byte{unix.CTRL_CMD_GETFAMILY, 1, 0, 0},
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got := errors.Is(tt.err, tt.target)
-			if diff := cmp.Diff(tt.ok, got); diff != "" {
-				t.Fatalf("unexpected result (-want +got):\n%s", diff)
-			}
-		})
+	want, err := c.Execute(req)
+	if err != nil {
+		t.Fatalf("failed to execute: %v", err)
+	}
+
+	arena := netlink.NewArena(0)
+	for i := 0; i < 2; i++ {
+		got, err := c.ExecuteArena(req, arena)
+		if err != nil {
+			t.Fatalf("failed to execute with arena: %v", err)
+		}
+
+		// Sequence numbers differ between requests.
+		if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(netlink.Header{}, "Sequence")); diff != "" {
+			t.Fatalf("unexpected replies (-want +got):\n%s", diff)
+		}
+
+		arena.Reset()
 	}
 }
 
-func TestIntegrationConnClosedConn(t *testing.T) {
+func TestIntegrationConnReceiveBuf(t *testing.T) {
 	t.Parallel()
 
 	c, err := netlink.Dial(unix.NETLINK_GENERIC, nil)
 	if err != nil {
-		t.Fatalf("failed to dial netlink: %v", err)
+		t.Fatalf("failed to dial: %v", err)
 	}
+	defer c.Close()
 
-	// Close the connection immediately and ensure that future calls get EBADF.
-	if err := c.Close(); err != nil {
-		t.Fatalf("failed to close: %v", err)
+	req := netlink.Message{
+		Header: netlink.Header{
+			Type:  unix.GENL_ID_CTRL,
+			Flags: netlink.Request | netlink.Dump,
+		},
+		Data: []byte{unix.CTRL_CMD_GETFAMILY, 1, 0, 0},
 	}
 
-	tests := []struct {
-		name string
-		fn   func() error
-	}{
-		{
-			name: "receive",
-			fn: func() error {
+	want, err := c.Execute(req)
+	if err != nil {
+		t.Fatalf("failed to execute: %v", err)
+	}
+
+	var (
+		b   = make([]byte, 64*1024)
+		got []netlink.Message
+	)
+	for i := 0; i < 2; i++ {
+		if _, err := c.Send(req); err != nil {
+			t.Fatalf("failed to send: %v", err)
+		}
+
+		got, err = c.ReceiveBuf(b)
+		if err != nil {
+			t.Fatalf("failed to receive: %v", err)
+		}
+
+		// Sequence numbers differ between requests.
+		if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(netlink.Header{}, "Sequence")); diff != "" {
+			t.Fatalf("unexpected replies (-want +got):\n%s", diff)
+		}
+	}
+
+	// The data of the replies refers to b.
+	for i := range b {
+		b[i] = 0
+	}
+	if got[0].Data[0] != 0 {
+		t.Fatal("reply data was not received into buffer")
+	}
+}
+
+func TestIntegrationConnReceiveScatter(t *testing.T) {
+	t.Parallel()
+
+	c, err := netlink.Dial(unix.NETLINK_GENERIC, nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer c.Close()
+
+	attrs, err := netlink.MarshalAttributes([]netlink.Attribute{{
+		Type: unix.CTRL_ATTR_FAMILY_NAME,
+		Data: nlenc.Bytes("nlctrl"),
+	}})
+	if err != nil {
+		t.Fatalf("failed to marshal attributes: %v", err)
+	}
+
+	req := netlink.Message{
+		Header: netlink.Header{
+			Type:  unix.GENL_ID_CTRL,
+			Flags: netlink.Request,
+		},
+		Data: append([]byte{unix.CTRL_CMD_GETFAMILY, 1, 0, 0}, attrs...),
+	}
+
+	want, err := c.Execute(req)
+	if err != nil {
+		t.Fatalf("failed to execute: %v", err)
+	}
+
+	sent, err := c.Send(req)
+	if err != nil {
+		t.Fatalf("failed to send: %v", err)
+	}
+
+	b := make([]byte, os.Getpagesize())
+	h, payload, err := c.ReceiveScatter(b)
+	if err != nil {
+		t.Fatalf("failed to receive: %v", err)
+	}
+
+	if diff := cmp.Diff(sent.Header.Sequence, h.Sequence); diff != "" {
+		t.Fatalf("unexpected sequence (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(want[0].Data, payload); diff != "" {
+		t.Fatalf("unexpected payload (-want +got):\n%s", diff)
+	}
+	if &payload[0] != &b[0] {
+		t.Fatal("payload was not received into buffer")
+	}
+
+	// A payload buffer which is too small truncates the datagram.
+	if _, err := c.Send(req); err != nil {
+		t.Fatalf("failed to send: %v", err)
+	}
+
+	_, payload, err = c.ReceiveScatter(b[:8])
+	var terr *netlink.TruncatedError
+	if !errors.As(err, &terr) || !errors.Is(err, netlink.ErrTruncated) {
+		t.Fatalf("expected truncated error, but got: %v", err)
+	}
+
+	wantErr := &netlink.TruncatedError{
+		Length:   netlink.HeaderLen + len(want[0].Data),
+		Received: netlink.HeaderLen + 8,
+	}
+	if diff := cmp.Diff(wantErr, terr); diff != "" {
+		t.Fatalf("unexpected truncated error (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(want[0].Data[:8], payload); diff != "" {
+		t.Fatalf("unexpected truncated payload (-want +got):\n%s", diff)
+	}
+
+	// An error message is reported as an error.
+	req.Data[0] = 0xff
+	if _, err := c.Send(req); err != nil {
+		t.Fatalf("failed to send: %v", err)
+	}
+
+	h, _, err = c.ReceiveScatter(b)
+	if !errors.Is(err, unix.EOPNOTSUPP) {
+		t.Fatalf("expected not supported error, but got: %v", err)
+	}
+	if h.Type != netlink.Error {
+		t.Fatalf("unexpected header type: %v", h.Type)
+	}
+}
+
+func TestIntegrationConnExplicitPID(t *testing.T) {
+	t.Parallel()
+
+	// Compute a random uint32 PID and explicitly bind using it. We expect this
+	// PID will be used in messages that are sent to and received from the
+	// kernel.
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	pid := rng.Uint32()
+
+	c, err := netlink.Dial(unix.NETLINK_GENERIC, &netlink.Config{PID: pid})
+	if err != nil {
+		t.Fatalf("failed to dial netlink: %v", err)
+	}
+	defer c.Close()
+
+	req := netlink.Message{
+		Header: netlink.Header{
+			Flags: netlink.Request | netlink.Acknowledge,
+		},
+	}
+
+	msg, err := c.Send(req)
+	if err != nil {
+		t.Fatalf("failed to send message: %v", err)
+	}
+
+	msgs, err := c.Receive()
+	if err != nil {
+		t.Fatalf("failed to receive messages: %v", err)
+	}
+
+	// Verify both the request and response messages contain the same PID.
+	for _, m := range append([]netlink.Message{msg}, msgs...) {
+		if diff := cmp.Diff(pid, m.Header.PID); diff != "" {
+			t.Fatalf("unexpected message PID (-want +got):\n%s", diff)
+		}
+	}
+}
+
+func TestIntegrationConnExplicitPIDInUse(t *testing.T) {
+	t.Parallel()
+
+	// Bind a random PID, and then try to bind it again with another socket.
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	pid := rng.Uint32()
+
+	c, err := netlink.Dial(unix.NETLINK_GENERIC, &netlink.Config{PID: pid})
+	if err != nil {
+		t.Fatalf("failed to dial netlink: %v", err)
+	}
+	defer c.Close()
+
+	_, err = netlink.Dial(unix.NETLINK_GENERIC, &netlink.Config{PID: pid})
+	if !errors.Is(err, netlink.ErrPortInUse) || !errors.Is(err, unix.EADDRINUSE) {
+		t.Fatalf("expected port in use error, but got: %v", err)
+	}
+
+	var perr *netlink.PortInUseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("expected *netlink.PortInUseError, but got: %T", err)
+	}
+
+	// This process holds the port.
+	want := &netlink.PortInUseError{PortID: pid, PID: os.Getpid()}
+	if diff := cmp.Diff(want, perr, cmpopts.IgnoreFields(netlink.PortInUseError{}, "Err")); diff != "" {
+		t.Fatalf("unexpected port in use error (-want +got):\n%s", diff)
+	}
+}
+
+func TestIntegrationConnDefaultFlags(t *testing.T) {
+	t.Parallel()
+
+	c, err := netlink.Dial(unix.NETLINK_GENERIC, &netlink.Config{
+		DefaultFlags: netlink.Request | netlink.Acknowledge,
+	})
+	if err != nil {
+		t.Fatalf("failed to dial netlink: %v", err)
+	}
+	defer c.Close()
+
+	// No flags are set on the request, so the kernel would not reply unless
+	// the default flags are applied.
+	msgs, err := c.Execute(netlink.Message{})
+	if err != nil {
+		t.Fatalf("failed to execute request: %v", err)
+	}
+
+	if diff := cmp.Diff(1, len(msgs)); diff != "" {
+		t.Fatalf("unexpected number of messages (-want +got):\n%s", diff)
+	}
+
+	// The kernel echoes our request header, which should contain the default
+	// flags.
+	var req netlink.Message
+	if err := req.UnmarshalBinary(msgs[0].Data[4:]); err != nil {
+		t.Fatalf("failed to unmarshal echoed request: %v", err)
+	}
+
+	if diff := cmp.Diff(netlink.Request|netlink.Acknowledge, req.Header.Flags); diff != "" {
+		t.Fatalf("unexpected request flags (-want +got):\n%s", diff)
+	}
+}
+
+func TestIntegrationConnNetNSUnprivileged(t *testing.T) {
+	t.Parallel()
+
+	skipPrivileged(t)
+
+	// Created in CI build environment.
+	const ns = "unpriv0"
+	f, err := os.Open("/var/run/netns/" + ns)
+	if err != nil {
+		if os.IsNotExist(err) {
+			t.Skipf("skipping, expected %s namespace to exist", ns)
+		}
+
+		t.Fatalf("failed to open namespace file: %v", err)
+	}
+	defer f.Close()
+
+	_, err = netlink.Dial(unix.NETLINK_ROUTE, &netlink.Config{
+		NetNS: int(f.Fd()),
+	})
+	if !os.IsPermission(err) {
+		t.Fatalf("expected permission denied, but got: %v", err)
+	}
+}
+
+func TestIntegrationConnVerifyThreadNetNS(t *testing.T) {
+	t.Parallel()
+
+	ns := nltestenv.NewNetNS(t)
+	err := ns.Do(func() error {
+		c, err := netlink.Dial(unix.NETLINK_ROUTE, &netlink.Config{
+			VerifyThreadNetNS: true,
+		})
+		if err != nil {
+			return err
+		}
+
+		return c.Close()
+	})
+	if err != nil {
+		t.Fatalf("failed to dial in new network namespace: %v", err)
+	}
+}
+
+func TestIntegrationConnVerifyThreadNetNSProcess(t *testing.T) {
+	t.Parallel()
+
+	// A thread in the process's network namespace most likely indicates the
+	// goroutine migrated away from the thread which entered a namespace.
+	_, err := netlink.Dial(unix.NETLINK_ROUTE, &netlink.Config{
+		VerifyThreadNetNS: true,
+	})
+	if err == nil || !strings.Contains(err.Error(), "runtime.LockOSThread") {
+		t.Fatalf("expected thread network namespace error, but got: %v", err)
+	}
+}
+
+func TestIntegrationConnSendTimeout(t *testing.T) {
+	t.Parallel()
+
+	c, err := netlink.Dial(unix.NETLINK_GENERIC, nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer c.Close()
+
+	if err := c.SetWriteDeadline(time.Unix(0, 1)); err != nil {
+		t.Fatalf("failed to set deadline: %v", err)
+	}
+
+	_, err = c.Send(netlink.Message{
+		Header: netlink.Header{
+			Flags: netlink.Request | netlink.Acknowledge,
+		},
+	})
+	mustBeTimeoutNetError(t, err)
+}
+
+func TestIntegrationConnReceiveTimeout(t *testing.T) {
+	t.Parallel()
+
+	c, err := netlink.Dial(unix.NETLINK_GENERIC, nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer c.Close()
+
+	if err := c.SetReadDeadline(time.Unix(0, 1)); err != nil {
+		t.Fatalf("failed to set deadline: %v", err)
+	}
+
+	_, err = c.Receive()
+	mustBeTimeoutNetError(t, err)
+}
+
+func TestIntegrationConnContextRestoresDeadlines(t *testing.T) {
+	t.Parallel()
+
+	c, err := netlink.Dial(unix.NETLINK_GENERIC, nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer c.Close()
+
+	if err := c.SetReadDeadline(time.Now().Add(500 * time.Millisecond)); err != nil {
+		t.Fatalf("failed to set read deadline: %v", err)
+	}
+	if err := c.SetWriteDeadline(time.Unix(0, 1)); err != nil {
+		t.Fatalf("failed to set write deadline: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := c.ReceiveContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context deadline exceeded, but got: %v", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req := netlink.Message{Header: netlink.Header{Flags: netlink.Request}}
+	if _, err := c.SendContext(ctx, req); err != nil {
+		t.Fatalf("failed to send: %v", err)
+	}
+
+	// Both of the Conn's deadlines must still apply.
+	_, err = c.Send(req)
+	mustBeTimeoutNetError(t, err)
+
+	errC := make(chan error, 1)
+	go func() {
+		_, err := c.Receive()
+		errC <- err
+	}()
+
+	select {
+	case err := <-errC:
+		mustBeTimeoutNetError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for read deadline")
+	}
+}
+
+func TestIntegrationConnExecuteNoReplyTimeout(t *testing.T) {
+	t.Parallel()
+
+	c, err := netlink.Dial(unix.NETLINK_GENERIC, &netlink.Config{
+		NoReplyTimeout: 50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer c.Close()
+
+	// Without Acknowledge, the kernel will not reply to this message and
+	// Execute would block forever.
+	_, err = c.Execute(netlink.Message{
+		Header: netlink.Header{Flags: netlink.Request},
+	})
+	mustBeTimeoutNetError(t, err)
+
+	// The deadline must be cleared for later requests.
+	time.Sleep(100 * time.Millisecond)
+	if _, err := c.Execute(netlink.Message{
+		Header: netlink.Header{Flags: netlink.Request | netlink.Acknowledge},
+	}); err != nil {
+		t.Fatalf("failed to execute: %v", err)
+	}
+}
+
+func TestIntegrationConnServeCanceled(t *testing.T) {
+	t.Parallel()
+
+	c, err := netlink.Dial(unix.NETLINK_GENERIC, nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer c.Close()
+
+	// No multicast messages will arrive, so Serve blocks until the context
+	// is canceled.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err = c.Serve(ctx, func(_ []netlink.Message) error {
+		panic("should not be called")
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The Conn must remain usable after Serve returns.
+	if _, err := c.Execute(netlink.Message{
+		Header: netlink.Header{Flags: netlink.Request | netlink.Acknowledge},
+	}); err != nil {
+		t.Fatalf("failed to execute: %v", err)
+	}
+}
+
+func TestIntegrationConnExecuteContext(t *testing.T) {
+	t.Parallel()
+
+	c, err := netlink.Dial(unix.NETLINK_GENERIC, nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer c.Close()
+
+	// Without Acknowledge, the kernel will not reply to this message and
+	// ExecuteContext blocks until the context is canceled.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = c.ExecuteContext(ctx, netlink.Message{
+		Header: netlink.Header{Flags: netlink.Request},
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The Conn must remain usable after cancelation.
+	if _, err := c.ExecuteContext(context.Background(), netlink.Message{
+		Header: netlink.Header{Flags: netlink.Request | netlink.Acknowledge},
+	}); err != nil {
+		t.Fatalf("failed to execute: %v", err)
+	}
+}
+
+func TestIntegrationConnReceiveContext(t *testing.T) {
+	t.Parallel()
+
+	c, err := netlink.Dial(unix.NETLINK_GENERIC, nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	_, err = c.ReceiveContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req, err := c.SendContext(context.Background(), netlink.Message{
+		Header: netlink.Header{Flags: netlink.Request | netlink.Acknowledge},
+	})
+	if err != nil {
+		t.Fatalf("failed to send: %v", err)
+	}
+
+	msgs, err := c.ReceiveContext(context.Background())
+	if err != nil {
+		t.Fatalf("failed to receive: %v", err)
+	}
+	if err := netlink.Validate(req, msgs); err != nil {
+		t.Fatalf("failed to validate: %v", err)
+	}
+}
+
+func TestIntegrationConnExecuteTimeout(t *testing.T) {
+	t.Parallel()
+
+	c, err := netlink.Dial(unix.NETLINK_GENERIC, nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer c.Close()
+
+	if err := c.SetDeadline(time.Unix(0, 1)); err != nil {
+		t.Fatalf("failed to set deadline: %v", err)
+	}
+
+	req := netlink.Message{
+		Header: netlink.Header{
+			Flags: netlink.Request | netlink.Acknowledge,
+		},
+	}
+
+	_, err = c.Execute(req)
+	if err == nil {
+		t.Fatal("expected an error, but none occurred")
+	}
+
+	mustBeTimeoutNetError(t, err)
+}
+
+func TestOpErrorUnwrapLinux(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		target error
+		ok     bool
+	}{
+		{
+			name:   "ENOBUFS",
+			err:    unix.ENOBUFS,
+			target: os.ErrNotExist,
+		},
+		{
+			name: "OpError ENOBUFS",
+			err: &netlink.OpError{
+				Op:  "receive",
+				Err: unix.ENOBUFS,
+			},
+			target: os.ErrNotExist,
+		},
+		{
+			name: "OpError os.SyscallError ENOBUFS",
+			err: &netlink.OpError{
+				Op:  "receive",
+				Err: os.NewSyscallError("recvmsg", unix.ENOBUFS),
+			},
+			target: os.ErrNotExist,
+		},
+		{
+			name:   "ENOENT",
+			err:    unix.ENOENT,
+			target: os.ErrNotExist,
+			ok:     true,
+		},
+		{
+			name: "OpError ENOENT",
+			err: &netlink.OpError{
+				Op:  "receive",
+				Err: unix.ENOENT,
+			},
+			target: os.ErrNotExist,
+			ok:     true,
+		},
+		{
+			name: "OpError os.SyscallError ENOENT",
+			err: &netlink.OpError{
+				Op:  "receive",
+				Err: os.NewSyscallError("recvmsg", unix.ENOENT),
+			},
+			target: os.ErrNotExist,
+			ok:     true,
+		},
+		{
+			name: "OpError os.SyscallError EEXIST",
+			err: &netlink.OpError{
+				Op:  "receive",
+				Err: os.NewSyscallError("recvmsg", unix.EEXIST),
+			},
+			target: os.ErrExist,
+			ok:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := errors.Is(tt.err, tt.target)
+			if diff := cmp.Diff(tt.ok, got); diff != "" {
+				t.Fatalf("unexpected result (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIntegrationConnClosedConn(t *testing.T) {
+	t.Parallel()
+
+	c, err := netlink.Dial(unix.NETLINK_GENERIC, nil)
+	if err != nil {
+		t.Fatalf("failed to dial netlink: %v", err)
+	}
+
+	// Close the connection immediately and ensure that future calls get EBADF.
+	if err := c.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		fn   func() error
+	}{
+		{
+			name: "receive",
+			fn: func() error {
 				_, err := c.Receive()
 				return err
 			},
@@ -818,113 +1547,721 @@ func TestIntegrationConnClosedConn(t *testing.T) {
 		},
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if diff := cmp.Diff(unix.EBADF, tt.fn(), cmpopts.EquateErrors()); diff != "" {
-				t.Fatalf("unexpected error (-want +got):\n%s", diff)
-			}
-		})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(unix.EBADF, tt.fn(), cmpopts.EquateErrors()); diff != "" {
+				t.Fatalf("unexpected error (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIntegrationConnStrict(t *testing.T) {
+	c, err := netlink.Dial(unix.NETLINK_GENERIC, &netlink.Config{Strict: true})
+	if err != nil {
+		if errors.Is(err, unix.ENOPROTOOPT) {
+			t.Skipf("skipping, strict options not supported by this kernel: %v", err)
+		}
+
+		t.Fatalf("failed to dial netlink: %v", err)
+	}
+	defer c.Close()
+
+	sc, err := c.SyscallConn()
+	if err != nil {
+		t.Fatalf("failed to open syscall conn: %v", err)
+	}
+
+	// Strict mode applies a series of socket options. Check each applied option
+	// and update the map to true if we found it set to true. Any options which
+	// were not applied as expected will result in the test failing.
+	opts := map[int]bool{
+		unix.NETLINK_EXT_ACK:        false,
+		unix.NETLINK_GET_STRICT_CHK: false,
+	}
+
+	err = sc.Control(func(fd uintptr) {
+		for k := range opts {
+			// The kernel returns a non-zero value for true.
+			if v, err := unix.GetsockoptInt(int(fd), unix.SOL_NETLINK, k); err == nil && v != 0 {
+				opts[k] = true
+			}
+		}
+	})
+	if err != nil {
+		t.Fatalf("failed to call control: %v", err)
+	}
+
+	for k, v := range opts {
+		if !v {
+			t.Errorf("socket option %d was not set to true", k)
+		}
+	}
+}
+
+func mustBeTimeoutNetError(t *testing.T, err error) {
+	t.Helper()
+
+	nerr, ok := err.(net.Error)
+	if !ok {
+		t.Fatalf("expected net.Error, but got: %T", err)
+	}
+	if !nerr.Timeout() {
+		t.Fatalf("error did not indicate a timeout")
+	}
+}
+
+func skipPrivileged(t *testing.T) {
+	u, err := user.Current()
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+	if u.Uid == "0" {
+		t.Skip("skipping, test must be run as non-root user")
+	}
+}
+
+func skipShort(t *testing.T) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("skipping in short test mode")
+	}
+}
+
+func panicf(format string, a ...interface{}) {
+	panic(fmt.Sprintf(format, a...))
+}
+
+func TestIntegrationDialInterceptorFallthrough(t *testing.T) {
+	var family int
+	prev := netlink.SetDialInterceptor(func(f int, _ *netlink.Config) (netlink.Socket, uint32, error) {
+		// Observe the call but let Dial create a real socket.
+		family = f
+		return nil, 0, nil
+	})
+	defer netlink.SetDialInterceptor(prev)
+
+	c, err := netlink.Dial(unix.NETLINK_GENERIC, nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer c.Close()
+
+	if diff := cmp.Diff(unix.NETLINK_GENERIC, family); diff != "" {
+		t.Fatalf("unexpected intercepted family (-want +got):\n%s", diff)
+	}
+
+	// A real socket supports options which the nltest socket does not.
+	if err := c.SetOption(netlink.ExtendedAcknowledge, true); err != nil {
+		t.Fatalf("failed to set option on real socket: %v", err)
+	}
+}
+
+func TestIntegrationConnBindGroups(t *testing.T) {
+	t.Parallel()
+
+	ns := nltestenv.NewNetNS(t)
+
+	// Dial without any groups and subscribe to link notifications later.
+	c, err := netlink.Dial(unix.NETLINK_ROUTE, &netlink.Config{NetNS: ns.FD()})
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer c.Close()
+
+	if err := c.BindGroups(unix.RTMGRP_LINK); err != nil {
+		t.Fatalf("failed to bind groups: %v", err)
+	}
+
+	if err := c.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("failed to set deadline: %v", err)
+	}
+
+	ns.AddVeth("nlbind0", "nlbind1")
+
+	msgs, err := c.Receive()
+	if err != nil {
+		t.Fatalf("failed to receive notification: %v", err)
+	}
+
+	if diff := cmp.Diff(netlink.HeaderType(unix.RTM_NEWLINK), msgs[0].Header.Type); diff != "" {
+		t.Fatalf("unexpected notification type (-want +got):\n%s", diff)
+	}
+}
+
+func TestIntegrationNetNSID(t *testing.T) {
+	t.Parallel()
+
+	var (
+		// Namespace IDs are assigned from the point of view of the monitor
+		// namespace to the other namespaces.
+		monitor = nltestenv.NewNetNS(t)
+		a       = nltestenv.NewNetNS(t)
+		b       = nltestenv.NewNetNS(t)
+	)
+
+	c, err := netlink.Dial(unix.NETLINK_ROUTE, &netlink.Config{NetNS: monitor.FD()})
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer c.Close()
+
+	if err := netlink.SetNetNSID(c, a.FD(), 10); err != nil {
+		t.Fatalf("failed to set namespace ID: %v", err)
+	}
+
+	for _, tt := range []struct {
+		ns *nltestenv.NetNS
+		id int32
+	}{
+		{ns: a, id: 10},
+		{ns: b, id: netlink.NetNSIDNotAssigned},
+	} {
+		id, err := netlink.NetNSID(c, tt.ns.FD())
+		if err != nil {
+			t.Fatalf("failed to get namespace ID: %v", err)
+		}
+
+		if diff := cmp.Diff(tt.id, id); diff != "" {
+			t.Fatalf("unexpected namespace ID (-want +got):\n%s", diff)
+		}
+	}
+
+	// IDs cannot be reassigned.
+	if err := netlink.SetNetNSID(c, a.FD(), 11); err == nil {
+		t.Fatal("expected an error, but none occurred")
+	}
+}
+
+func TestIntegrationConnConfig(t *testing.T) {
+	t.Parallel()
+
+	netns := nltestenv.NewNetNS(t).FD()
+	c, err := netlink.Dial(unix.NETLINK_ROUTE, &netlink.Config{
+		NetNS:             netns,
+		Groups:            unix.RTMGRP_LINK,
+		JoinGroups:        []uint32{unix.RTNLGRP_BRVLAN},
+		ReadBuffer:        4096,
+		NoReplyTimeout:    time.Second,
+		SplitSendMessages: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer c.Close()
+
+	for _, o := range []netlink.ConnOption{netlink.ExtendedAcknowledge, netlink.GetStrictCheck} {
+		if err := c.SetOption(o, true); err != nil {
+			t.Fatalf("failed to set option %s: %v", o, err)
+		}
+	}
+	if err := c.JoinGroup(unix.RTNLGRP_IPV4_IFADDR); err != nil {
+		t.Fatalf("failed to join group: %v", err)
+	}
+
+	cfg, err := c.Config()
+	if err != nil {
+		t.Fatalf("failed to get config: %v", err)
+	}
+
+	want := netlink.ConnConfig{
+		Family: netlink.FamilyRoute,
+		Groups: unix.RTMGRP_LINK | unix.RTMGRP_IPV4_IFADDR,
+		// Groups above 32 are only reported by Memberships.
+		Memberships: []uint32{unix.RTNLGRP_LINK, unix.RTNLGRP_IPV4_IFADDR, unix.RTNLGRP_BRVLAN},
+		Options:     []netlink.ConnOption{netlink.ExtendedAcknowledge, netlink.GetStrictCheck},
+		// Linux doubles the requested buffer size.
+		ReadBuffer:        2 * 4096,
+		JoinGroups:        []uint32{unix.RTNLGRP_BRVLAN},
+		NetNS:             netns,
+		NoReplyTimeout:    time.Second,
+		SplitSendMessages: true,
+	}
+
+	if diff := cmp.Diff(want, cfg, cmpopts.IgnoreFields(netlink.ConnConfig{}, "PID", "WriteBuffer")); diff != "" {
+		t.Fatalf("unexpected config (-want +got):\n%s", diff)
+	}
+	if cfg.PID == 0 || cfg.WriteBuffer == 0 {
+		t.Fatalf("expected non-zero PID and write buffer: %+v", cfg)
+	}
+}
+
+func TestIntegrationConnSendMessagesSplit(t *testing.T) {
+	t.Parallel()
+
+	c, err := netlink.Dial(unix.NETLINK_GENERIC, &netlink.Config{
+		SplitSendMessages: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer c.Close()
+
+	const n = 64
+
+	msgs := make([]netlink.Message, n)
+	for i := range msgs {
+		msgs[i].Header.Flags = netlink.Request | netlink.Acknowledge
+	}
+
+	sent, err := c.SendMessages(msgs)
+	if err != nil {
+		t.Fatalf("failed to send messages: %v", err)
+	}
+
+	// Each datagram produces its own acknowledgement.
+	var acks []netlink.Message
+	for len(acks) < n {
+		msgs, err := c.Receive()
+		if err != nil {
+			t.Fatalf("failed to receive: %v", err)
+		}
+
+		acks = append(acks, msgs...)
+	}
+
+	for i := range sent {
+		if err := netlink.Validate(sent[i], acks[i:i+1]); err != nil {
+			t.Fatalf("failed to validate acknowledgement %d: %v", i, err)
+		}
 	}
 }
 
-func TestIntegrationConnStrict(t *testing.T) {
-	c, err := netlink.Dial(unix.NETLINK_GENERIC, &netlink.Config{Strict: true})
+func TestIntegrationConnReceiveLarge(t *testing.T) {
+	t.Parallel()
+
+	c, err := netlink.Dial(unix.NETLINK_GENERIC, nil)
 	if err != nil {
-		if errors.Is(err, unix.ENOPROTOOPT) {
-			t.Skipf("skipping, strict options not supported by this kernel: %v", err)
-		}
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer c.Close()
 
-		t.Fatalf("failed to dial netlink: %v", err)
+	cfg, err := c.Config()
+	if err != nil {
+		t.Fatalf("failed to get config: %v", err)
+	}
+
+	// Send a message larger than a page directly to c from another socket.
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_GENERIC)
+	if err != nil {
+		t.Fatalf("failed to open socket: %v", err)
+	}
+	defer unix.Close(fd)
+
+	want := netlink.Message{
+		Header: netlink.Header{Type: 0xff, Sequence: 1},
+		Data:   bytes.Repeat([]byte{0xff}, 3*os.Getpagesize()),
+	}
+	want.Header.Length = uint32(netlink.HeaderLen + len(want.Data))
+
+	b, err := want.MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to marshal message: %v", err)
+	}
+
+	if err := unix.Sendto(fd, b, 0, &unix.SockaddrNetlink{
+		Family: unix.AF_NETLINK,
+		Pid:    cfg.PID,
+	}); err != nil {
+		t.Fatalf("failed to send: %v", err)
+	}
+
+	got, err := c.Receive()
+	if err != nil {
+		t.Fatalf("failed to receive: %v", err)
+	}
+
+	// The sender's PID is assigned by the kernel.
+	if diff := cmp.Diff([]netlink.Message{want}, got, cmpopts.IgnoreFields(netlink.Header{}, "PID")); diff != "" {
+		t.Fatalf("unexpected messages (-want +got):\n%s", diff)
+	}
+}
+
+func TestIntegrationConnBestEffortOptions(t *testing.T) {
+	t.Parallel()
+
+	// An unknown option is not supported by any kernel.
+	const unknown netlink.ConnOption = 100
+
+	c, err := netlink.Dial(unix.NETLINK_GENERIC, &netlink.Config{
+		BestEffortOptions: []netlink.ConnOption{netlink.ExtendedAcknowledge, unknown},
+	})
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
 	}
 	defer c.Close()
 
-	sc, err := c.SyscallConn()
+	want := netlink.Features{
+		Options: map[netlink.ConnOption]bool{
+			netlink.ExtendedAcknowledge: true,
+			unknown:                     false,
+		},
+	}
+
+	if diff := cmp.Diff(want, c.Features()); diff != "" {
+		t.Fatalf("unexpected features (-want +got):\n%s", diff)
+	}
+}
+
+func TestIntegrationConnJoinGroups(t *testing.T) {
+	t.Parallel()
+
+	ns := nltestenv.NewNetNS(t)
+
+	// Groups beyond the 32-bit Groups bitmask can also be joined.
+	c, err := netlink.Dial(unix.NETLINK_ROUTE, &netlink.Config{
+		NetNS:      ns.FD(),
+		JoinGroups: []uint32{unix.RTNLGRP_LINK, unix.RTNLGRP_NEXTHOP},
+	})
 	if err != nil {
-		t.Fatalf("failed to open syscall conn: %v", err)
+		t.Fatalf("failed to dial: %v", err)
 	}
+	defer c.Close()
 
-	// Strict mode applies a series of socket options. Check each applied option
-	// and update the map to true if we found it set to true. Any options which
-	// were not applied as expected will result in the test failing.
-	opts := map[int]bool{
-		unix.NETLINK_EXT_ACK:        false,
-		unix.NETLINK_GET_STRICT_CHK: false,
+	if err := c.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("failed to set deadline: %v", err)
 	}
 
-	err = sc.Control(func(fd uintptr) {
-		for k := range opts {
-			// The kernel returns a non-zero value for true.
-			if v, err := unix.GetsockoptInt(int(fd), unix.SOL_NETLINK, k); err == nil && v != 0 {
-				opts[k] = true
-			}
-		}
+	ns.AddVeth("nljoin0", "nljoin1")
+
+	msgs, err := c.Receive()
+	if err != nil {
+		t.Fatalf("failed to receive notification: %v", err)
+	}
+
+	if diff := cmp.Diff(netlink.HeaderType(unix.RTM_NEWLINK), msgs[0].Header.Type); diff != "" {
+		t.Fatalf("unexpected notification type (-want +got):\n%s", diff)
+	}
+}
+
+func TestIntegrationConnMemberships(t *testing.T) {
+	t.Parallel()
+
+	c, err := netlink.Dial(unix.NETLINK_ROUTE, &netlink.Config{
+		NetNS:      nltestenv.NewNetNS(t).FD(),
+		Groups:     unix.RTMGRP_IPV4_IFADDR,
+		JoinGroups: []uint32{unix.RTNLGRP_NEXTHOP, unix.RTNLGRP_BRVLAN},
 	})
 	if err != nil {
-		t.Fatalf("failed to call control: %v", err)
+		t.Fatalf("failed to dial: %v", err)
 	}
+	defer c.Close()
 
-	for k, v := range opts {
-		if !v {
-			t.Errorf("socket option %d was not set to true", k)
-		}
+	if err := c.JoinGroup(unix.RTNLGRP_LINK); err != nil {
+		t.Fatalf("failed to join group: %v", err)
+	}
+
+	got, err := c.Memberships()
+	if err != nil {
+		t.Fatalf("failed to list memberships: %v", err)
+	}
+
+	want := []uint32{
+		unix.RTNLGRP_LINK,
+		unix.RTNLGRP_IPV4_IFADDR,
+		unix.RTNLGRP_NEXTHOP,
+		unix.RTNLGRP_BRVLAN,
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("unexpected memberships (-want +got):\n%s", diff)
 	}
 }
 
-func mustBeTimeoutNetError(t *testing.T, err error) {
-	t.Helper()
+func TestIntegrationConnReceiveWithInfo(t *testing.T) {
+	t.Parallel()
 
-	nerr, ok := err.(net.Error)
-	if !ok {
-		t.Fatalf("expected net.Error, but got: %T", err)
+	ns := nltestenv.NewNetNS(t)
+
+	c, err := netlink.Dial(unix.NETLINK_ROUTE, &netlink.Config{
+		NetNS:      ns.FD(),
+		JoinGroups: []uint32{unix.RTNLGRP_LINK},
+	})
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
 	}
-	if !nerr.Timeout() {
-		t.Fatalf("error did not indicate a timeout")
+	defer c.Close()
+
+	if err := c.SetOption(netlink.PacketInfo, true); err != nil {
+		t.Fatalf("failed to enable packet info: %v", err)
+	}
+	if err := c.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("failed to set deadline: %v", err)
+	}
+
+	ns.AddVeth("nlinfo0", "nlinfo1")
+
+	msgs, infos, err := c.ReceiveWithInfo()
+	if err != nil {
+		t.Fatalf("failed to receive notification: %v", err)
+	}
+	if len(msgs) != len(infos) {
+		t.Fatalf("mismatched messages and info: %d != %d", len(msgs), len(infos))
+	}
+
+	want := netlink.MessageInfo{
+		Group: unix.RTNLGRP_LINK,
+		NSID:  netlink.NetNSIDNotAssigned,
+	}
+
+	if diff := cmp.Diff(want, infos[0]); diff != "" {
+		t.Fatalf("unexpected message info (-want +got):\n%s", diff)
 	}
 }
 
-func skipPrivileged(t *testing.T) {
-	u, err := user.Current()
+func TestIntegrationConnReceiveWithInfoNSID(t *testing.T) {
+	t.Parallel()
+
+	var (
+		monitor = nltestenv.NewNetNS(t)
+		peer    = nltestenv.NewNetNS(t)
+	)
+
+	c, err := netlink.Dial(unix.NETLINK_ROUTE, &netlink.Config{
+		NetNS:      monitor.FD(),
+		JoinGroups: []uint32{unix.RTNLGRP_LINK},
+	})
 	if err != nil {
-		t.Fatalf("failed to get user: %v", err)
+		t.Fatalf("failed to dial: %v", err)
 	}
-	if u.Uid == "0" {
-		t.Skip("skipping, test must be run as non-root user")
+	defer c.Close()
+
+	// Messages are only received from namespaces which have an ID.
+	const id = 20
+	if err := netlink.SetNetNSID(c, peer.FD(), id); err != nil {
+		t.Fatalf("failed to set namespace ID: %v", err)
+	}
+
+	if err := c.SetOption(netlink.ListenAllNSID, true); err != nil {
+		t.Fatalf("failed to enable listen all NSID: %v", err)
+	}
+	if err := c.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("failed to set deadline: %v", err)
+	}
+
+	peer.AddVeth("nlnsid0", "nlnsid1")
+
+	msgs, infos, err := c.ReceiveWithInfo()
+	if err != nil {
+		t.Fatalf("failed to receive notification: %v", err)
+	}
+	if len(msgs) != len(infos) {
+		t.Fatalf("mismatched messages and info: %d != %d", len(msgs), len(infos))
+	}
+
+	if diff := cmp.Diff(netlink.MessageInfo{NSID: id}, infos[0]); diff != "" {
+		t.Fatalf("unexpected message info (-want +got):\n%s", diff)
 	}
 }
 
-func skipUnprivileged(t *testing.T) {
-	const ifName = "nlprobe0"
-	shell(t, "ip", "tuntap", "add", ifName, "mode", "tun")
-	shell(t, "ip", "link", "del", ifName)
+func TestIntegrationConnReceiveWithInfoCredentials(t *testing.T) {
+	t.Parallel()
+
+	c, err := netlink.Dial(unix.NETLINK_USERSOCK, nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer c.Close()
+
+	if err := c.SetOption(netlink.PassCredentials, true); err != nil {
+		t.Fatalf("failed to enable pass credentials: %v", err)
+	}
+
+	cfg, err := c.Config()
+	if err != nil {
+		t.Fatalf("failed to get config: %v", err)
+	}
+
+	// Send a message directly to c from another socket owned by this process.
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_USERSOCK)
+	if err != nil {
+		t.Fatalf("failed to open socket: %v", err)
+	}
+	defer unix.Close(fd)
+
+	b, err := netlink.Message{
+		Header: netlink.Header{Length: netlink.HeaderLen, Type: 0xff},
+	}.MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to marshal message: %v", err)
+	}
+
+	if err := unix.Sendto(fd, b, 0, &unix.SockaddrNetlink{
+		Family: unix.AF_NETLINK,
+		Pid:    cfg.PID,
+	}); err != nil {
+		t.Fatalf("failed to send: %v", err)
+	}
+
+	_, infos, err := c.ReceiveWithInfo()
+	if err != nil {
+		t.Fatalf("failed to receive: %v", err)
+	}
+
+	want := &netlink.Credentials{
+		PID: uint32(os.Getpid()),
+		UID: uint32(os.Getuid()),
+		GID: uint32(os.Getgid()),
+	}
+
+	if diff := cmp.Diff(want, infos[0].Credentials); diff != "" {
+		t.Fatalf("unexpected credentials (-want +got):\n%s", diff)
+	}
 }
 
-func skipShort(t *testing.T) {
-	t.Helper()
-	if testing.Short() {
-		t.Skip("skipping in short test mode")
+func TestIntegrationConnFile(t *testing.T) {
+	t.Parallel()
+
+	c, err := netlink.Dial(unix.NETLINK_GENERIC, nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+
+	f, err := c.File()
+	if err != nil {
+		t.Fatalf("failed to get file: %v", err)
+	}
+	defer f.Close()
+
+	// The File must remain usable after the Conn is closed.
+	if err := c.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	proto, err := unix.GetsockoptInt(int(f.Fd()), unix.SOL_SOCKET, unix.SO_PROTOCOL)
+	if err != nil {
+		t.Fatalf("failed to get protocol: %v", err)
+	}
+
+	if diff := cmp.Diff(unix.NETLINK_GENERIC, proto); diff != "" {
+		t.Fatalf("unexpected protocol (-want +got):\n%s", diff)
 	}
 }
 
-func shell(t *testing.T, name string, arg ...string) {
-	t.Helper()
+func TestIntegrationConnInjectReceive(t *testing.T) {
+	t.Parallel()
 
-	t.Logf("$ %s %v", name, arg)
+	c, err := netlink.Dial(unix.NETLINK_GENERIC, nil)
+	if err != nil {
+		t.Fatalf("failed to dial netlink: %v", err)
+	}
+	defer c.Close()
+
+	// The Messages goroutine blocks waiting on the socket, and must be
+	// interrupted to deliver the injected message.
+	msgC, _ := c.Messages()
+	time.Sleep(50 * time.Millisecond)
 
-	cmd := exec.Command(name, arg...)
-	if err := cmd.Start(); err != nil {
-		t.Fatalf("failed to start command %q: %v", name, err)
+	want := netlink.Message{Header: netlink.Header{Type: 0x20}, Data: []byte{0xff}}
+	if err := c.InjectReceive([]netlink.Message{want}); err != nil {
+		t.Fatalf("failed to inject: %v", err)
 	}
 
-	if err := cmd.Wait(); err != nil {
-		// Shell operations in these tests require elevated privileges.
-		if cmd.ProcessState.ExitCode() == int(unix.EPERM) {
-			t.Skipf("skipping, permission denied: %v", err)
+	select {
+	case got := <-msgC:
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Fatalf("unexpected message (-want +got):\n%s", diff)
 		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for injected message")
+	}
+
+	// The Conn remains usable afterward.
+	req := netlink.Message{Header: netlink.Header{Flags: netlink.Request | netlink.Acknowledge}}
+	if _, err := c.Send(req); err != nil {
+		t.Fatalf("failed to send: %v", err)
+	}
 
-		t.Fatalf("failed to wait for command %q: %v", name, err)
+	select {
+	case m := <-msgC:
+		if m.Header.Type != netlink.Error {
+			t.Fatalf("expected acknowledgement, but got: %+v", m)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for acknowledgement")
 	}
 }
 
-func panicf(format string, a ...interface{}) {
-	panic(fmt.Sprintf(format, a...))
+func TestIntegrationConnInjectReceiveReadDeadline(t *testing.T) {
+	t.Parallel()
+
+	c, err := netlink.Dial(unix.NETLINK_GENERIC, nil)
+	if err != nil {
+		t.Fatalf("failed to dial netlink: %v", err)
+	}
+	defer c.Close()
+
+	if err := c.SetReadDeadline(time.Now().Add(500 * time.Millisecond)); err != nil {
+		t.Fatalf("failed to set deadline: %v", err)
+	}
+
+	// The first Receive blocks waiting on the socket, and must be interrupted
+	// to deliver the injected message.
+	errC := make(chan error, 1)
+	go func() {
+		_, err := c.Receive()
+		errC <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	if err := c.InjectReceive([]netlink.Message{{Header: netlink.Header{Type: 0x20}}}); err != nil {
+		t.Fatalf("failed to inject: %v", err)
+	}
+
+	if err := <-errC; err != nil {
+		t.Fatalf("failed to receive injected message: %v", err)
+	}
+
+	// The read deadline must still apply to the second Receive.
+	go func() {
+		_, err := c.Receive()
+		errC <- err
+	}()
+
+	select {
+	case err := <-errC:
+		mustBeTimeoutNetError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for read deadline")
+	}
+}
+
+func TestIntegrationConnExtendedAcknowledgePolicy(t *testing.T) {
+	t.Parallel()
+
+	c, err := netlink.Dial(unix.NETLINK_GENERIC, &netlink.Config{Strict: true})
+	if err != nil {
+		t.Fatalf("failed to dial netlink: %v", err)
+	}
+	defer c.Close()
+
+	// A family name which exceeds the maximum length permitted by the
+	// generic netlink controller's policy.
+	ae := netlink.NewAttributeEncoder()
+	ae.String(unix.CTRL_ATTR_FAMILY_NAME, strings.Repeat("a", 64))
+	b, err := ae.Encode()
+	if err != nil {
+		t.Fatalf("failed to encode attributes: %v", err)
+	}
+
+	_, err = c.Execute(netlink.Message{
+		Header: netlink.Header{Type: unix.GENL_ID_CTRL, Flags: netlink.Request},
+		Data:   append([]byte{unix.CTRL_CMD_GETFAMILY, 1, 0, 0}, b...),
+	})
+
+	var oerr *netlink.OpError
+	if !errors.As(err, &oerr) {
+		t.Fatalf("expected *netlink.OpError, but got: %v", err)
+	}
+	if oerr.Policy == nil {
+		t.Skipf("skipping, kernel did not report attribute policy: %v", err)
+	}
+
+	want := &netlink.AttributePolicy{
+		Type:      unix.NL_ATTR_TYPE_NUL_STRING,
+		MaxLength: unix.GENL_NAMSIZ - 1,
+	}
+	if diff := cmp.Diff(want, oerr.Policy); diff != "" {
+		t.Fatalf("unexpected attribute policy (-want +got):\n%s", diff)
+	}
 }
@@ -4,7 +4,10 @@
 package netlink
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"os"
 	"runtime"
 )
 
@@ -23,6 +26,14 @@ type conn struct{}
 
 func dial(_ int, _ *Config) (*conn, uint32, error) { return nil, 0, errUnimplemented }
 func newError(_ int) error                         { return errUnimplemented }
+func isOverrun(_ error) bool                       { return false }
+func isUnsupportedOption(_ error) bool             { return false }
+
+// isUnusable reports whether err indicates that a socket can no longer be
+// used.
+func isUnusable(err error) bool {
+	return errors.Is(err, os.ErrClosed) || errors.Is(err, net.ErrClosed)
+}
 
 func (c *conn) Send(_ Message) error           { return errUnimplemented }
 func (c *conn) SendMessages(_ []Message) error { return errUnimplemented }
@@ -1,13 +1,16 @@
 package netlink_test
 
 import (
+	"context"
 	"errors"
 	"io"
 	"reflect"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/mdlayher/netlink"
 	"github.com/mdlayher/netlink/nltest"
 )
@@ -123,6 +126,445 @@ func TestConnExecuteMultipart(t *testing.T) {
 	}
 }
 
+func TestConnDump(t *testing.T) {
+	req := netlink.Message{
+		Header: netlink.Header{
+			Flags:    netlink.Request | netlink.Dump,
+			Sequence: 1,
+		},
+	}
+
+	var want []netlink.Message
+	c := nltest.Dial(func(_ []netlink.Message) ([]netlink.Message, error) {
+		msgs := []netlink.Message{
+			{Header: netlink.Header{Sequence: 1}, Data: []byte{0x01}},
+			{Header: netlink.Header{Sequence: 1}, Data: []byte{0x02}},
+			{Header: netlink.Header{Sequence: 1}, Data: []byte{0x03}},
+			// Will be filled with multipart done information.
+			{},
+		}
+
+		msgs, err := nltest.Multipart(msgs)
+		want = msgs[:len(msgs)-1]
+		return msgs, err
+	})
+	defer c.Close()
+
+	var got []netlink.Message
+	err := c.Dump(context.Background(), req, func(m netlink.Message) error {
+		got = append(got, m)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to dump: %v", err)
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("unexpected dumped messages (-want +got):\n%s", diff)
+	}
+}
+
+func TestConnDumpError(t *testing.T) {
+	req := netlink.Message{
+		Header: netlink.Header{
+			Flags:    netlink.Request | netlink.Dump,
+			Sequence: 1,
+		},
+	}
+
+	c := nltest.Dial(func(_ []netlink.Message) ([]netlink.Message, error) {
+		return nltest.Multipart([]netlink.Message{
+			{Header: netlink.Header{Sequence: 1}},
+			{Header: netlink.Header{Sequence: 1}},
+			{},
+		})
+	})
+	defer c.Close()
+
+	errBad := errors.New("bad message")
+
+	var n int
+	err := c.Dump(context.Background(), req, func(_ netlink.Message) error {
+		n++
+		return errBad
+	})
+	if !errors.Is(err, errBad) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if n != 1 {
+		t.Fatalf("expected fn to be called once, but got: %d", n)
+	}
+}
+
+func TestConnExecuteReplyTooLarge(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  netlink.Config
+		ok   bool
+	}{
+		{
+			name: "within limits",
+			cfg:  netlink.Config{MaxReplies: 3, MaxReplyBytes: 3 * 20},
+			ok:   true,
+		},
+		{
+			name: "too many replies",
+			cfg:  netlink.Config{MaxReplies: 2},
+		},
+		{
+			name: "too many bytes",
+			cfg:  netlink.Config{MaxReplyBytes: 3*20 - 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restore := nltest.Intercept(func(req []netlink.Message) ([]netlink.Message, error) {
+				if req[0].Header.Flags&netlink.Dump == 0 {
+					return nltest.Error(0, req)
+				}
+
+				m := netlink.Message{
+					Header: netlink.Header{Sequence: req[0].Header.Sequence},
+					Data:   []byte{0xff, 0xff, 0xff, 0xff},
+				}
+
+				return nltest.Multipart([]netlink.Message{m, m, m, {}})
+			})
+			defer restore()
+
+			c, err := netlink.Dial(0, &tt.cfg)
+			if err != nil {
+				t.Fatalf("failed to dial: %v", err)
+			}
+			defer c.Close()
+
+			msgs, err := c.Execute(netlink.Message{
+				Header: netlink.Header{Flags: netlink.Request | netlink.Dump},
+			})
+			if tt.ok {
+				if err != nil || len(msgs) != 3 {
+					t.Fatalf("failed to execute: %d messages, %v", len(msgs), err)
+				}
+				return
+			}
+
+			if !errors.Is(err, netlink.ErrReplyTooLarge) || msgs != nil {
+				t.Fatalf("expected reply too large error, but got: %d messages, %v", len(msgs), err)
+			}
+
+			// The remainder of the dump was consumed, so the Conn is usable.
+			if _, err := c.Execute(netlink.Message{
+				Header: netlink.Header{Flags: netlink.Request | netlink.Acknowledge},
+			}); err != nil {
+				t.Fatalf("failed to execute after error: %v", err)
+			}
+		})
+	}
+}
+
+func TestConnExecuteDumpRetries(t *testing.T) {
+	tests := []struct {
+		name                 string
+		retries, interrupted int
+		report               bool
+		attempts             int
+		ok                   bool
+	}{
+		{
+			name:        "disabled",
+			interrupted: 1,
+			attempts:    1,
+			ok:          true,
+		},
+		{
+			name:        "retried",
+			retries:     2,
+			interrupted: 2,
+			attempts:    3,
+			ok:          true,
+		},
+		{
+			name:        "retries exhausted",
+			retries:     2,
+			interrupted: 3,
+			attempts:    3,
+		},
+		{
+			name:        "detect only",
+			retries:     -1,
+			interrupted: 1,
+			attempts:    1,
+		},
+		{
+			name:        "report only",
+			report:      true,
+			interrupted: 1,
+			attempts:    1,
+		},
+		{
+			name:        "report retried",
+			retries:     1,
+			report:      true,
+			interrupted: 1,
+			attempts:    2,
+			ok:          true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int
+			restore := nltest.Intercept(func(req []netlink.Message) ([]netlink.Message, error) {
+				attempts++
+
+				m := netlink.Message{
+					Header: netlink.Header{Sequence: req[0].Header.Sequence},
+					Data:   []byte{0xff, 0xff, 0xff, 0xff},
+				}
+
+				// The kernel may flag only the final "multi-part done" message.
+				done := netlink.Message{Header: m.Header}
+				if attempts <= tt.interrupted {
+					done.Header.Flags |= netlink.DumpInterrupted
+				}
+
+				return nltest.Multipart([]netlink.Message{m, m, done})
+			})
+			defer restore()
+
+			c, err := netlink.Dial(0, &netlink.Config{
+				DumpRetries:           tt.retries,
+				ReportDumpInterrupted: tt.report,
+			})
+			if err != nil {
+				t.Fatalf("failed to dial: %v", err)
+			}
+			defer c.Close()
+
+			msgs, err := c.Execute(netlink.Message{
+				Header: netlink.Header{Flags: netlink.Request | netlink.Dump},
+			})
+			if tt.ok && err != nil {
+				t.Fatalf("failed to execute: %v", err)
+			}
+			if !tt.ok && !errors.Is(err, netlink.ErrDumpInterrupted) {
+				t.Fatalf("expected dump interrupted error, but got: %v", err)
+			}
+
+			// The replies of the final attempt are always returned.
+			if len(msgs) != 2 {
+				t.Fatalf("expected 2 messages, but got: %d", len(msgs))
+			}
+			if diff := cmp.Diff(tt.attempts, attempts); diff != "" {
+				t.Fatalf("unexpected number of attempts (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConnReceiveDumpInterrupted(t *testing.T) {
+	restore := nltest.Intercept(func(req []netlink.Message) ([]netlink.Message, error) {
+		m := netlink.Message{
+			Header: netlink.Header{
+				Sequence: req[0].Header.Sequence,
+				Flags:    netlink.DumpInterrupted,
+			},
+		}
+
+		return nltest.Multipart([]netlink.Message{m, m, {}})
+	})
+	defer restore()
+
+	c, err := netlink.Dial(0, &netlink.Config{DumpRetries: 1})
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer c.Close()
+
+	req := netlink.Message{
+		Header: netlink.Header{Flags: netlink.Request | netlink.Dump},
+	}
+
+	if _, err := c.Send(req); err != nil {
+		t.Fatalf("failed to send: %v", err)
+	}
+
+	// Receive cannot retry, but reports the interrupted dump.
+	msgs, err := c.Receive()
+	if !errors.Is(err, netlink.ErrDumpInterrupted) || len(msgs) != 2 {
+		t.Fatalf("expected dump interrupted error and 2 messages, but got: %d, %v", len(msgs), err)
+	}
+
+	// Neither can Dump.
+	var n int
+	err = c.Dump(context.Background(), req, func(_ netlink.Message) error {
+		n++
+		return nil
+	})
+	if !errors.Is(err, netlink.ErrDumpInterrupted) || n != 2 {
+		t.Fatalf("expected dump interrupted error and 2 messages, but got: %d, %v", n, err)
+	}
+}
+
+func TestConnStrictMarshal(t *testing.T) {
+	restore := nltest.Intercept(func(req []netlink.Message) ([]netlink.Message, error) {
+		return nltest.Error(0, req)
+	})
+	defer restore()
+
+	c, err := netlink.Dial(0, &netlink.Config{
+		DefaultFlags:  netlink.Request,
+		StrictMarshal: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer c.Close()
+
+	// DefaultFlags are applied before the request is verified.
+	if _, err := c.Execute(netlink.Message{
+		Header: netlink.Header{Type: 0x10, Flags: netlink.Acknowledge},
+	}); err != nil {
+		t.Fatalf("failed to execute: %v", err)
+	}
+
+	if _, err := c.Execute(netlink.Message{
+		Header: netlink.Header{Flags: netlink.Acknowledge},
+	}); err == nil {
+		t.Fatal("expected an error for zero header type, but none occurred")
+	}
+
+	if _, err := c.SendMessages([]netlink.Message{
+		{Header: netlink.Header{Type: 0x10}},
+		{Header: netlink.Header{Type: netlink.Done}},
+	}); err == nil {
+		t.Fatal("expected an error for control header type, but none occurred")
+	}
+}
+
+func TestConnReceiveWithInfo(t *testing.T) {
+	c := nltest.Dial(func(_ []netlink.Message) ([]netlink.Message, error) {
+		return nltest.Multipart([]netlink.Message{
+			{Data: []byte{0x01}},
+			{Data: []byte{0x02}},
+			{},
+		})
+	})
+	defer c.Close()
+
+	msgs, infos, err := c.ReceiveWithInfo()
+	if err != nil {
+		t.Fatalf("failed to receive: %v", err)
+	}
+
+	// nltest cannot report metadata, but an entry is present for each
+	// message, excluding the final "multi-part done" message.
+	if len(msgs) != 2 {
+		t.Fatalf("expected 2 messages, but got: %d", len(msgs))
+	}
+	want := []netlink.MessageInfo{
+		{NSID: netlink.NetNSIDNotAssigned},
+		{NSID: netlink.NetNSIDNotAssigned},
+	}
+
+	if diff := cmp.Diff(want, infos); diff != "" {
+		t.Fatalf("unexpected message info (-want +got):\n%s", diff)
+	}
+}
+
+func TestConnExecuteErrorContext(t *testing.T) {
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{name: "disabled"},
+		{name: "enabled", ok: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restore := nltest.Intercept(func(req []netlink.Message) ([]netlink.Message, error) {
+				return nltest.Error(int(syscall.ENOENT), req)
+			})
+			defer restore()
+
+			c, err := netlink.Dial(0, &netlink.Config{ErrorContext: tt.ok})
+			if err != nil {
+				t.Fatalf("failed to dial: %v", err)
+			}
+			defer c.Close()
+
+			req := netlink.Message{
+				Header: netlink.Header{Type: 0x10, Flags: netlink.Request | netlink.Acknowledge},
+				Data:   []byte{0x01},
+			}
+
+			_, err = c.Execute(req)
+			var oerr *netlink.OpError
+			if !errors.As(err, &oerr) || !errors.Is(err, syscall.ENOENT) {
+				t.Fatalf("expected ENOENT OpError, but got: %v", err)
+			}
+
+			if !tt.ok {
+				if oerr.Request != nil || oerr.Reply != nil {
+					t.Fatalf("unexpected error context: %v", err)
+				}
+				return
+			}
+
+			if oerr.Request == nil || oerr.Reply == nil {
+				t.Fatalf("expected request and reply, but got: %v", err)
+			}
+
+			// The request is reported as it was sent.
+			want := req
+			want.Header.Length = 20
+			want.Header.Sequence = oerr.Request.Header.Sequence
+			want.Header.PID = oerr.Request.Header.PID
+
+			if diff := cmp.Diff(want, *oerr.Request); diff != "" {
+				t.Fatalf("unexpected request (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(netlink.Error, oerr.Reply.Header.Type); diff != "" {
+				t.Fatalf("unexpected reply type (-want +got):\n%s", diff)
+			}
+
+			if !strings.Contains(err.Error(), ", request: {length=20 type=16") {
+				t.Fatalf("error string does not contain request: %v", err)
+			}
+		})
+	}
+}
+
+func TestConnSendMessagesSplit(t *testing.T) {
+	var n int
+	restore := nltest.Intercept(func(req []netlink.Message) ([]netlink.Message, error) {
+		if len(req) != 1 {
+			t.Fatalf("expected a single message per datagram, but got: %d", len(req))
+		}
+
+		n++
+		return nil, nil
+	})
+	defer restore()
+
+	c, err := netlink.Dial(0, &netlink.Config{SplitSendMessages: true})
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer c.Close()
+
+	if _, err := c.SendMessages(make([]netlink.Message, 3)); err != nil {
+		t.Fatalf("failed to send messages: %v", err)
+	}
+
+	if diff := cmp.Diff(3, n); diff != "" {
+		t.Fatalf("unexpected number of datagrams (-want +got):\n%s", diff)
+	}
+}
+
 func TestConnExecuteNoMessages(t *testing.T) {
 	c := nltest.Dial(func(_ []netlink.Message) ([]netlink.Message, error) {
 		return nil, io.EOF
@@ -155,6 +597,28 @@ func TestConnReceiveNoMessages(t *testing.T) {
 	}
 }
 
+func TestConnContextCanceled(t *testing.T) {
+	c := nltest.Dial(func(_ []netlink.Message) ([]netlink.Message, error) {
+		panic("should not be called")
+	})
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// The Socket cannot be interrupted, so operations must not begin once
+	// ctx is canceled.
+	if _, err := c.ExecuteContext(ctx, netlink.Message{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected execute error: %v", err)
+	}
+	if _, err := c.SendContext(ctx, netlink.Message{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected send error: %v", err)
+	}
+	if _, err := c.ReceiveContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected receive error: %v", err)
+	}
+}
+
 func TestConnReceiveShortErrorNumber(t *testing.T) {
 	c := nltest.Dial(func(_ []netlink.Message) ([]netlink.Message, error) {
 		return []netlink.Message{{
@@ -269,3 +733,237 @@ func TestConnSyscallConnUnsupported(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 }
+
+func TestSetDefaultConfig(t *testing.T) {
+	var got []*netlink.Config
+	prevDial := netlink.SetDialInterceptor(func(_ int, config *netlink.Config) (netlink.Socket, uint32, error) {
+		got = append(got, config)
+		return &deadlineSocket{}, nltest.PID, nil
+	})
+	defer netlink.SetDialInterceptor(prevDial)
+
+	var sent int
+	def := &netlink.Config{
+		MaxReplies: 10,
+		Observer: &netlink.Observer{
+			Send: func(_ []netlink.Message) { sent++ },
+		},
+	}
+
+	prev := netlink.SetDefaultConfig(def)
+	defer netlink.SetDefaultConfig(prev)
+
+	// Later changes to the registered Config have no effect.
+	def.MaxReplies = 0
+
+	for _, config := range []*netlink.Config{nil, {}} {
+		c, err := netlink.Dial(0, config)
+		if err != nil {
+			t.Fatalf("failed to dial: %v", err)
+		}
+
+		if _, err := c.Execute(netlink.Message{Header: netlink.Header{Flags: netlink.Request}}); err != nil {
+			t.Fatalf("failed to execute: %v", err)
+		}
+		_ = c.Close()
+	}
+
+	// Only the Conn dialed with a nil Config uses the defaults.
+	if diff := cmp.Diff(10, got[0].MaxReplies); diff != "" {
+		t.Fatalf("unexpected default max replies (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(0, got[1].MaxReplies); diff != "" {
+		t.Fatalf("unexpected explicit max replies (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(1, sent); diff != "" {
+		t.Fatalf("unexpected number of observed sends (-want +got):\n%s", diff)
+	}
+
+	if cfg := netlink.SetDefaultConfig(nil); cfg == nil || cfg.MaxReplies != 10 {
+		t.Fatalf("unexpected previous default config: %+v", cfg)
+	}
+}
+
+func TestConnReceiveScatterUnsupported(t *testing.T) {
+	c := nltest.Dial(nil)
+	defer c.Close()
+
+	if _, _, err := c.ReceiveScatter(nil); !strings.Contains(err.Error(), "not supported") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestConnFileUnsupported(t *testing.T) {
+	c := nltest.Dial(nil)
+	defer c.Close()
+
+	if _, err := c.File(); !strings.Contains(err.Error(), "not supported") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestConnSetTypeFilter(t *testing.T) {
+	var calls int
+	c := nltest.Dial(func(_ []netlink.Message) ([]netlink.Message, error) {
+		calls++
+		if calls == 1 {
+			// Every message is filtered, so the Conn must receive again.
+			return []netlink.Message{{Header: netlink.Header{Type: 0x11}}}, nil
+		}
+
+		return []netlink.Message{
+			{Header: netlink.Header{Type: 0x10}},
+			{Header: netlink.Header{Type: 0x11}},
+			{Header: netlink.Header{Type: 0x12}},
+		}, nil
+	})
+	defer c.Close()
+
+	// nltest does not support BPF, so filtering occurs in user space.
+	if err := c.SetTypeFilter(0x10, 0x12); err != nil {
+		t.Fatalf("failed to set type filter: %v", err)
+	}
+
+	msgs, err := c.Receive()
+	if err != nil {
+		t.Fatalf("failed to receive: %v", err)
+	}
+
+	var types []netlink.HeaderType
+	for _, m := range msgs {
+		types = append(types, m.Header.Type)
+	}
+
+	if want, got := []netlink.HeaderType{0x10, 0x12}, types; !reflect.DeepEqual(want, got) {
+		t.Fatalf("unexpected message types:\n- want: %v\n-  got: %v", want, got)
+	}
+
+	// Removing the filter returns all messages.
+	if err := c.SetTypeFilter(); err != nil {
+		t.Fatalf("failed to remove type filter: %v", err)
+	}
+
+	msgs, err = c.Receive()
+	if err != nil {
+		t.Fatalf("failed to receive: %v", err)
+	}
+
+	if want, got := 3, len(msgs); want != got {
+		t.Fatalf("unexpected number of messages:\n- want: %d\n-  got: %d", want, got)
+	}
+}
+
+func TestConnExecuteRaw(t *testing.T) {
+	c := nltest.Dial(func(req []netlink.Message) ([]netlink.Message, error) {
+		h := req[0].Header
+		return nltest.Multipart([]netlink.Message{
+			{Header: h, Data: []byte{0x01, 0x02, 0x03, 0x04}},
+			{Header: h, Data: []byte{0x05, 0x06, 0x07, 0x08}},
+			{Header: h},
+		})
+	})
+	defer c.Close()
+
+	msgs, raw, err := c.ExecuteRaw(netlink.Message{
+		Header: netlink.Header{Flags: netlink.Request | netlink.Dump},
+	})
+	if err != nil {
+		t.Fatalf("failed to execute: %v", err)
+	}
+
+	if want, got := 2, len(msgs); want != got {
+		t.Fatalf("unexpected number of messages:\n- want: %d\n-  got: %d", want, got)
+	}
+
+	// nltest cannot expose raw datagrams, so they are marshaled from the
+	// messages, including the final multi-part done message.
+	var all []byte
+	for _, b := range raw {
+		all = append(all, b...)
+	}
+
+	var m netlink.Message
+	if err := m.UnmarshalBinary(all[:20]); err != nil {
+		t.Fatalf("failed to unmarshal raw message: %v", err)
+	}
+
+	if want, got := msgs[0].Data, m.Data; !reflect.DeepEqual(want, got) {
+		t.Fatalf("unexpected raw message data:\n- want: %v\n-  got: %v", want, got)
+	}
+
+	if want, got := 2*20+16, len(all); want != got {
+		t.Fatalf("unexpected raw length:\n- want: %d\n-  got: %d", want, got)
+	}
+}
+
+func TestConnReceiveLenient(t *testing.T) {
+	c := nltest.Dial(func(req []netlink.Message) ([]netlink.Message, error) {
+		h := req[0].Header
+		return nltest.Multipart([]netlink.Message{
+			{Header: h, Data: []byte{0x01, 0x02, 0x03, 0x04}},
+			// An error message which is too short to contain an error number.
+			{Header: netlink.Header{Type: netlink.Error}, Data: []byte{0x01}},
+			{Header: h, Data: []byte{0x05, 0x06, 0x07, 0x08}},
+			{Header: h},
+		})
+	})
+	defer c.Close()
+
+	if _, err := c.Send(netlink.Message{
+		Header: netlink.Header{Flags: netlink.Request | netlink.Dump},
+	}); err != nil {
+		t.Fatalf("failed to send: %v", err)
+	}
+
+	msgs, errs, err := c.ReceiveLenient()
+	if err != nil {
+		t.Fatalf("failed to receive: %v", err)
+	}
+
+	if want, got := 3, len(msgs); want != got {
+		t.Fatalf("unexpected number of messages:\n- want: %d\n-  got: %d", want, got)
+	}
+	if want, got := len(msgs), len(errs); want != got {
+		t.Fatalf("unexpected number of errors:\n- want: %d\n-  got: %d", want, got)
+	}
+
+	for i, derr := range errs {
+		if bad := i == 1; bad != (derr != nil) {
+			t.Fatalf("unexpected error for message %d: %v", i, derr)
+		}
+	}
+
+	if want, got := []byte{0x01}, errs[1].Data; !reflect.DeepEqual(want, got) {
+		t.Fatalf("unexpected placeholder data:\n- want: %v\n-  got: %v", want, got)
+	}
+	if want, got := []byte{0x05, 0x06, 0x07, 0x08}, msgs[2].Data; !reflect.DeepEqual(want, got) {
+		t.Fatalf("unexpected message data:\n- want: %v\n-  got: %v", want, got)
+	}
+}
+
+func TestConnBindGroupsJoin(t *testing.T) {
+	sock := &joinSocket{}
+	c := netlink.NewConn(sock, 1)
+
+	// Groups 1, 3, and 32.
+	if err := c.BindGroups(1<<0 | 1<<2 | 1<<31); err != nil {
+		t.Fatalf("failed to bind groups: %v", err)
+	}
+
+	if want, got := []uint32{1, 3, 32}, sock.groups; !reflect.DeepEqual(want, got) {
+		t.Fatalf("unexpected joined groups:\n- want: %v\n-  got: %v", want, got)
+	}
+}
+
+// joinSocket is a netlink.Socket which records joined multicast groups.
+type joinSocket struct {
+	netlink.Socket
+	groups []uint32
+}
+
+func (s *joinSocket) JoinGroup(group uint32) error {
+	s.groups = append(s.groups, group)
+	return nil
+}
+
+func (s *joinSocket) LeaveGroup(_ uint32) error { panic("unimplemented") }
@@ -1,11 +1,14 @@
 package netlink
 
 import (
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 // Arguments used to create a debugger.
@@ -25,13 +28,28 @@ func init() {
 type debugger struct {
 	Log   *log.Logger
 	Level int
+
+	// Dedupe specifies whether runs of identical messages are collapsed into
+	// a single message followed by a repeat count.
+	Dedupe bool
+
+	// Format specifies the output format for netlink messages: "text",
+	// "json", or "strace".
+	Format string
+
+	// mu guards the most recently printed message and the number of times it
+	// has since been repeated, when Dedupe is set.
+	mu      sync.Mutex
+	last    string
+	repeats int
 }
 
 // newDebugger creates a debugger by parsing key=value arguments.
 func newDebugger(args []string) *debugger {
 	d := &debugger{
-		Log:   log.New(os.Stderr, "nl: ", 0),
-		Level: 1,
+		Log:    log.New(os.Stderr, "nl: ", 0),
+		Level:  1,
+		Format: "text",
 	}
 
 	for _, a := range args {
@@ -50,6 +68,23 @@ func newDebugger(args []string) *debugger {
 			}
 
 			d.Level = level
+		// Collapse runs of identical messages.
+		case "dedupe":
+			dedupe, err := strconv.ParseBool(kv[1])
+			if err != nil {
+				panicf("netlink: invalid NLDEBUG dedupe: %q", a)
+			}
+
+			d.Dedupe = dedupe
+		// Select the output format for messages.
+		case "format":
+			switch kv[1] {
+			case "text", "json", "strace":
+			default:
+				panicf("netlink: invalid NLDEBUG format: %q", a)
+			}
+
+			d.Format = kv[1]
 		}
 	}
 
@@ -59,8 +94,227 @@ func newDebugger(args []string) *debugger {
 // debugf prints debugging information at the specified level, if d.Level is
 // high enough to print the message.
 func (d *debugger) debugf(level int, format string, v ...interface{}) {
-	if d.Level >= level {
+	if d.Level < level {
+		return
+	}
+
+	if !d.Dedupe {
 		d.Log.Printf(format, v...)
+		return
+	}
+
+	s := fmt.Sprintf(format, v...)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if s == d.last {
+		d.repeats++
+		return
+	}
+
+	d.lockedFlush()
+	d.Log.Print(s)
+	d.last = s
+}
+
+// debugMessage prints m, which was sent or received by a Conn of the specified
+// family during operation op, in the debugger's Format.
+func (d *debugger) debugMessage(level int, op string, family Family, m Message) {
+	if d.Level < level {
+		return
+	}
+
+	switch d.Format {
+	case "json":
+	case "strace":
+		d.debugf(level, "%s", straceLine(op, family, m))
+		return
+	default:
+		d.debugf(level, "%s: %v", op, m)
+		return
+	}
+
+	b, err := json.Marshal(newDebugJSON(op, family, m))
+	if err != nil {
+		d.debugf(level, "%s: failed to marshal JSON: %v", op, err)
+		return
+	}
+
+	d.debugf(level, "%s", b)
+}
+
+// debugJSON is the JSON representation of a Message printed by a debugger.
+type debugJSON struct {
+	Op     string `json:"op"`
+	Header struct {
+		Length   uint32 `json:"length"`
+		Type     uint16 `json:"type"`
+		Flags    string `json:"flags"`
+		Sequence uint32 `json:"sequence"`
+		PID      uint32 `json:"pid"`
+	} `json:"header"`
+	Attributes []debugAttribute `json:"attributes,omitempty"`
+	Data       string           `json:"data"`
+}
+
+// debugAttribute is the JSON representation of an Attribute printed by a
+// debugger.
+type debugAttribute struct {
+	Type         uint16           `json:"type"`
+	Nested       bool             `json:"nested,omitempty"`
+	NetByteOrder bool             `json:"net_byte_order,omitempty"`
+	Data         string           `json:"data,omitempty"`
+	Attributes   []debugAttribute `json:"attributes,omitempty"`
+}
+
+// newDebugJSON creates the JSON representation of m. Attributes are only
+// decoded for message types whose family-specific header length is known.
+func newDebugJSON(op string, family Family, m Message) debugJSON {
+	dj := debugJSON{
+		Op:   op,
+		Data: hex.EncodeToString(m.Data),
+	}
+
+	dj.Header.Length = m.Header.Length
+	dj.Header.Type = uint16(m.Header.Type)
+	dj.Header.Flags = m.Header.Flags.String()
+	dj.Header.Sequence = m.Header.Sequence
+	dj.Header.PID = m.Header.PID
+
+	if n, ok := familyHeaderLen(family, m.Header.Type); ok && n <= len(m.Data) {
+		dj.Attributes, _ = newDebugAttributes(m.Data[n:])
+	}
+
+	return dj
+}
+
+// newDebugAttributes decodes the attributes in b into a tree, descending into
+// attributes with the Nested flag.
+func newDebugAttributes(b []byte) ([]debugAttribute, bool) {
+	attrs, err := UnmarshalAttributes(b)
+	if err != nil {
+		return nil, false
+	}
+
+	das := make([]debugAttribute, 0, len(attrs))
+	for _, a := range attrs {
+		da := debugAttribute{
+			Type:         a.Type &^ (Nested | NetByteOrder),
+			Nested:       a.Type&Nested != 0,
+			NetByteOrder: a.Type&NetByteOrder != 0,
+		}
+
+		var ok bool
+		if da.Nested {
+			da.Attributes, ok = newDebugAttributes(a.Data)
+		}
+		if !ok {
+			da.Data = hex.EncodeToString(a.Data)
+		}
+
+		das = append(das, da)
+	}
+
+	return das, true
+}
+
+// straceAttrs is the maximum number of attributes summarized by straceLine.
+const straceAttrs = 4
+
+// straceLine produces a compact one-line summary of m, which is more readable
+// than the text format when tracing many messages. The types and lengths of
+// the first few attributes are included for message types whose
+// family-specific header length is known.
+func straceLine(op string, family Family, m Message) string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "%s: type=%s flags=%s seq=%d pid=%d len=%d",
+		op, typeString(m.Header.Type), m.Header.Flags, m.Header.Sequence, m.Header.PID, len(m.Data))
+
+	n, ok := familyHeaderLen(family, m.Header.Type)
+	if !ok || n > len(m.Data) {
+		return sb.String()
+	}
+
+	attrs, err := UnmarshalAttributes(m.Data[n:])
+	if err != nil || len(attrs) == 0 {
+		return sb.String()
+	}
+
+	sb.WriteString(" attrs=[")
+	for i, a := range attrs {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		if i == straceAttrs {
+			fmt.Fprintf(&sb, "+%d", len(attrs)-i)
+			break
+		}
+
+		fmt.Fprintf(&sb, "%d:%d", a.Type&^(Nested|NetByteOrder), len(a.Data))
+	}
+	sb.WriteByte(']')
+
+	return sb.String()
+}
+
+// familyHeaderLen returns the length of the family-specific header which
+// precedes the attributes of messages of type t in family f, if known.
+func familyHeaderLen(f Family, t HeaderType) (int, bool) {
+	if t < minType {
+		// Netlink control messages carry no attributes.
+		return 0, false
+	}
+
+	switch f {
+	case FamilyGeneric:
+		// struct genlmsghdr.
+		return 4, true
+	case FamilyNetfilter:
+		// struct nfgenmsg.
+		return 4, true
+	case FamilyRoute:
+		switch {
+		case t >= 16 && t <= 19:
+			// RTM_*LINK: struct ifinfomsg.
+			return 16, true
+		case t >= 20 && t <= 23:
+			// RTM_*ADDR: struct ifaddrmsg.
+			return 8, true
+		case t >= 24 && t <= 27:
+			// RTM_*ROUTE: struct rtmsg.
+			return 12, true
+		case t >= 28 && t <= 30:
+			// RTM_*NEIGH: struct ndmsg.
+			return 12, true
+		case t >= 32 && t <= 34:
+			// RTM_*RULE: struct fib_rule_hdr.
+			return 12, true
+		case t >= 36 && t <= 46:
+			// RTM_*QDISC, RTM_*TCLASS, and RTM_*TFILTER: struct tcmsg.
+			return 20, true
+		}
+	}
+
+	return 0, false
+}
+
+// flush prints the number of times the most recent message was repeated, if
+// any, when Dedupe is set.
+func (d *debugger) flush() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.lockedFlush()
+	d.last = ""
+}
+
+// lockedFlush implements flush, but must be called with d.mu held.
+func (d *debugger) lockedFlush() {
+	if d.repeats > 0 {
+		d.Log.Printf("last message repeated %d times", d.repeats)
+		d.repeats = 0
 	}
 }
 
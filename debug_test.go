@@ -0,0 +1,134 @@
+package netlink
+
+import (
+	"bytes"
+	"log"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDebuggerDedupe(t *testing.T) {
+	var buf bytes.Buffer
+	d := newDebugger([]string{"dedupe=true"})
+	d.Log = log.New(&buf, "", 0)
+
+	for _, s := range []string{"a", "a", "a", "b", "a", "a"} {
+		d.debugf(1, "recv: %s", s)
+	}
+	d.flush()
+
+	want := `recv: a
+last message repeated 2 times
+recv: b
+recv: a
+last message repeated 1 times
+`
+
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Fatalf("unexpected debug output (-want +got):\n%s", diff)
+	}
+}
+
+func TestDebuggerFormatJSON(t *testing.T) {
+	skipBigEndian(t)
+
+	var buf bytes.Buffer
+	d := newDebugger([]string{"format=json"})
+	d.Log = log.New(&buf, "", 0)
+
+	nested, err := MarshalAttributes([]Attribute{{Type: 2, Data: []byte{0xff}}})
+	if err != nil {
+		t.Fatalf("failed to marshal nested attributes: %v", err)
+	}
+
+	attrs, err := MarshalAttributes([]Attribute{
+		{Type: 1, Data: []byte("hi")},
+		{Type: 3 | Nested, Data: nested},
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal attributes: %v", err)
+	}
+
+	// A generic netlink message with a 4 byte header.
+	d.debugMessage(1, "send", FamilyGeneric, Message{
+		Header: Header{
+			Length:   uint32(nlmsgHeaderLen + 4 + len(attrs)),
+			Type:     0x10,
+			Flags:    Request | Acknowledge,
+			Sequence: 1,
+			PID:      10,
+		},
+		Data: append([]byte{0x03, 0x01, 0x00, 0x00}, attrs...),
+	})
+
+	// A control message, whose attributes are not decoded.
+	d.debugMessage(1, "recv", FamilyGeneric, Message{
+		Header: Header{Length: uint32(nlmsgHeaderLen + 4), Type: Error},
+		Data:   []byte{0x00, 0x00, 0x00, 0x00},
+	})
+
+	want := `{"op":"send","header":{"length":40,"type":16,"flags":"request|acknowledge","sequence":1,"pid":10},"attributes":[{"type":1,"data":"6869"},{"type":3,"nested":true,"attributes":[{"type":2,"data":"ff"}]}],"data":"0301000006000100686900000c00038005000200ff000000"}
+{"op":"recv","header":{"length":20,"type":2,"flags":"0","sequence":0,"pid":0},"data":"00000000"}
+`
+
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Fatalf("unexpected debug output (-want +got):\n%s", diff)
+	}
+}
+
+func TestDebuggerFormatText(t *testing.T) {
+	var buf bytes.Buffer
+	d := newDebugger(nil)
+	d.Log = log.New(&buf, "", 0)
+
+	d.debugMessage(1, "recv", FamilyGeneric, Message{Header: Header{Length: 16}})
+
+	if diff := cmp.Diff("recv: length=16 type=unknown(0) flags=0 seq=0 pid=0 data=[]\n", buf.String()); diff != "" {
+		t.Fatalf("unexpected debug output (-want +got):\n%s", diff)
+	}
+}
+
+func TestDebuggerFormatStrace(t *testing.T) {
+	skipBigEndian(t)
+
+	var buf bytes.Buffer
+	d := newDebugger([]string{"format=strace"})
+	d.Log = log.New(&buf, "", 0)
+
+	attrs := make([]Attribute, 0, 6)
+	for i := 1; i <= 6; i++ {
+		attrs = append(attrs, Attribute{Type: uint16(i), Data: make([]byte, i)})
+	}
+	attrs[1].Type |= Nested
+
+	b, err := MarshalAttributes(attrs)
+	if err != nil {
+		t.Fatalf("failed to marshal attributes: %v", err)
+	}
+
+	// A generic netlink message with a 4 byte header.
+	d.debugMessage(1, "send", FamilyGeneric, Message{
+		Header: Header{
+			Type:     0x10,
+			Flags:    Request | Dump,
+			Sequence: 1,
+			PID:      10,
+		},
+		Data: append([]byte{0x03, 0x01, 0x00, 0x00}, b...),
+	})
+
+	// A control message, whose attributes are not decoded.
+	d.debugMessage(1, "recv", FamilyGeneric, Message{
+		Header: Header{Type: Done, Flags: Multi, Sequence: 1, PID: 10},
+		Data:   []byte{0x00, 0x00, 0x00, 0x00},
+	})
+
+	want := `send: type=16 flags=request|0x300 seq=1 pid=10 len=60 attrs=[1:1 2:2 3:3 4:4 +2]
+recv: type=done flags=multi seq=1 pid=10 len=4
+`
+
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Fatalf("unexpected debug output (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,90 @@
+package netlink
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// A dupFilter discards messages which duplicate another message received
+// within a window of time.
+type dupFilter struct {
+	window time.Duration
+
+	mu   sync.Mutex
+	seen map[dupKey]time.Time
+	// pruned is the time at which expired entries were last removed from
+	// seen.
+	pruned time.Time
+}
+
+// A dupKey identifies a message for duplicate detection.
+type dupKey struct {
+	typ  HeaderType
+	seq  uint32
+	hash uint64
+}
+
+// accept reports whether m, received at now, is accepted by the dupFilter.
+// Messages produced by netlink itself, such as errors and acknowledgements,
+// are always accepted.
+func (f *dupFilter) accept(m Message, now time.Time) bool {
+	if f == nil || m.Header.Type < minType {
+		return true
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write(m.Data)
+	k := dupKey{
+		typ:  m.Header.Type,
+		seq:  m.Header.Sequence,
+		hash: h.Sum64(),
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if now.Sub(f.pruned) >= f.window {
+		for k, t := range f.seen {
+			if now.Sub(t) >= f.window {
+				delete(f.seen, k)
+			}
+		}
+		f.pruned = now
+	}
+
+	if t, ok := f.seen[k]; ok && now.Sub(t) < f.window {
+		return false
+	}
+
+	f.seen[k] = now
+	return true
+}
+
+// SetDuplicateFilter configures the Conn to discard received messages which
+// duplicate a message received within the preceding window of time, such as
+// when a Conn joins multiple multicast groups which deliver the same kernel
+// event. Messages are considered duplicates if they have the same header type,
+// sequence number, and data. Netlink control messages such as errors and
+// acknowledgements are never discarded. If window is less than or equal to 0,
+// any existing duplicate filter is removed.
+//
+// Duplicate messages are discarded as they are received, and are counted by
+// Stats.Duplicates.
+func (c *Conn) SetDuplicateFilter(window time.Duration) {
+	if window <= 0 {
+		c.dupes.Store((*dupFilter)(nil))
+		return
+	}
+
+	c.dupes.Store(&dupFilter{
+		window: window,
+		seen:   make(map[dupKey]time.Time),
+	})
+}
+
+// dupFilter returns the Conn's duplicate filter, or nil if none is set.
+func (c *Conn) dupFilter() *dupFilter {
+	f, _ := c.dupes.Load().(*dupFilter)
+	return f
+}
@@ -0,0 +1,98 @@
+package netlink_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nltest"
+)
+
+func TestConnSetDuplicateFilter(t *testing.T) {
+	var (
+		a = netlink.Message{Header: netlink.Header{Type: 0x10}, Data: []byte{0x01}}
+		b = netlink.Message{Header: netlink.Header{Type: 0x10}, Data: []byte{0x02}}
+		c = netlink.Message{Header: netlink.Header{Type: 0x11}, Data: []byte{0x01}}
+		d = netlink.Message{Header: netlink.Header{Type: 0x10, Sequence: 1}, Data: []byte{0x01}}
+	)
+
+	// Each batch is returned by one read from the socket.
+	batches := [][]netlink.Message{
+		{a, a, b},
+		{a, c},
+		// Every message is a duplicate, so the Conn must receive again.
+		{b},
+		{d},
+		{a},
+	}
+
+	restore := nltest.Intercept(func(req []netlink.Message) ([]netlink.Message, error) {
+		if len(batches) == 0 {
+			t.Fatal("no more batches")
+		}
+
+		msgs := batches[0]
+		batches = batches[1:]
+		return msgs, nil
+	})
+	defer restore()
+
+	clock := &fakeClock{now: time.Unix(1, 0)}
+
+	conn, err := netlink.Dial(0, &netlink.Config{Clock: clock})
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	const window = time.Second
+	conn.SetDuplicateFilter(window)
+
+	for i, want := range [][]netlink.Message{
+		{a, b},
+		{c},
+		{d},
+		nil,
+	} {
+		if want == nil {
+			// Once the window elapses, a is no longer a duplicate.
+			clock.now = clock.now.Add(window)
+			want = []netlink.Message{a}
+		}
+
+		got, err := conn.Receive()
+		if err != nil {
+			t.Fatalf("failed to receive %d: %v", i, err)
+		}
+
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Fatalf("unexpected messages %d (-want +got):\n%s", i, diff)
+		}
+	}
+
+	if diff := cmp.Diff(uint64(3), conn.Stats().Duplicates); diff != "" {
+		t.Fatalf("unexpected duplicates (-want +got):\n%s", diff)
+	}
+}
+
+func TestConnSetDuplicateFilterRemove(t *testing.T) {
+	m := netlink.Message{Header: netlink.Header{Type: 0x10}, Data: []byte{0x01}}
+
+	c := nltest.Dial(func(_ []netlink.Message) ([]netlink.Message, error) {
+		return []netlink.Message{m, m}, nil
+	})
+	defer c.Close()
+
+	c.SetDuplicateFilter(time.Minute)
+	c.SetDuplicateFilter(0)
+
+	msgs, err := c.Receive()
+	if err != nil {
+		t.Fatalf("failed to receive: %v", err)
+	}
+
+	if diff := cmp.Diff([]netlink.Message{m, m}, msgs); diff != "" {
+		t.Fatalf("unexpected messages (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,100 @@
+package netlink
+
+import "time"
+
+// A DialOption configures a Conn created by DialWithOptions. Each DialOption
+// sets one or more fields of a Config; see the documentation of Config for
+// details about each option.
+type DialOption func(cfg *Config)
+
+// DialWithOptions dials a connection to netlink like Dial, using the
+// specified netlink family. The Config passed to Dial is built by applying
+// opts in order, so an option overrides any earlier option which sets the
+// same Config field. The options are applied to the configuration set by
+// SetDefaultConfig, or to a default configuration if none is set.
+func DialWithOptions(family int, opts ...DialOption) (*Conn, error) {
+	var cfg Config
+	if def := defaultDialConfig(); def != nil {
+		cfg = *def
+	}
+
+	for _, o := range opts {
+		o(&cfg)
+	}
+
+	return Dial(family, &cfg)
+}
+
+// WithConfig replaces all fields of the Config with those of cfg, so it is
+// typically the first option passed to DialWithOptions.
+func WithConfig(cfg Config) DialOption {
+	return func(c *Config) { *c = cfg }
+}
+
+// WithGroups joins the multicast groups specified by ID during Dial. Unlike
+// other options, WithGroups adds to the groups specified by earlier options.
+// See Config.JoinGroups.
+func WithGroups(groups ...uint32) DialOption {
+	return func(c *Config) {
+		// Never modify a slice passed to WithConfig.
+		n := len(c.JoinGroups)
+		c.JoinGroups = append(c.JoinGroups[:n:n], groups...)
+	}
+}
+
+// WithNetNS creates the Conn in the network namespace referred to by the file
+// descriptor fd. See Config.NetNS.
+func WithNetNS(fd int) DialOption {
+	return func(c *Config) { c.NetNS = fd }
+}
+
+// WithStrict applies a more strict default set of options to the Conn. See
+// Config.Strict.
+func WithStrict() DialOption {
+	return func(c *Config) { c.Strict = true }
+}
+
+// WithBestEffortOptions enables options if they are supported. Unlike other
+// options, WithBestEffortOptions adds to the options specified by earlier
+// options. See Config.BestEffortOptions.
+func WithBestEffortOptions(options ...ConnOption) DialOption {
+	return func(c *Config) {
+		n := len(c.BestEffortOptions)
+		c.BestEffortOptions = append(c.BestEffortOptions[:n:n], options...)
+	}
+}
+
+// WithDefaultFlags adds flags to every Message sent by the Conn. See
+// Config.DefaultFlags.
+func WithDefaultFlags(flags HeaderFlags) DialOption {
+	return func(c *Config) { c.DefaultFlags = flags }
+}
+
+// WithNoReplyTimeout bounds the amount of time Execute waits for a reply to a
+// request which does not ask for one. See Config.NoReplyTimeout.
+func WithNoReplyTimeout(d time.Duration) DialOption {
+	return func(c *Config) { c.NoReplyTimeout = d }
+}
+
+// WithDumpTimeout bounds the amount of time spent receiving a single
+// multi-part message. See Config.DumpTimeout.
+func WithDumpTimeout(d time.Duration) DialOption {
+	return func(c *Config) { c.DumpTimeout = d }
+}
+
+// WithReadBuffer sets the size of the operating system's receive buffer for
+// the Conn. See Config.ReadBuffer.
+func WithReadBuffer(bytes int) DialOption {
+	return func(c *Config) { c.ReadBuffer = bytes }
+}
+
+// WithWriteBuffer sets the size of the operating system's transmit buffer for
+// the Conn. See Config.WriteBuffer.
+func WithWriteBuffer(bytes int) DialOption {
+	return func(c *Config) { c.WriteBuffer = bytes }
+}
+
+// WithClock sets the Clock used by the Conn. See Config.Clock.
+func WithClock(clock Clock) DialOption {
+	return func(c *Config) { c.Clock = clock }
+}
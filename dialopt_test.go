@@ -0,0 +1,158 @@
+package netlink_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nltest"
+)
+
+func TestDialWithOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []netlink.DialOption
+		want netlink.Config
+	}{
+		{
+			name: "none",
+		},
+		{
+			name: "fields",
+			opts: []netlink.DialOption{
+				netlink.WithGroups(1, 2),
+				netlink.WithNetNS(10),
+				netlink.WithStrict(),
+				netlink.WithBestEffortOptions(netlink.NoENOBUFS),
+				netlink.WithDefaultFlags(netlink.Request),
+				netlink.WithNoReplyTimeout(time.Second),
+				netlink.WithDumpTimeout(2 * time.Second),
+				netlink.WithReadBuffer(1024),
+				netlink.WithWriteBuffer(2048),
+			},
+			want: netlink.Config{
+				JoinGroups:        []uint32{1, 2},
+				NetNS:             10,
+				Strict:            true,
+				BestEffortOptions: []netlink.ConnOption{netlink.NoENOBUFS},
+				DefaultFlags:      netlink.Request,
+				NoReplyTimeout:    time.Second,
+				DumpTimeout:       2 * time.Second,
+				ReadBuffer:        1024,
+				WriteBuffer:       2048,
+			},
+		},
+		{
+			name: "config overridden",
+			opts: []netlink.DialOption{
+				netlink.WithConfig(netlink.Config{
+					JoinGroups: []uint32{1},
+					NetNS:      10,
+					PID:        100,
+				}),
+				netlink.WithGroups(2),
+				netlink.WithNetNS(20),
+			},
+			want: netlink.Config{
+				JoinGroups: []uint32{1, 2},
+				NetNS:      20,
+				PID:        100,
+			},
+		},
+		{
+			name: "config replaces",
+			opts: []netlink.DialOption{
+				netlink.WithNetNS(20),
+				netlink.WithConfig(netlink.Config{PID: 100}),
+			},
+			want: netlink.Config{PID: 100},
+		},
+	}
+
+	// Stop each Dial once the Config is captured.
+	errDial := errors.New("dial stopped")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *netlink.Config
+			prev := netlink.SetDialInterceptor(func(_ int, cfg *netlink.Config) (netlink.Socket, uint32, error) {
+				got = cfg
+				return nil, 0, errDial
+			})
+			defer netlink.SetDialInterceptor(prev)
+
+			if _, err := netlink.DialWithOptions(0, tt.opts...); !errors.Is(err, errDial) {
+				t.Fatalf("unexpected dial error: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.want, *got); diff != "" {
+				t.Fatalf("unexpected config (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDialWithOptionsDefaultConfig(t *testing.T) {
+	var got []netlink.Config
+	prev := netlink.SetDialInterceptor(func(_ int, cfg *netlink.Config) (netlink.Socket, uint32, error) {
+		got = append(got, *cfg)
+		return nil, 0, errors.New("dial stopped")
+	})
+	defer netlink.SetDialInterceptor(prev)
+
+	def := &netlink.Config{
+		JoinGroups: []uint32{1},
+		Strict:     true,
+	}
+
+	prevDef := netlink.SetDefaultConfig(def)
+	defer netlink.SetDefaultConfig(prevDef)
+
+	_, _ = netlink.DialWithOptions(0)
+	_, _ = netlink.DialWithOptions(0, netlink.WithGroups(2))
+	_, _ = netlink.DialWithOptions(0, netlink.WithConfig(netlink.Config{PID: 100}))
+
+	want := []netlink.Config{
+		// Options are applied to the default Config, which is unmodified.
+		{JoinGroups: []uint32{1}, Strict: true},
+		{JoinGroups: []uint32{1, 2}, Strict: true},
+		{PID: 100},
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("unexpected configs (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]uint32{1}, def.JoinGroups); diff != "" {
+		t.Fatalf("unexpected default groups (-want +got):\n%s", diff)
+	}
+}
+
+func TestDialWithOptionsClock(t *testing.T) {
+	restore := nltest.Intercept(func(req []netlink.Message) ([]netlink.Message, error) {
+		return []netlink.Message{{Header: req[0].Header}}, nil
+	})
+	defer restore()
+
+	clock := &fakeClock{now: time.Unix(1, 0)}
+
+	c, err := netlink.DialWithOptions(0, netlink.WithClock(clock))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer c.Close()
+
+	var tx netlink.Transaction
+	c.SetObserver(&netlink.Observer{
+		Execute: func(t netlink.Transaction) { tx = t },
+	})
+
+	if _, err := c.Execute(netlink.Message{Header: netlink.Header{Flags: netlink.Request | netlink.Acknowledge}}); err != nil {
+		t.Fatalf("failed to execute: %v", err)
+	}
+
+	if diff := cmp.Diff(clock.now, tx.Start); diff != "" {
+		t.Fatalf("unexpected transaction start (-want +got):\n%s", diff)
+	}
+}
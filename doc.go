@@ -12,6 +12,11 @@
 // configuration. The Config structure passed to Dial to create a Conn controls
 // these behaviors. See the documentation of Config.NetNS for details.
 //
+// Applications which change the network namespace of the calling thread before
+// calling Dial must lock the calling goroutine to its thread using
+// runtime.LockOSThread. Config.VerifyThreadNetNS can be used to detect some
+// cases where this was not done.
+//
 // # Debugging
 //
 // This package supports rudimentary netlink connection debugging support. To
@@ -30,4 +35,15 @@
 // Available key/value debugger options include:
 //
 //	level=N: specify the debugging level (only "1" is currently supported)
+//	dedupe=true: collapse runs of identical messages, such as repeated
+//	  multicast events, into a single message and a repeat count
+//	format=json: print each message as a JSON object with its header fields,
+//	  hex-encoded data, and its attributes, decoded for message types with
+//	  a well-known family header
+//	format=strace: print a one-line summary of each message with its header
+//	  fields, data length, and the types and lengths of its first attributes
+//
+// To trace the messages sent and received by an individual Conn using your own
+// logging or metrics, set the Send, Receive, and Error callbacks of an Observer
+// with Conn.SetObserver.
 package netlink
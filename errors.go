@@ -15,6 +15,83 @@ var (
 	errShortErrorMessage  = errors.New("not enough data for netlink error code")
 )
 
+// ErrDumpTimeout is returned along with any partial results when a multi-part
+// message is not completely received within Config.DumpTimeout.
+var ErrDumpTimeout = errors.New("netlink: timed out receiving multi-part message")
+
+// ErrDumpInterrupted is returned along with the replies to a dump which was
+// interrupted by a change in kernel state, and which remained inconsistent
+// after the retries allowed by Config.DumpRetries, if Config.DumpRetries or
+// Config.ReportDumpInterrupted is set. Use errors.Is to check for this error
+// rather than inspecting the flags of each reply.
+var ErrDumpInterrupted = errors.New("netlink: dump was interrupted by a change in kernel state")
+
+// ErrReplyTooLarge is returned when the replies to a request exceed the limits
+// set by Config.MaxReplies or Config.MaxReplyBytes.
+var ErrReplyTooLarge = errors.New("netlink: replies exceed configured size limit")
+
+// ErrPortInUse is matched by errors.Is when Dial fails because the port ID
+// set in Config.PID is already bound by another netlink socket. The error is a
+// *PortInUseError, which identifies the process holding the port if possible.
+var ErrPortInUse = errors.New("netlink: port ID already in use")
+
+// A PortInUseError is returned by Dial when the port ID set in Config.PID is
+// already bound by another netlink socket of the same family and network
+// namespace.
+type PortInUseError struct {
+	// PortID is the port ID which could not be bound.
+	PortID uint32
+
+	// PID is the ID of a process which holds the socket bound to PortID, or 0
+	// if it could not be determined, such as when the sock_diag family is
+	// unavailable or the process is not visible to the caller.
+	PID int
+
+	// Err is the underlying error returned when binding the socket.
+	Err error
+}
+
+// Error implements error.
+func (e *PortInUseError) Error() string {
+	if e.PID == 0 {
+		return fmt.Sprintf("netlink: port ID %d already in use", e.PortID)
+	}
+
+	return fmt.Sprintf("netlink: port ID %d already in use by process %d", e.PortID, e.PID)
+}
+
+// Unwrap unwraps the internal Err field for use with errors.Unwrap.
+func (e *PortInUseError) Unwrap() error { return e.Err }
+
+// Is reports whether target is ErrPortInUse, for use with errors.Is.
+func (e *PortInUseError) Is(target error) bool { return target == ErrPortInUse }
+
+// ErrTruncated is matched by errors.Is when a datagram received from netlink
+// was larger than the buffer available to receive it, so only part of the
+// datagram could be read. The error is a *TruncatedError.
+var ErrTruncated = errors.New("netlink: datagram truncated")
+
+// A TruncatedError is returned by receive operations when the kernel reports
+// that a datagram did not fit in the receive buffer. The remainder of the
+// datagram is discarded by the kernel, so rather than parsing a partial
+// message, the receive operation reports the sizes involved.
+type TruncatedError struct {
+	// Length is the full length of the datagram as reported by the kernel.
+	Length int
+
+	// Received is the number of bytes of the datagram which were received.
+	Received int
+}
+
+// Error implements error.
+func (e *TruncatedError) Error() string {
+	return fmt.Sprintf("netlink: datagram truncated: received %d of %d bytes",
+		e.Received, e.Length)
+}
+
+// Is reports whether target is ErrTruncated, for use with errors.Is.
+func (e *TruncatedError) Is(target error) bool { return target == ErrTruncated }
+
 // Errors which can be returned by a Socket that does not implement
 // all exposed methods of Conn.
 
@@ -25,6 +102,30 @@ func notSupported(op string) error {
 	return newOpError(op, errNotSupported)
 }
 
+// A DecodeError describes a received message which could not be decoded. In
+// lenient mode, such as with Conn.ReceiveLenient, a DecodeError is returned in
+// place of the message rather than aborting the entire receive operation.
+type DecodeError struct {
+	// Header is the header of the message, which may contain invalid values.
+	Header Header
+
+	// Data is the message data following the header. If the message could not
+	// be separated from the messages after it, Data extends to the end of the
+	// datagram.
+	Data []byte
+
+	// Err is the reason the message could not be decoded.
+	Err error
+}
+
+// Error implements error.
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("netlink: failed to decode message of type %s: %v", e.Header.Type, e.Err)
+}
+
+// Unwrap unwraps the internal Err field for use with errors.Unwrap.
+func (e *DecodeError) Unwrap() error { return e.Err }
+
 // IsNotExist determines if an error is produced as the result of querying some
 // file, object, resource, etc. which does not exist.
 //
@@ -69,6 +170,48 @@ type OpError struct {
 	// is not set, both of these fields will be empty.
 	Message string
 	Offset  int
+
+	// Policy describes the constraints on the offending attribute, if the
+	// kernel provided them in the extended acknowledgement, or nil if not.
+	// Policy is only provided by newer kernels when the ExtendedAcknowledge
+	// option is set.
+	Policy *AttributePolicy
+
+	// MissingType is the type of a required attribute which was missing from
+	// the request, or 0 if none was reported. If the attribute was missing
+	// from a nested attribute, MissingNest is the offset of that nested
+	// attribute in the request, or 0 if it was missing at the top level. Both
+	// fields are only provided by Linux 5.19+ when the ExtendedAcknowledge
+	// option is set.
+	MissingType uint16
+	MissingNest int
+
+	// Cookie is an opaque value attached to the extended acknowledgement by
+	// some subsystems, or nil if none was provided. Cookies usually accompany
+	// successful acknowledgements, which are returned by Conn.ExecuteAck.
+	Cookie []byte
+
+	// Request and Reply contain the request sent by Execute and the reply
+	// which carried the error, if any, when the ErrorContext option is set in
+	// Config. If this option is not set, both of these fields will be nil.
+	Request *Message
+	Reply   *Message
+}
+
+// withRequest sets the Request field of err to req, if err is an *OpError.
+func withRequest(err error, req Message) {
+	var oerr *OpError
+	if errors.As(err, &oerr) && oerr.Request == nil {
+		oerr.Request = &req
+	}
+}
+
+// withReply sets the Reply field of err to reply, if err is an *OpError.
+func withReply(err error, reply Message) {
+	var oerr *OpError
+	if errors.As(err, &oerr) && oerr.Reply == nil {
+		oerr.Reply = &reply
+	}
 }
 
 // newOpError is a small wrapper for creating an OpError. As a convenience, it
@@ -96,6 +239,21 @@ func (e *OpError) Error() string {
 		_, _ = sb.WriteString(fmt.Sprintf(", offset: %d, message: %q",
 			e.Offset, e.Message))
 	}
+	if e.Policy != nil {
+		_, _ = sb.WriteString(fmt.Sprintf(", policy: {%v}", e.Policy))
+	}
+	if e.MissingType != 0 {
+		_, _ = sb.WriteString(fmt.Sprintf(", missing attribute: %d", e.MissingType))
+		if e.MissingNest != 0 {
+			_, _ = sb.WriteString(fmt.Sprintf(" in nest at offset: %d", e.MissingNest))
+		}
+	}
+	if e.Request != nil {
+		_, _ = sb.WriteString(fmt.Sprintf(", request: {%v}", e.Request))
+	}
+	if e.Reply != nil {
+		_, _ = sb.WriteString(fmt.Sprintf(", reply: {%v}", e.Reply))
+	}
 
 	return sb.String()
 }
@@ -136,3 +294,54 @@ func (e *OpError) Temporary() bool {
 	t, ok := e.Err.(temporary)
 	return ok && t.Temporary()
 }
+
+// An AttributePolicy describes the constraints which the kernel places on the
+// value of an attribute, as reported in an extended acknowledgement when an
+// attribute fails validation. Fields which were not reported are zero.
+type AttributePolicy struct {
+	// Type is the type of the attribute expected by the kernel, as one of
+	// the NL_ATTR_TYPE_* constants from linux/netlink.h.
+	Type uint32
+
+	// MinSigned and MaxSigned are the inclusive range of values permitted for
+	// a signed integer attribute, and MinUnsigned and MaxUnsigned are the
+	// range permitted for an unsigned integer attribute.
+	MinSigned, MaxSigned     int64
+	MinUnsigned, MaxUnsigned uint64
+
+	// MinLength and MaxLength are the inclusive range of lengths in bytes
+	// permitted for a binary or string attribute.
+	MinLength, MaxLength uint32
+
+	// Mask is the set of bits which may be set in an integer or bitfield32
+	// attribute.
+	Mask uint64
+
+	// PolicyIndex and PolicyMaxType identify the policy of a nested attribute
+	// and the maximum attribute type permitted within it.
+	PolicyIndex, PolicyMaxType uint32
+}
+
+// String returns a compact representation of the non-zero fields of p.
+func (p *AttributePolicy) String() string {
+	var sb strings.Builder
+	_, _ = sb.WriteString(fmt.Sprintf("type: %d", p.Type))
+
+	if p.MinSigned != 0 || p.MaxSigned != 0 {
+		_, _ = sb.WriteString(fmt.Sprintf(", range: [%d, %d]", p.MinSigned, p.MaxSigned))
+	}
+	if p.MinUnsigned != 0 || p.MaxUnsigned != 0 {
+		_, _ = sb.WriteString(fmt.Sprintf(", range: [%d, %d]", p.MinUnsigned, p.MaxUnsigned))
+	}
+	if p.MinLength != 0 || p.MaxLength != 0 {
+		_, _ = sb.WriteString(fmt.Sprintf(", length: [%d, %d]", p.MinLength, p.MaxLength))
+	}
+	if p.Mask != 0 {
+		_, _ = sb.WriteString(fmt.Sprintf(", mask: %#x", p.Mask))
+	}
+	if p.PolicyIndex != 0 || p.PolicyMaxType != 0 {
+		_, _ = sb.WriteString(fmt.Sprintf(", policy: %d, max type: %d", p.PolicyIndex, p.PolicyMaxType))
+	}
+
+	return sb.String()
+}
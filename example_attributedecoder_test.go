@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 
+	"github.com/josharian/native"
 	"github.com/mdlayher/netlink"
 )
 
@@ -89,3 +90,140 @@ Nested:
 	//    - A: 2
 	//    - B: 3
 }
+
+// This example demonstrates using a netlink.AttributeDecoder and
+// netlink.AttributeEncoder to modify a single attribute while preserving all
+// other attributes, which may not be understood by the caller.
+func ExampleAttributeDecoder_rewrite() {
+	ad, err := netlink.NewAttributeDecoder(exampleAttributes())
+	if err != nil {
+		log.Fatalf("failed to create attribute decoder: %v", err)
+	}
+
+	// Collect any attributes which we do not explicitly decode.
+	ad.CollectUnknown = true
+
+	var s string
+	for ad.Next() {
+		// Only the string attribute is understood by this program.
+		if ad.Type() == 2 {
+			s = ad.String()
+		}
+	}
+
+	if err := ad.Err(); err != nil {
+		log.Fatalf("failed to decode attributes: %v", err)
+	}
+
+	// Re-encode the modified string along with the unknown attributes, which
+	// are preserved exactly as they were received.
+	ae := netlink.NewAttributeEncoder()
+	ae.String(2, s+", from netlink")
+	ae.Attributes(ad.Unknown()...)
+
+	b, err := ae.Encode()
+	if err != nil {
+		log.Fatalf("failed to encode attributes: %v", err)
+	}
+
+	attrs, err := netlink.UnmarshalAttributes(b)
+	if err != nil {
+		log.Fatalf("failed to unmarshal attributes: %v", err)
+	}
+
+	for _, a := range attrs {
+		fmt.Printf("type: %d, length: %d\n", a.Type, a.Length)
+	}
+	// Output:
+	// type: 2, length: 30
+	// type: 1, length: 6
+	// type: 3, length: 20
+}
+
+// This example demonstrates decoding nested attributes which contain 64-bit
+// alignment padding, as sent by the taskstats generic netlink family. A
+// taskstats reply contains a TASKSTATS_TYPE_AGGR_PID attribute which nests the
+// PID, a zero-length TASKSTATS_TYPE_NULL padding attribute, and the raw
+// struct taskstats in TASKSTATS_TYPE_STATS.
+func ExampleAttributeDecoder_taskstats() {
+	const (
+		typePID     = 1
+		typeStats   = 3
+		typeAggrPID = 4
+		typeNull    = 6
+	)
+
+	ad, err := netlink.NewAttributeDecoder(exampleTaskstatsAttributes())
+	if err != nil {
+		log.Fatalf("failed to create attribute decoder: %v", err)
+	}
+
+	var (
+		pid     uint32
+		version uint16
+		code    uint32
+	)
+
+	for ad.Next() {
+		if ad.Type() != typeAggrPID {
+			continue
+		}
+
+		ad.Nested(func(nad *netlink.AttributeDecoder) error {
+			// Padding attributes are specific to each set of attributes and
+			// must be configured on the nested decoder.
+			nad.Pad64 = typeNull
+
+			for nad.Next() {
+				switch nad.Type() {
+				case typePID:
+					pid = nad.Uint32()
+				case typeStats:
+					// Decode the leading fields of struct taskstats.
+					nad.Do(func(b []byte) error {
+						if len(b) < 8 {
+							return fmt.Errorf("unexpected taskstats length: %d", len(b))
+						}
+
+						version = nad.ByteOrder.Uint16(b[0:2])
+						code = nad.ByteOrder.Uint32(b[4:8])
+						return nil
+					})
+				}
+			}
+
+			return nil
+		})
+	}
+
+	if err := ad.Err(); err != nil {
+		log.Fatalf("failed to decode attributes: %v", err)
+	}
+
+	fmt.Printf("PID: %d, version: %d, exit code: %d\n", pid, version, code)
+	// Output:
+	// PID: 1234, version: 10, exit code: 1
+}
+
+// exampleTaskstatsAttributes returns attributes in the format of a taskstats
+// reply, including a padding attribute.
+func exampleTaskstatsAttributes() []byte {
+	stats := make([]byte, 16)
+	native.Endian.PutUint16(stats[0:2], 10)
+	native.Endian.PutUint32(stats[4:8], 1)
+
+	ae := netlink.NewAttributeEncoder()
+	ae.Nested(4, func(nae *netlink.AttributeEncoder) error {
+		nae.Uint32(1, 1234)
+		nae.Bytes(6, nil)
+		nae.Bytes(3, stats)
+		return nil
+	})
+
+	b, err := ae.Encode()
+	if err != nil {
+		log.Fatalf("failed to encode attributes: %v", err)
+	}
+
+	return b
+}
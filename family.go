@@ -0,0 +1,85 @@
+package netlink
+
+import "fmt"
+
+// A Family is a netlink family, used to select the kernel subsystem a Conn
+// communicates with when calling Dial:
+//
+//	c, err := netlink.Dial(int(netlink.FamilyGeneric), nil)
+type Family int
+
+// Well-known netlink families. These constants are equivalent to the Linux
+// NETLINK_* protocol values.
+const (
+	FamilyRoute         Family = 0
+	FamilyUserSock      Family = 2
+	FamilyFirewall      Family = 3
+	FamilySockDiag      Family = 4
+	FamilyNFLog         Family = 5
+	FamilyXFRM          Family = 6
+	FamilySELinux       Family = 7
+	FamilyISCSI         Family = 8
+	FamilyAudit         Family = 9
+	FamilyFIBLookup     Family = 10
+	FamilyConnector     Family = 11
+	FamilyNetfilter     Family = 12
+	FamilyIP6Firewall   Family = 13
+	FamilyDNRTMsg       Family = 14
+	FamilyKobjectUevent Family = 15
+	FamilyGeneric       Family = 16
+	FamilySCSITransport Family = 18
+	FamilyECryptfs      Family = 19
+	FamilyRDMA          Family = 20
+	FamilyCrypto        Family = 21
+	FamilySMC           Family = 22
+)
+
+// String returns the string representation of a Family.
+func (f Family) String() string {
+	switch f {
+	case FamilyRoute:
+		return "route"
+	case FamilyUserSock:
+		return "usersock"
+	case FamilyFirewall:
+		return "firewall"
+	case FamilySockDiag:
+		return "sock_diag"
+	case FamilyNFLog:
+		return "nflog"
+	case FamilyXFRM:
+		return "xfrm"
+	case FamilySELinux:
+		return "selinux"
+	case FamilyISCSI:
+		return "iscsi"
+	case FamilyAudit:
+		return "audit"
+	case FamilyFIBLookup:
+		return "fib_lookup"
+	case FamilyConnector:
+		return "connector"
+	case FamilyNetfilter:
+		return "netfilter"
+	case FamilyIP6Firewall:
+		return "ip6_fw"
+	case FamilyDNRTMsg:
+		return "dnrtmsg"
+	case FamilyKobjectUevent:
+		return "kobject_uevent"
+	case FamilyGeneric:
+		return "generic"
+	case FamilySCSITransport:
+		return "scsitransport"
+	case FamilyECryptfs:
+		return "ecryptfs"
+	case FamilyRDMA:
+		return "rdma"
+	case FamilyCrypto:
+		return "crypto"
+	case FamilySMC:
+		return "smc"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(f))
+	}
+}
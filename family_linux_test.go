@@ -0,0 +1,46 @@
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestFamilyValuesLinux(t *testing.T) {
+	tests := []struct {
+		f    Family
+		want int
+	}{
+		{f: FamilyRoute, want: unix.NETLINK_ROUTE},
+		{f: FamilyUserSock, want: unix.NETLINK_USERSOCK},
+		{f: FamilyFirewall, want: unix.NETLINK_FIREWALL},
+		{f: FamilySockDiag, want: unix.NETLINK_SOCK_DIAG},
+		{f: FamilyNFLog, want: unix.NETLINK_NFLOG},
+		{f: FamilyXFRM, want: unix.NETLINK_XFRM},
+		{f: FamilySELinux, want: unix.NETLINK_SELINUX},
+		{f: FamilyISCSI, want: unix.NETLINK_ISCSI},
+		{f: FamilyAudit, want: unix.NETLINK_AUDIT},
+		{f: FamilyFIBLookup, want: unix.NETLINK_FIB_LOOKUP},
+		{f: FamilyConnector, want: unix.NETLINK_CONNECTOR},
+		{f: FamilyNetfilter, want: unix.NETLINK_NETFILTER},
+		{f: FamilyIP6Firewall, want: unix.NETLINK_IP6_FW},
+		{f: FamilyDNRTMsg, want: unix.NETLINK_DNRTMSG},
+		{f: FamilyKobjectUevent, want: unix.NETLINK_KOBJECT_UEVENT},
+		{f: FamilyGeneric, want: unix.NETLINK_GENERIC},
+		{f: FamilySCSITransport, want: unix.NETLINK_SCSITRANSPORT},
+		{f: FamilyECryptfs, want: unix.NETLINK_ECRYPTFS},
+		{f: FamilyRDMA, want: unix.NETLINK_RDMA},
+		{f: FamilyCrypto, want: unix.NETLINK_CRYPTO},
+		{f: FamilySMC, want: unix.NETLINK_SMC},
+	}
+
+	for _, tt := range tests {
+		if want, got := tt.want, int(tt.f); want != got {
+			t.Errorf("unexpected value for family %s:\n- want: %d\n-  got: %d",
+				tt.f, want, got)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package netlink
+
+import "testing"
+
+func TestFamilyString(t *testing.T) {
+	tests := []struct {
+		f Family
+		s string
+	}{
+		{
+			f: FamilyRoute,
+			s: "route",
+		},
+		{
+			f: FamilySockDiag,
+			s: "sock_diag",
+		},
+		{
+			f: FamilyKobjectUevent,
+			s: "kobject_uevent",
+		},
+		{
+			f: FamilyGeneric,
+			s: "generic",
+		},
+		{
+			f: 1,
+			s: "unknown(1)",
+		},
+		{
+			f: 100,
+			s: "unknown(100)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			if want, got := tt.s, tt.f.String(); want != got {
+				t.Fatalf("unexpected string for %d:\n- want: %q\n-  got: %q",
+					int(tt.f), want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package netlink
+
+import "errors"
+
+// Features describes the optional capabilities which were successfully
+// enabled for a Conn, such as those requested by Config.BestEffortOptions.
+type Features struct {
+	// Options reports whether each of the ConnOptions specified by
+	// Config.BestEffortOptions was enabled. Options which are not supported by
+	// the kernel or the Conn's Socket are reported as false.
+	Options map[ConnOption]bool
+}
+
+// Features returns the optional capabilities which were enabled for the Conn
+// by Dial. The returned Features must not be modified.
+func (c *Conn) Features() Features { return c.features }
+
+// enableBestEffort enables each of options for the Conn, recording which of
+// the options are supported in the Conn's Features. Any error other than a
+// lack of support for an option is returned.
+func (c *Conn) enableBestEffort(options []ConnOption) error {
+	if len(options) == 0 {
+		return nil
+	}
+
+	enabled := make(map[ConnOption]bool, len(options))
+	for _, o := range options {
+		err := c.SetOption(o, true)
+		switch {
+		case err == nil:
+			enabled[o] = true
+		case errors.Is(err, errNotSupported) || isUnsupportedOption(err):
+			enabled[o] = false
+		default:
+			return err
+		}
+	}
+
+	c.features.Options = enabled
+	return nil
+}
@@ -0,0 +1,34 @@
+package netlink_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nltest"
+)
+
+func TestConnFeatures(t *testing.T) {
+	restore := nltest.Intercept(func(_ []netlink.Message) ([]netlink.Message, error) {
+		return nil, nil
+	})
+	defer restore()
+
+	c, err := netlink.Dial(int(netlink.FamilyGeneric), &netlink.Config{
+		BestEffortOptions: []netlink.ConnOption{netlink.ExtendedAcknowledge},
+	})
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer c.Close()
+
+	// nltest cannot set options, so the option is reported as disabled rather
+	// than causing Dial to fail.
+	want := netlink.Features{
+		Options: map[netlink.ConnOption]bool{netlink.ExtendedAcknowledge: false},
+	}
+
+	if diff := cmp.Diff(want, c.Features()); diff != "" {
+		t.Fatalf("unexpected features (-want +got):\n%s", diff)
+	}
+}
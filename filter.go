@@ -0,0 +1,131 @@
+package netlink
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+
+	"github.com/mdlayher/netlink/nlenc"
+	"golang.org/x/net/bpf"
+)
+
+// A typeFilter is a set of HeaderTypes which a Conn accepts.
+type typeFilter struct {
+	types map[HeaderType]struct{}
+
+	// bpf reports whether a BPF program was attached for this filter.
+	bpf bool
+}
+
+// accept reports whether m is accepted by the typeFilter. Messages produced by
+// netlink itself, such as errors and acknowledgements, are always accepted.
+func (f *typeFilter) accept(m Message) bool {
+	if f == nil || m.Header.Type < minType {
+		return true
+	}
+
+	_, ok := f.types[m.Header.Type]
+	return ok
+}
+
+// minType is the lowest HeaderType which may be used by a netlink family;
+// lower values are reserved for netlink control messages such as Error.
+const minType HeaderType = 0x10
+
+// SetTypeFilter configures the Conn to only receive messages whose header type
+// is one of types, such as the notification types an application monitors.
+// Netlink control messages such as errors and acknowledgements are always
+// received. If types is empty, any existing type filter is removed.
+//
+// When supported, SetTypeFilter attaches a BPF program to the Conn so that the
+// kernel discards unwanted messages, replacing any program attached with
+// SetBPF. Because a BPF program only inspects the first message in each
+// datagram, messages are also filtered in user space as they are received,
+// which allows SetTypeFilter to function with Sockets which do not support BPF.
+func (c *Conn) SetTypeFilter(types ...HeaderType) error {
+	prev, _ := c.filter.Load().(*typeFilter)
+
+	if len(types) == 0 {
+		c.filter.Store((*typeFilter)(nil))
+
+		if prev != nil && prev.bpf {
+			return c.RemoveBPF()
+		}
+
+		return nil
+	}
+
+	f := &typeFilter{types: make(map[HeaderType]struct{}, len(types))}
+	for _, t := range types {
+		f.types[t] = struct{}{}
+	}
+
+	prog, ok := typeFilterBPF(types)
+	if ok {
+		err := c.SetBPF(prog)
+		switch {
+		case err == nil:
+			f.bpf = true
+		case errors.Is(err, errNotSupported):
+			// Fall back to user space filtering alone.
+		default:
+			return err
+		}
+	}
+
+	if !f.bpf && prev != nil && prev.bpf {
+		if err := c.RemoveBPF(); err != nil {
+			return err
+		}
+	}
+
+	c.filter.Store(f)
+	return nil
+}
+
+// typeFilter returns the Conn's type filter, or nil if none is set.
+func (c *Conn) typeFilter() *typeFilter {
+	f, _ := c.filter.Load().(*typeFilter)
+	return f
+}
+
+// typeFilterBPF assembles a BPF program which accepts datagrams whose first
+// message has a control type or one of types. It reports false if the program
+// cannot be assembled, such as when too many types are specified.
+func typeFilterBPF(types []HeaderType) ([]bpf.RawInstruction, bool) {
+	all := append([]HeaderType{Noop, Error, Done, Overrun}, types...)
+
+	// Each jump must be able to reach the accept instruction at the end of
+	// the program.
+	if len(all) > math.MaxUint8 {
+		return nil, false
+	}
+
+	// The header type field is in native byte order, but BPF loads values in
+	// network byte order.
+	insts := []bpf.Instruction{
+		bpf.LoadAbsolute{Off: 4, Size: 2},
+	}
+
+	for i, t := range all {
+		insts = append(insts, bpf.JumpIf{
+			Cond:     bpf.JumpEqual,
+			Val:      uint32(binary.BigEndian.Uint16(nlenc.Uint16Bytes(uint16(t)))),
+			SkipTrue: uint8(len(all) - i),
+		})
+	}
+
+	insts = append(insts,
+		// Reject.
+		bpf.RetConstant{Val: 0},
+		// Accept.
+		bpf.RetConstant{Val: math.MaxUint32},
+	)
+
+	prog, err := bpf.Assemble(insts)
+	if err != nil {
+		return nil, false
+	}
+
+	return prog, true
+}
@@ -0,0 +1,63 @@
+package netlink
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/net/bpf"
+)
+
+func TestTypeFilterBPF(t *testing.T) {
+	prog, ok := typeFilterBPF([]HeaderType{0x10, 0x20})
+	if !ok {
+		t.Fatal("failed to assemble BPF program")
+	}
+
+	insts, allDecoded := bpf.Disassemble(prog)
+	if !allDecoded {
+		t.Fatal("failed to disassemble BPF program")
+	}
+
+	vm, err := bpf.NewVM(insts)
+	if err != nil {
+		t.Fatalf("failed to create BPF VM: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		typ    HeaderType
+		accept bool
+	}{
+		{name: "error", typ: Error, accept: true},
+		{name: "done", typ: Done, accept: true},
+		{name: "first", typ: 0x10, accept: true},
+		{name: "second", typ: 0x20, accept: true},
+		{name: "other", typ: 0x11},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := Message{Header: Header{Length: uint32(nlmsgHeaderLen), Type: tt.typ}}
+			b, err := m.MarshalBinary()
+			if err != nil {
+				t.Fatalf("failed to marshal: %v", err)
+			}
+
+			n, err := vm.Run(b)
+			if err != nil {
+				t.Fatalf("failed to run BPF VM: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.accept, n > 0); diff != "" {
+				t.Fatalf("unexpected accept (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTypeFilterBPFTooManyTypes(t *testing.T) {
+	types := make([]HeaderType, 256)
+	if _, ok := typeFilterBPF(types); ok {
+		t.Fatal("expected assembly to fail with too many types")
+	}
+}
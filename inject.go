@@ -0,0 +1,141 @@
+package netlink
+
+import (
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// InjectReceive queues msgs to be returned by the next call to one of the
+// Receive methods or delivered by Messages, Serve, or ServeErrors, as if they
+// had been received from netlink in a single datagram. InjectReceive allows
+// tests and higher level packages to splice synthetic events, such as a
+// notification that state must be refreshed, into the stream of messages
+// which consumers already read.
+//
+// Injected messages are returned exactly as given, before any further messages
+// are received from netlink. They are never returned as the replies to a
+// request made by Execute or Dump, and are not passed through filters such as
+// SetTypeFilter, counted in Stats, or reported to an Observer.
+//
+// A receive operation which is waiting for messages is interrupted to return
+// the injected messages, unless it is in the midst of receiving a multi-part
+// message, or its Socket does not support deadlines, such as a Socket passed
+// to NewConn. Interrupting a receive operation does not affect the deadline
+// set by SetDeadline or SetReadDeadline.
+func (c *Conn) InjectReceive(msgs []Message) error {
+	if atomic.LoadUint32(&c.closed) != 0 {
+		return newOpError("inject", os.ErrClosed)
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	c.inject.push(append([]Message(nil), msgs...), c.interruptRead)
+	return nil
+}
+
+// injectState contains the messages queued by Conn.InjectReceive.
+type injectState struct {
+	mu    sync.Mutex
+	queue [][]Message
+	// waiters are the receive operations which can be interrupted when
+	// messages are queued.
+	waiters map[*injectWaiter]struct{}
+}
+
+// An injectWaiter is a receive operation waiting for messages.
+type injectWaiter struct {
+	// cancel interrupts a receive operation with a cancelable context, or is
+	// nil if the receive operation must be interrupted by its read deadline.
+	cancel context.CancelFunc
+	woken  bool
+}
+
+// push queues msgs and interrupts any waiting receive operations, using
+// interrupt for those which cannot be canceled.
+func (s *injectState) push(msgs []Message, interrupt func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.queue = append(s.queue, msgs)
+
+	var interrupted bool
+	for w := range s.waiters {
+		w.woken = true
+		switch {
+		case w.cancel != nil:
+			w.cancel()
+		case !interrupted:
+			// The read deadline is shared by all receive operations.
+			interrupt()
+			interrupted = true
+		}
+	}
+}
+
+// take dequeues the next batch of injected messages, if any, and records
+// the corresponding metadata in rs.
+func (s *injectState) take(rs *receiveState) ([]Message, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.queue) == 0 {
+		return nil, false
+	}
+
+	msgs := s.queue[0]
+	s.queue[0] = nil
+	s.queue = s.queue[1:]
+
+	if rs.lenient {
+		rs.errs = append(rs.errs, make([]*DecodeError, len(msgs))...)
+	}
+	if rs.info {
+		for range msgs {
+			rs.infos = append(rs.infos, MessageInfo{NSID: NetNSIDNotAssigned})
+		}
+	}
+
+	return msgs, true
+}
+
+// wait registers a receive operation which may be interrupted when messages
+// are queued. It returns a context derived from ctx which is canceled if ctx
+// can be canceled, and a function which must be called once the receive
+// operation is complete to report whether it was interrupted. If messages were
+// queued since the caller last called take, wait reports false and the receive
+// operation must not begin.
+func (s *injectState) wait(ctx context.Context) (context.Context, func() bool, bool) {
+	w := &injectWaiter{}
+	if ctx.Done() != nil {
+		ctx, w.cancel = context.WithCancel(ctx)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.queue) > 0 {
+		if w.cancel != nil {
+			w.cancel()
+		}
+		return ctx, nil, false
+	}
+
+	if s.waiters == nil {
+		s.waiters = make(map[*injectWaiter]struct{})
+	}
+	s.waiters[w] = struct{}{}
+
+	return ctx, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		delete(s.waiters, w)
+		if w.cancel != nil {
+			w.cancel()
+		}
+		return w.woken
+	}, true
+}
@@ -0,0 +1,146 @@
+package netlink_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nltest"
+)
+
+func TestConnInjectReceive(t *testing.T) {
+	var (
+		refresh = netlink.Message{Header: netlink.Header{Type: 0x20}, Data: []byte{0xff}}
+		event   = netlink.Message{Header: netlink.Header{Type: 0x10}, Data: []byte{0x01}}
+	)
+
+	c := nltest.Dial(func(req []netlink.Message) ([]netlink.Message, error) {
+		if len(req) == 0 {
+			// Receive with no request, return an event.
+			return []netlink.Message{event}, nil
+		}
+
+		return []netlink.Message{{Header: reply(req[0])}}, nil
+	})
+	defer c.Close()
+
+	if err := c.InjectReceive([]netlink.Message{refresh, refresh}); err != nil {
+		t.Fatalf("failed to inject: %v", err)
+	}
+
+	// Injected messages are never returned as replies to a request.
+	req := netlink.Message{Header: netlink.Header{Type: 0x10, Flags: netlink.Request}}
+	if _, err := c.Execute(req); err != nil {
+		t.Fatalf("failed to execute: %v", err)
+	}
+
+	for i, want := range [][]netlink.Message{
+		{refresh, refresh},
+		{event},
+	} {
+		got, err := c.Receive()
+		if err != nil {
+			t.Fatalf("failed to receive %d: %v", i, err)
+		}
+
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Fatalf("unexpected messages %d (-want +got):\n%s", i, diff)
+		}
+	}
+}
+
+func TestConnInjectReceiveWithInfo(t *testing.T) {
+	c := nltest.Dial(func(_ []netlink.Message) ([]netlink.Message, error) {
+		panic("should not be called")
+	})
+	defer c.Close()
+
+	want := []netlink.Message{{Data: []byte{0x01}}, {Data: []byte{0x02}}}
+	if err := c.InjectReceive(want); err != nil {
+		t.Fatalf("failed to inject: %v", err)
+	}
+
+	msgs, infos, err := c.ReceiveWithInfo()
+	if err != nil {
+		t.Fatalf("failed to receive: %v", err)
+	}
+
+	if diff := cmp.Diff(want, msgs); diff != "" {
+		t.Fatalf("unexpected messages (-want +got):\n%s", diff)
+	}
+
+	wantInfos := []netlink.MessageInfo{
+		{NSID: netlink.NetNSIDNotAssigned},
+		{NSID: netlink.NetNSIDNotAssigned},
+	}
+	if diff := cmp.Diff(wantInfos, infos); diff != "" {
+		t.Fatalf("unexpected message info (-want +got):\n%s", diff)
+	}
+}
+
+func TestConnInjectReceiveMessages(t *testing.T) {
+	// Block the Messages goroutine once the injected message is delivered.
+	done := make(chan struct{})
+	c := nltest.Dial(func(_ []netlink.Message) ([]netlink.Message, error) {
+		<-done
+		return nil, io.EOF
+	})
+	defer c.Close()
+	defer close(done)
+
+	want := netlink.Message{Header: netlink.Header{Type: 0x20}}
+	if err := c.InjectReceive([]netlink.Message{want}); err != nil {
+		t.Fatalf("failed to inject: %v", err)
+	}
+
+	msgC, _ := c.Messages()
+	if diff := cmp.Diff(want, <-msgC); diff != "" {
+		t.Fatalf("unexpected message (-want +got):\n%s", diff)
+	}
+}
+
+func TestConnInjectReceiveServe(t *testing.T) {
+	// Block the Serve receive loop once the injected message is delivered.
+	done := make(chan struct{})
+	c := nltest.Dial(func(_ []netlink.Message) ([]netlink.Message, error) {
+		<-done
+		return nil, io.EOF
+	})
+	defer c.Close()
+	defer close(done)
+
+	want := netlink.Message{Header: netlink.Header{Type: 0x20}}
+	if err := c.InjectReceive([]netlink.Message{want}); err != nil {
+		t.Fatalf("failed to inject: %v", err)
+	}
+
+	errStop := errors.New("stop")
+	var got []netlink.Message
+	err := c.Serve(context.Background(), func(msgs []netlink.Message) error {
+		got = msgs
+		return errStop
+	})
+	if !errors.Is(err, errStop) {
+		t.Fatalf("unexpected serve error: %v", err)
+	}
+
+	if diff := cmp.Diff([]netlink.Message{want}, got); diff != "" {
+		t.Fatalf("unexpected messages (-want +got):\n%s", diff)
+	}
+}
+
+func TestConnInjectReceiveClosed(t *testing.T) {
+	c := nltest.Dial(nil)
+	if err := c.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	err := c.InjectReceive([]netlink.Message{{}})
+	if !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("expected closed error, but got: %v", err)
+	}
+}
@@ -8,7 +8,6 @@ import (
 	"fmt"
 	"net"
 	"os"
-	"os/exec"
 	"sync"
 	"testing"
 	"time"
@@ -17,43 +16,37 @@ import (
 	"github.com/jsimonetti/rtnetlink"
 	"github.com/mdlayher/ethtool"
 	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nltestenv"
 	"golang.org/x/net/nettest"
 	"golang.org/x/sys/unix"
 )
 
 func TestIntegrationConnMulticast(t *testing.T) {
-	skipUnprivileged(t)
+	ns := nltestenv.NewNetNS(t)
 
-	c, done := rtnlDial(t, 0)
+	c, done := rtnlDial(t, ns.FD())
 	defer done()
 
-	// Create an interface to trigger a notification, and remove it at the end
-	// of the test.
-	const ifName = "nltest0"
-	defer shell(t, "ip", "link", "del", ifName)
+	// Create interfaces to trigger a notification. The notification may
+	// describe either end of the veth pair. The interfaces are removed along
+	// with the namespace.
+	const (
+		ifName = "nltest0"
+		peer   = "nltest1"
+	)
 
 	ifi := rtnlReceive(t, c, func() {
-		shell(t, "ip", "tuntap", "add", ifName, "mode", "tun")
+		ns.AddVeth(ifName, peer)
 	})
 
-	if diff := cmp.Diff(ifName, ifi); diff != "" {
-		t.Fatalf("unexpected interface name (-want +got):\n%s", diff)
+	if ifi != ifName && ifi != peer {
+		t.Fatalf("unexpected interface name: %q, want %q or %q", ifi, ifName, peer)
 	}
 }
 
 func TestIntegrationConnNetNSExplicit(t *testing.T) {
-	skipUnprivileged(t)
-
 	// Create a network namespace for use within this test.
-	const ns = "nltest0"
-	shell(t, "ip", "netns", "add", ns)
-	defer shell(t, "ip", "netns", "del", ns)
-
-	f, err := os.Open("/var/run/netns/" + ns)
-	if err != nil {
-		t.Fatalf("failed to open namespace file: %v", err)
-	}
-	defer f.Close()
+	ns := nltestenv.NewNetNS(t)
 
 	// Create a connection in each the host namespace and the new network
 	// namespace. We will use these to validate that a namespace was entered
@@ -62,7 +55,7 @@ func TestIntegrationConnNetNSExplicit(t *testing.T) {
 	hostC, hostDone := rtnlDial(t, 0)
 	defer hostDone()
 
-	nsC, nsDone := rtnlDial(t, int(f.Fd()))
+	nsC, nsDone := rtnlDial(t, ns.FD())
 	defer nsDone()
 
 	var wg sync.WaitGroup
@@ -85,13 +78,15 @@ func TestIntegrationConnNetNSExplicit(t *testing.T) {
 		panicf("failed to receive in host namespace: %v", err)
 	}()
 
-	// Create a temporary interface within the new network namespace.
-	const ifName = "nltestns0"
-	defer shell(t, "ip", "netns", "exec", ns, "ip", "link", "del", ifName)
+	// Create temporary interfaces within the new network namespace to trigger
+	// a notification, which may describe either end of the veth pair.
+	const (
+		ifName = "nltestns0"
+		peer   = "nltestns1"
+	)
 
 	ifi := rtnlReceive(t, nsC, func() {
-		// Trigger a notification in the new namespace.
-		shell(t, "ip", "netns", "exec", ns, "ip", "tuntap", "add", ifName, "mode", "tun")
+		ns.AddVeth(ifName, peer)
 	})
 
 	// And finally interrupt the host connection so it can exit its
@@ -100,8 +95,8 @@ func TestIntegrationConnNetNSExplicit(t *testing.T) {
 		t.Fatalf("failed to interrupt host connection: %v", err)
 	}
 
-	if diff := cmp.Diff(ifName, ifi); diff != "" {
-		t.Fatalf("unexpected interface name (-want +got):\n%s", diff)
+	if ifi != ifName && ifi != peer {
+		t.Fatalf("unexpected interface name: %q, want %q or %q", ifi, ifName, peer)
 	}
 }
 
@@ -154,7 +149,7 @@ func TestIntegrationRTNetlinkStrictCheckExtendedAcknowledge(t *testing.T) {
 }
 
 func TestIntegrationRTNetlinkRouteManipulation(t *testing.T) {
-	skipUnprivileged(t)
+	nltestenv.SkipUnprivileged(t)
 
 	c, err := netlink.Dial(unix.NETLINK_ROUTE, nil)
 	if err != nil {
@@ -398,32 +393,6 @@ func rtnlReceive(t *testing.T, c *netlink.Conn, do func()) string {
 	return m.Attributes.Name
 }
 
-func skipUnprivileged(t *testing.T) {
-	const ifName = "nlprobe0"
-	shell(t, "ip", "tuntap", "add", ifName, "mode", "tun")
-	shell(t, "ip", "link", "del", ifName)
-}
-
-func shell(t *testing.T, name string, arg ...string) {
-	t.Helper()
-
-	t.Logf("$ %s %v", name, arg)
-
-	cmd := exec.Command(name, arg...)
-	if err := cmd.Start(); err != nil {
-		t.Fatalf("failed to start command %q: %v", name, err)
-	}
-
-	if err := cmd.Wait(); err != nil {
-		// Shell operations in these tests require elevated privileges.
-		if cmd.ProcessState.ExitCode() == int(unix.EPERM) {
-			t.Skipf("skipping, permission denied: %v", err)
-		}
-
-		t.Fatalf("failed to wait for command %q: %v", name, err)
-	}
-}
-
 func panicf(format string, a ...interface{}) {
 	panic(fmt.Sprintf(format, a...))
 }
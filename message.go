@@ -3,6 +3,9 @@ package netlink
 import (
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"syscall"
 	"unsafe"
 
 	"github.com/mdlayher/netlink/nlenc"
@@ -94,6 +97,22 @@ const (
 	AcknowledgeTLVs HeaderFlags = 0x200
 )
 
+// Common combinations of HeaderFlags for requests. See ValidateRequestFlags to
+// check flags which are composed manually.
+const (
+	// FlagsCreateRequest requests that netlink create an object, failing if
+	// it already exists, and acknowledge the request.
+	FlagsCreateRequest = Request | Acknowledge | Create | Excl
+
+	// FlagsReplaceRequest requests that netlink create an object or replace
+	// it if it already exists, and acknowledge the request.
+	FlagsReplaceRequest = Request | Acknowledge | Create | Replace
+
+	// FlagsDumpRequest requests that netlink return a complete list of all
+	// entries.
+	FlagsDumpRequest = Request | Dump
+)
+
 // String returns the string representation of a HeaderFlags.
 func (f HeaderFlags) String() string {
 	names := []string{
@@ -135,6 +154,30 @@ func (f HeaderFlags) String() string {
 	return s
 }
 
+// ValidateRequestFlags checks that f is a sensible set of HeaderFlags for a
+// request sent to netlink, returning an error describing the problem if not.
+//
+// The Request flag must be set, and flags which are only valid in replies
+// from netlink must not be set. Because the retrieval and creation modifier
+// flags share values, such as Create and Atomic, ValidateRequestFlags can
+// only reject combinations which are contradictory under either
+// interpretation, such as Append with Replace, as Append has no meaning in a
+// retrieval request and the kernel ignores Append when replacing.
+func ValidateRequestFlags(f HeaderFlags) error {
+	const replyOnly = Multi | DumpInterrupted | DumpFiltered
+
+	switch {
+	case f&Request == 0:
+		return fmt.Errorf("netlink: invalid request flags %s: request flag is not set", f)
+	case f&replyOnly != 0:
+		return fmt.Errorf("netlink: invalid request flags %s: reply-only flags %s are set", f, f&replyOnly)
+	case f&Append != 0 && f&Replace != 0:
+		return fmt.Errorf("netlink: invalid request flags %s: append and replace are mutually exclusive", f)
+	}
+
+	return nil
+}
+
 // HeaderType specifies the type of a Header.
 type HeaderType uint16
 
@@ -169,9 +212,10 @@ func (t HeaderType) String() string {
 	}
 }
 
-// NB: the memory layout of Header and Linux's syscall.NlMsgHdr must be
-// exactly the same.  Cannot reorder, change data type, add, or remove fields.
-// Named types of the same size (e.g. HeaderFlags is a uint16) are okay.
+// NB: the memory layout of Header and the kernel's struct nlmsghdr must be
+// exactly the same, as this is verified on init.  Cannot reorder, change data
+// type, add, or remove fields. Named types of the same size (e.g. HeaderFlags
+// is a uint16) are okay.
 
 // A Header is a netlink header.  A Header is sent and received with each
 // Message to indicate metadata regarding a Message.
@@ -192,6 +236,44 @@ type Header struct {
 	PID uint32
 }
 
+// String returns a human-readable representation of a Header, such as:
+//
+//	length=20 type=16 flags=request|acknowledge seq=1 pid=10
+//
+// Netlink control message types such as Error are printed by name.
+func (h Header) String() string {
+	return fmt.Sprintf("length=%d type=%s flags=%s seq=%d pid=%d",
+		h.Length, typeString(h.Type), h.Flags, h.Sequence, h.PID)
+}
+
+// typeString returns the name of a netlink control message type, or the
+// number of any other type.
+func typeString(t HeaderType) string {
+	if t < minType {
+		return t.String()
+	}
+
+	return strconv.Itoa(int(t))
+}
+
+// HeaderLen is the length in bytes of a Header. The memory layout of Header is
+// identical to the kernel's struct nlmsghdr, so a Header can be used directly
+// to read and write netlink messages.
+const HeaderLen = 16
+
+func init() {
+	// Messages are framed by casting their bytes directly to a Header, which
+	// would silently corrupt data if the layouts ever diverged.
+	var h Header
+	if unsafe.Sizeof(h) != HeaderLen ||
+		unsafe.Offsetof(h.Type) != 4 ||
+		unsafe.Offsetof(h.Flags) != 6 ||
+		unsafe.Offsetof(h.Sequence) != 8 ||
+		unsafe.Offsetof(h.PID) != 12 {
+		panic("netlink: memory layout of Header does not match struct nlmsghdr")
+	}
+}
+
 // A Message is a netlink message.  It contains a Header and an arbitrary
 // byte payload, which may be decoded using information from the Header.
 //
@@ -202,25 +284,116 @@ type Message struct {
 	Data   []byte
 }
 
+// messagePreview is the maximum number of bytes of Data included by
+// Message.String.
+const messagePreview = 16
+
+// String returns a human-readable representation of a Message, consisting of
+// its Header and a hex and ASCII preview of the start of its Data, such as:
+//
+//	length=20 type=16 flags=request seq=1 pid=10 data=[68 69 00 00] "hi.."
+func (m Message) String() string {
+	var sb strings.Builder
+	sb.WriteString(m.Header.String())
+
+	b := m.Data
+	if len(b) > messagePreview {
+		b = b[:messagePreview]
+	}
+
+	fmt.Fprintf(&sb, " data=[% x]", b)
+
+	if len(b) == 0 {
+		return sb.String()
+	}
+
+	// Replace non-printable bytes so the preview stays on one line.
+	ascii := make([]byte, len(b))
+	for i, c := range b {
+		if c < 0x20 || c > 0x7e {
+			c = '.'
+		}
+		ascii[i] = c
+	}
+	fmt.Fprintf(&sb, " %q", ascii)
+
+	if n := len(m.Data) - len(b); n > 0 {
+		fmt.Fprintf(&sb, " (+%d bytes)", n)
+	}
+
+	return sb.String()
+}
+
 // MarshalBinary marshals a Message into a byte slice.
 func (m Message) MarshalBinary() ([]byte, error) {
+	return m.AppendBinary(nil)
+}
+
+// AppendBinary marshals a Message and appends the result to b, returning the
+// extended buffer. It is equivalent to MarshalBinary, but allows callers (such
+// as higher-level netlink family packages) to build one or more messages in a
+// single buffer without an intermediate copy.
+func (m Message) AppendBinary(b []byte) ([]byte, error) {
 	ml := nlmsgAlign(int(m.Header.Length))
 	if ml < nlmsgHeaderLen || ml != int(m.Header.Length) {
 		return nil, errIncorrectMessageLength
 	}
 
-	b := make([]byte, ml)
+	// Extend b with zeroed bytes so any trailing padding is always zero, even
+	// if b's spare capacity contains stale data.
+	off := len(b)
+	b = append(b, make([]byte, ml)...)
+	mb := b[off:]
 
-	nlenc.PutUint32(b[0:4], m.Header.Length)
-	nlenc.PutUint16(b[4:6], uint16(m.Header.Type))
-	nlenc.PutUint16(b[6:8], uint16(m.Header.Flags))
-	nlenc.PutUint32(b[8:12], m.Header.Sequence)
-	nlenc.PutUint32(b[12:16], m.Header.PID)
-	copy(b[16:], m.Data)
+	nlenc.PutUint32(mb[0:4], m.Header.Length)
+	nlenc.PutUint16(mb[4:6], uint16(m.Header.Type))
+	nlenc.PutUint16(mb[6:8], uint16(m.Header.Flags))
+	nlenc.PutUint32(mb[8:12], m.Header.Sequence)
+	nlenc.PutUint32(mb[12:16], m.Header.PID)
+	copy(mb[16:], m.Data)
 
 	return b, nil
 }
 
+// MarshalBinaryStrict is like MarshalBinary, but first verifies that m is a
+// well-formed request using VerifyRequest.
+func (m Message) MarshalBinaryStrict() ([]byte, error) {
+	if err := m.VerifyRequest(); err != nil {
+		return nil, err
+	}
+
+	return m.MarshalBinary()
+}
+
+// VerifyRequest checks that m is a well-formed request to be sent to netlink,
+// returning an error describing the problem if not. VerifyRequest catches
+// mistakes which the kernel would otherwise reject with a less descriptive
+// error or silently ignore, such as a zero or reserved header type, or header
+// flags which are rejected by ValidateRequestFlags.
+//
+// A zero header length is permitted, because Conn computes the length of
+// messages when they are sent.
+func (m Message) VerifyRequest() error {
+	switch t := m.Header.Type; {
+	case t == 0:
+		return errors.New("netlink: invalid request: header type must be nonzero")
+	case t < minType:
+		// Types below minType are reserved for control messages.
+		return fmt.Errorf("netlink: invalid request: header type %s is reserved for control messages", t)
+	}
+
+	if err := ValidateRequestFlags(m.Header.Flags); err != nil {
+		return err
+	}
+
+	if l := int(m.Header.Length); l != 0 && nlmsgAlign(l) != nlmsgAlign(nlmsgLength(len(m.Data))) {
+		return fmt.Errorf("netlink: invalid request: header length %d does not match message length %d",
+			l, nlmsgLength(len(m.Data)))
+	}
+
+	return nil
+}
+
 // UnmarshalBinary unmarshals the contents of a byte slice into a Message.
 func (m *Message) UnmarshalBinary(b []byte) error {
 	if len(b) < nlmsgHeaderLen {
@@ -245,12 +418,145 @@ func (m *Message) UnmarshalBinary(b []byte) error {
 	return nil
 }
 
+// parseMessages parses one or more Messages from a single datagram b.
+func parseMessages(b []byte) ([]Message, error) {
+	msgs, bad := parseDatagram(b)
+	if bad != nil {
+		return nil, bad.Err
+	}
+
+	return msgs, nil
+}
+
+// parseDatagram parses one or more Messages from a single datagram b. If a
+// message cannot be framed, parseDatagram stops and returns a DecodeError for
+// the remaining bytes along with any Messages parsed before it.
+func parseDatagram(b []byte) ([]Message, *DecodeError) {
+	if TraceEnabled {
+		defer traceSince(TraceParse, len(b), traceStart())
+	}
+
+	var msgs []Message
+	for len(b) >= nlmsgHeaderLen {
+		h := *(*Header)(unsafe.Pointer(&b[:nlmsgHeaderLen][0]))
+
+		// The final message in a datagram need not be padded.
+		l := nlmsgAlign(int(h.Length))
+		if l > len(b) && int(h.Length) <= len(b) {
+			l = len(b)
+		}
+
+		if int(h.Length) < nlmsgHeaderLen || l > len(b) {
+			return msgs, &DecodeError{
+				Header: h,
+				Data:   b[nlmsgHeaderLen:],
+				Err:    syscall.EINVAL,
+			}
+		}
+
+		msgs = append(msgs, Message{
+			Header: h,
+			Data:   b[nlmsgHeaderLen:h.Length],
+		})
+
+		b = b[l:]
+	}
+
+	return msgs, nil
+}
+
 // checkMessage checks a single Message for netlink errors.
 func checkMessage(m Message) error {
 	// NB: All non-nil errors returned from this function *must* be of type
 	// OpError in order to maintain the appropriate contract with callers of
 	// this package.
 
+	a, ok, err := parseAck(m)
+	if err != nil {
+		return newOpError("receive", err)
+	}
+	if !ok || a.Errno == 0 {
+		// Not an error message, or 0 indicating no error.
+		return nil
+	}
+
+	return &OpError{
+		Op: "receive",
+		// Error code is a negative integer, convert it into an OS-specific raw
+		// system call error, but do not wrap with os.NewSyscallError to signify
+		// that this error was produced by a netlink message; not a system call.
+		Err:         newError(a.Errno),
+		Message:     a.Message,
+		Offset:      a.Offset,
+		Policy:      a.Policy,
+		MissingType: a.MissingType,
+		MissingNest: a.MissingNest,
+		Cookie:      a.Cookie,
+	}
+}
+
+// parsePolicy parses the NL_POLICY_TYPE_ATTR_* attributes of an extended
+// acknowledgement policy TLV.
+func parsePolicy(ad *AttributeDecoder) *AttributePolicy {
+	var p AttributePolicy
+	for ad.Next() {
+		switch ad.Type() {
+		case 1: // unix.NL_POLICY_TYPE_ATTR_TYPE
+			p.Type = ad.Uint32()
+		case 2: // unix.NL_POLICY_TYPE_ATTR_MIN_VALUE_S
+			p.MinSigned = ad.Int64()
+		case 3: // unix.NL_POLICY_TYPE_ATTR_MAX_VALUE_S
+			p.MaxSigned = ad.Int64()
+		case 4: // unix.NL_POLICY_TYPE_ATTR_MIN_VALUE_U
+			p.MinUnsigned = ad.Uint64()
+		case 5: // unix.NL_POLICY_TYPE_ATTR_MAX_VALUE_U
+			p.MaxUnsigned = ad.Uint64()
+		case 6: // unix.NL_POLICY_TYPE_ATTR_MIN_LENGTH
+			p.MinLength = ad.Uint32()
+		case 7: // unix.NL_POLICY_TYPE_ATTR_MAX_LENGTH
+			p.MaxLength = ad.Uint32()
+		case 8: // unix.NL_POLICY_TYPE_ATTR_POLICY_IDX
+			p.PolicyIndex = ad.Uint32()
+		case 9: // unix.NL_POLICY_TYPE_ATTR_POLICY_MAXTYPE
+			p.PolicyMaxType = ad.Uint32()
+		case 10: // unix.NL_POLICY_TYPE_ATTR_BITFIELD32_MASK
+			p.Mask = uint64(ad.Uint32())
+		case 12: // unix.NL_POLICY_TYPE_ATTR_MASK
+			p.Mask = ad.Uint64()
+		}
+	}
+
+	return &p
+}
+
+// An ack is a netlink error or acknowledgement message, along with any
+// extended acknowledgement TLVs.
+type ack struct {
+	// Errno is the error number carried by the message as a positive
+	// integer, or 0 for a successful acknowledgement.
+	Errno int
+
+	// Message and Offset are set from the extended acknowledgement TLVs. A
+	// Message accompanying an Errno of 0 is a warning.
+	Message string
+	Offset  int
+
+	// Policy is set from the extended acknowledgement policy TLV, if any.
+	Policy *AttributePolicy
+
+	// Cookie is set from the extended acknowledgement cookie TLV, if any.
+	Cookie []byte
+
+	// MissingType and MissingNest are set from the extended acknowledgement
+	// missing attribute TLVs, if any.
+	MissingType uint16
+	MissingNest int
+}
+
+// parseAck parses m as a netlink error or acknowledgement message. It reports
+// false if m is neither. Malformed extended acknowledgement TLVs are ignored,
+// but an error is returned if an error message is truncated.
+func parseAck(m Message) (ack, bool, error) {
 	// The libnl documentation indicates that type error can
 	// contain error codes:
 	// https://www.infradead.org/~tgr/libnl/doc/core.html#core_errmsg.
@@ -267,40 +573,36 @@ func checkMessage(m Message) error {
 		// of the unit tests hard-coded this but I don't actually know if this
 		// case occurs in the wild.
 		if len(m.Data) == 0 {
-			return nil
+			return ack{}, false, nil
 		}
 
 		// Done|Multi potentially followed by ext ack attributes.
 	default:
 		// Neither, nothing to do.
-		return nil
+		return ack{}, false, nil
 	}
 
 	// Errno occupies 4 bytes.
 	const endErrno = 4
 	if len(m.Data) < endErrno {
-		return newOpError("receive", errShortErrorMessage)
+		return ack{}, false, errShortErrorMessage
 	}
 
-	c := nlenc.Int32(m.Data[:endErrno])
-	if c == 0 {
-		// 0 indicates no error.
-		return nil
-	}
+	a := ack{Errno: -1 * int(nlenc.Int32(m.Data[:endErrno]))}
 
-	oerr := &OpError{
-		Op: "receive",
-		// Error code is a negative integer, convert it into an OS-specific raw
-		// system call error, but do not wrap with os.NewSyscallError to signify
-		// that this error was produced by a netlink message; not a system call.
-		Err: newError(-1 * int(c)),
-	}
+	// short reports a truncated message as an error only if the message
+	// carries an error number.
+	short := func() (ack, bool, error) {
+		if a.Errno == 0 {
+			return a, true, nil
+		}
 
-	// TODO(mdlayher): investigate the Capped flag.
+		return a, true, errShortErrorMessage
+	}
 
 	if m.Header.Flags&AcknowledgeTLVs == 0 {
 		// No extended acknowledgement.
-		return oerr
+		return a, true, nil
 	}
 
 	// Flags indicate an extended acknowledgement. The type/flags combination
@@ -309,17 +611,22 @@ func checkMessage(m Message) error {
 	if hasHeader {
 		// There is an nlmsghdr preceding the TLVs.
 		if len(m.Data) < endErrno+nlmsgHeaderLen {
-			return newOpError("receive", errShortErrorMessage)
+			return short()
 		}
 
-		// The TLVs should be at the offset indicated by the nlmsghdr.length,
-		// plus the offset where the header began. But make sure the calculated
-		// offset is still in-bounds.
-		h := *(*Header)(unsafe.Pointer(&m.Data[endErrno : endErrno+nlmsgHeaderLen][0]))
-		off = endErrno + int(h.Length)
+		if m.Header.Flags&Capped != 0 {
+			// The kernel did not echo the request payload, only its header.
+			off = endErrno + nlmsgHeaderLen
+		} else {
+			// The TLVs should be at the offset indicated by the
+			// nlmsghdr.length, plus the offset where the header began. But
+			// make sure the calculated offset is still in-bounds.
+			h := *(*Header)(unsafe.Pointer(&m.Data[endErrno : endErrno+nlmsgHeaderLen][0]))
+			off = endErrno + int(h.Length)
+		}
 
 		if len(m.Data) < off {
-			return newOpError("receive", errShortErrorMessage)
+			return short()
 		}
 	} else {
 		// There is no nlmsghdr preceding the TLVs, parse them directly.
@@ -328,20 +635,31 @@ func checkMessage(m Message) error {
 
 	ad, err := NewAttributeDecoder(m.Data[off:])
 	if err != nil {
-		// Malformed TLVs, just return the OpError with the info we have.
-		return oerr
+		// Malformed TLVs, just return the ack with the info we have.
+		return a, true, nil
 	}
 
 	for ad.Next() {
 		switch ad.Type() {
 		case 1: // unix.NLMSGERR_ATTR_MSG
-			oerr.Message = ad.String()
+			a.Message = ad.String()
 		case 2: // unix.NLMSGERR_ATTR_OFFS
-			oerr.Offset = int(ad.Uint32())
+			a.Offset = int(ad.Uint32())
+		case 3: // unix.NLMSGERR_ATTR_COOKIE
+			a.Cookie = ad.Bytes()
+		case 4: // unix.NLMSGERR_ATTR_POLICY
+			ad.Nested(func(nad *AttributeDecoder) error {
+				a.Policy = parsePolicy(nad)
+				return nil
+			})
+		case 5: // NLMSGERR_ATTR_MISS_TYPE
+			a.MissingType = uint16(ad.Uint32())
+		case 6: // NLMSGERR_ATTR_MISS_NEST
+			a.MissingNest = int(ad.Uint32())
 		}
 	}
 
-	// Explicitly ignore ad.Err: malformed TLVs, just return the OpError with
-	// the info we have.
-	return oerr
+	// Explicitly ignore ad.Err: malformed TLVs, just return the ack with the
+	// info we have.
+	return a, true, nil
 }
@@ -31,6 +31,13 @@ func TestHeaderMemoryLayoutLinux(t *testing.T) {
 	}
 	nh = sysToHeader(sh)
 
+	if !sysHeaderLayout {
+		t.Fatal("syscall.NlMsghdr layout unexpectedly differs from Header")
+	}
+	if diff := cmp.Diff(sysToHeaderFields(sh), nh); diff != "" {
+		t.Fatalf("unexpected field conversion (-want +got):\n%s", diff)
+	}
+
 	if want, got := sh.Len, nh.Length; want != got {
 		t.Fatalf("unexpected header length:\n- want: %v\n-  got: %v",
 			want, got)
@@ -95,6 +102,128 @@ func Test_checkMessageExtendedAcknowledgementTLVs(t *testing.T) {
 				Offset:  2,
 			},
 		},
+		{
+			name: "error capped",
+			m: Message{
+				Header: Header{
+					Type: Error,
+					// The kernel only echoes the request header when the
+					// acknowledgement is capped.
+					Flags: AcknowledgeTLVs | Capped,
+				},
+				Data: packCappedExtACK(
+					-1,
+					[]Attribute{{
+						Type: 1,
+						Data: nlenc.Bytes("bad request"),
+					}},
+				),
+			},
+			err: &OpError{
+				Op:      "receive",
+				Err:     unix.Errno(1),
+				Message: "bad request",
+			},
+		},
+		{
+			name: "error policy",
+			m: Message{
+				Header: Header{
+					Type:  Error,
+					Flags: AcknowledgeTLVs | Capped,
+				},
+				Data: packCappedExtACK(
+					-int32(unix.ERANGE),
+					[]Attribute{
+						{
+							Type: unix.NLMSGERR_ATTR_MSG,
+							Data: nlenc.Bytes("integer out of range"),
+						},
+						{
+							Type: 4 | Nested, // NLMSGERR_ATTR_POLICY
+							Data: mustMarshalAttributes([]Attribute{
+								{
+									Type: unix.NL_POLICY_TYPE_ATTR_TYPE,
+									Data: nlenc.Uint32Bytes(unix.NL_ATTR_TYPE_U32),
+								},
+								{
+									Type: unix.NL_POLICY_TYPE_ATTR_MIN_VALUE_U,
+									Data: nlenc.Uint64Bytes(1),
+								},
+								{
+									Type: unix.NL_POLICY_TYPE_ATTR_MAX_VALUE_U,
+									Data: nlenc.Uint64Bytes(10),
+								},
+							}),
+						},
+					},
+				),
+			},
+			err: &OpError{
+				Op:      "receive",
+				Err:     unix.ERANGE,
+				Message: "integer out of range",
+				Policy: &AttributePolicy{
+					Type:        unix.NL_ATTR_TYPE_U32,
+					MinUnsigned: 1,
+					MaxUnsigned: 10,
+				},
+			},
+		},
+		{
+			name: "error missing attribute",
+			m: Message{
+				Header: Header{
+					Type:  Error,
+					Flags: AcknowledgeTLVs | Capped,
+				},
+				Data: packCappedExtACK(
+					-int32(unix.EINVAL),
+					[]Attribute{
+						{
+							Type: unix.NLMSGERR_ATTR_MSG,
+							Data: nlenc.Bytes("missing attribute"),
+						},
+						{
+							Type: 5, // NLMSGERR_ATTR_MISS_TYPE
+							Data: nlenc.Uint32Bytes(3),
+						},
+						{
+							Type: 6, // NLMSGERR_ATTR_MISS_NEST
+							Data: nlenc.Uint32Bytes(20),
+						},
+					},
+				),
+			},
+			err: &OpError{
+				Op:          "receive",
+				Err:         unix.EINVAL,
+				Message:     "missing attribute",
+				MissingType: 3,
+				MissingNest: 20,
+			},
+		},
+		{
+			name: "error cookie",
+			m: Message{
+				Header: Header{
+					Type:  Error,
+					Flags: AcknowledgeTLVs | Capped,
+				},
+				Data: packCappedExtACK(
+					-int32(unix.EEXIST),
+					[]Attribute{{
+						Type: unix.NLMSGERR_ATTR_COOKIE,
+						Data: []byte{0xde, 0xad, 0xbe, 0xef},
+					}},
+				),
+			},
+			err: &OpError{
+				Op:     "receive",
+				Err:    unix.EEXIST,
+				Cookie: []byte{0xde, 0xad, 0xbe, 0xef},
+			},
+		},
 		{
 			name: "done multi",
 			m: Message{
@@ -159,3 +288,38 @@ func packExtACK(errno int32, m *Message, tlvs []Attribute) []byte {
 
 	return append(b, ab...)
 }
+
+// packCappedExtACK packs a capped extended acknowledgement response, in which
+// the request header claims a length longer than the echoed bytes.
+func packCappedExtACK(errno int32, tlvs []Attribute) []byte {
+	h := make([]byte, nlmsgHeaderLen)
+	nlenc.PutUint32(h[0:4], 1024)
+
+	ab, err := MarshalAttributes(tlvs)
+	if err != nil {
+		panicf("failed to marshal attributes: %v", err)
+	}
+
+	b := append(nlenc.Int32Bytes(errno), h...)
+	return append(b, ab...)
+}
+
+func Test_parseAckWarning(t *testing.T) {
+	a, ok, err := parseAck(Message{
+		Header: Header{
+			Type:  Error,
+			Flags: AcknowledgeTLVs | Capped,
+		},
+		Data: packCappedExtACK(0, []Attribute{{
+			Type: 1,
+			Data: nlenc.Bytes("deprecated"),
+		}}),
+	})
+	if err != nil || !ok {
+		t.Fatalf("failed to parse ack: %v, %v", ok, err)
+	}
+
+	if diff := cmp.Diff(ack{Message: "deprecated"}, a); diff != "" {
+		t.Fatalf("unexpected ack (-want +got):\n%s", diff)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"reflect"
+	"syscall"
 	"testing"
 
 	"github.com/josharian/native"
@@ -91,6 +92,149 @@ func TestHeaderFlagsString(t *testing.T) {
 	}
 }
 
+func TestValidateRequestFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		f    HeaderFlags
+		ok   bool
+	}{
+		{
+			name: "create",
+			f:    FlagsCreateRequest,
+			ok:   true,
+		},
+		{
+			name: "replace",
+			f:    FlagsReplaceRequest,
+			ok:   true,
+		},
+		{
+			name: "dump",
+			f:    FlagsDumpRequest,
+			ok:   true,
+		},
+		{
+			name: "append",
+			f:    Request | Acknowledge | Create | Append,
+			ok:   true,
+		},
+		{
+			// Atomic shares a value with Create, and Dump is Replace|Excl.
+			name: "dump atomic",
+			f:    Request | Dump | Atomic,
+			ok:   true,
+		},
+		{
+			name: "no request",
+			f:    Acknowledge | Create | Excl,
+		},
+		{
+			name: "multi",
+			f:    FlagsDumpRequest | Multi,
+		},
+		{
+			name: "dump interrupted",
+			f:    FlagsDumpRequest | DumpInterrupted,
+		},
+		{
+			name: "append replace",
+			f:    Request | Create | Replace | Append,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRequestFlags(tt.f)
+			if tt.ok && err != nil {
+				t.Fatalf("failed to validate flags %s: %v", tt.f, err)
+			}
+			if !tt.ok && err == nil {
+				t.Fatalf("expected an error for flags %s, but none occurred", tt.f)
+			}
+		})
+	}
+}
+
+func TestMessageVerifyRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		m    Message
+		ok   bool
+	}{
+		{
+			name: "OK",
+			m: Message{
+				Header: Header{Type: 0x10, Flags: Request | Acknowledge},
+				Data:   []byte{0xff},
+			},
+			ok: true,
+		},
+		{
+			name: "OK length",
+			m: Message{
+				Header: Header{Length: 20, Type: 0x10, Flags: Request},
+				Data:   []byte{0xff},
+			},
+			ok: true,
+		},
+		{
+			name: "zero type",
+			m:    Message{Header: Header{Flags: Request}},
+		},
+		{
+			name: "control type",
+			m:    Message{Header: Header{Type: Done, Flags: Request}},
+		},
+		{
+			name: "no request",
+			m:    Message{Header: Header{Type: 0x10, Flags: Dump}},
+		},
+		{
+			name: "bad length",
+			m: Message{
+				Header: Header{Length: 16, Type: 0x10, Flags: Request},
+				Data:   []byte{0xff},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.m.VerifyRequest()
+			if tt.ok && err != nil {
+				t.Fatalf("failed to verify request: %v", err)
+			}
+			if !tt.ok && err == nil {
+				t.Fatal("expected an error, but none occurred")
+			}
+			if err != nil || tt.m.Header.Length == 0 {
+				return
+			}
+
+			if _, err := tt.m.MarshalBinaryStrict(); err != nil {
+				t.Fatalf("failed to marshal request: %v", err)
+			}
+		})
+	}
+}
+
+func TestHeaderLen(t *testing.T) {
+	if want, got := HeaderLen, nlmsgHeaderLen; want != got {
+		t.Fatalf("unexpected aligned header length:\n- want: %v\n-  got: %v",
+			want, got)
+	}
+
+	b, err := (Message{Header: Header{Length: HeaderLen}}).MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to marshal message: %v", err)
+	}
+
+	if want, got := HeaderLen, len(b); want != got {
+		t.Fatalf("unexpected marshaled header length:\n- want: %v\n-  got: %v",
+			want, got)
+	}
+}
+
 func TestHeaderTypeString(t *testing.T) {
 	tests := []struct {
 		t HeaderType
@@ -128,6 +272,55 @@ func TestHeaderTypeString(t *testing.T) {
 	}
 }
 
+func TestMessageString(t *testing.T) {
+	tests := []struct {
+		name string
+		m    Message
+		s    string
+	}{
+		{
+			name: "empty",
+			m: Message{
+				Header: Header{Length: 16, Type: Done, Flags: Multi, Sequence: 1, PID: 10},
+			},
+			s: "length=16 type=done flags=multi seq=1 pid=10 data=[]",
+		},
+		{
+			name: "short",
+			m: Message{
+				Header: Header{Length: 20, Type: 0x10, Flags: Request | Acknowledge, Sequence: 1, PID: 10},
+				Data:   []byte{'h', 'i', 0x00, 0xff},
+			},
+			s: `length=20 type=16 flags=request|acknowledge seq=1 pid=10 data=[68 69 00 ff] "hi.."`,
+		},
+		{
+			name: "long",
+			m: Message{
+				Header: Header{Length: 36, Type: 0x10},
+				Data:   []byte("netlink messages"),
+			},
+			s: `length=36 type=16 flags=0 seq=0 pid=0 data=[6e 65 74 6c 69 6e 6b 20 6d 65 73 73 61 67 65 73] "netlink messages"`,
+		},
+		{
+			name: "truncated",
+			m: Message{
+				Header: Header{Length: 40, Type: 0x10},
+				Data:   []byte("netlink messages, truncated"),
+			},
+			s: `length=40 type=16 flags=0 seq=0 pid=0 data=[6e 65 74 6c 69 6e 6b 20 6d 65 73 73 61 67 65 73] "netlink messages" (+11 bytes)`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if want, got := tt.s, tt.m.String(); want != got {
+				t.Fatalf("unexpected message string:\n- want: %q\n-  got: %q",
+					want, got)
+			}
+		})
+	}
+}
+
 func TestMessageMarshal(t *testing.T) {
 	skipBigEndian(t)
 
@@ -231,6 +424,22 @@ func TestMessageMarshal(t *testing.T) {
 			if want, got := tt.b, b; !bytes.Equal(want, got) {
 				t.Fatalf("unexpected Message bytes:\n- want: [%# x]\n-  got: [%# x]", want, got)
 			}
+
+			// AppendBinary must produce identical output after any existing
+			// data, and must not leak stale bytes from spare capacity into
+			// the padding.
+			prefix := []byte{0xff, 0xff}
+			buf := append(make([]byte, 0, 64), prefix...)
+			_ = append(buf, bytes.Repeat([]byte{0xff}, 62)...)
+
+			ab, err := tt.m.AppendBinary(buf)
+			if err != nil {
+				t.Fatalf("failed to append binary: %v", err)
+			}
+
+			if want, got := append(prefix, tt.b...), ab; !bytes.Equal(want, got) {
+				t.Fatalf("unexpected appended Message bytes:\n- want: [%# x]\n-  got: [%# x]", want, got)
+			}
 		})
 	}
 }
@@ -480,3 +689,51 @@ func skipBigEndian(t *testing.T) {
 		t.Skip("skipping test on big-endian system")
 	}
 }
+
+func Test_parseDatagram(t *testing.T) {
+	good := Message{
+		Header: Header{Length: 20, Type: 0x10, Sequence: 1},
+		Data:   []byte{0x01, 0x02, 0x03, 0x04},
+	}
+
+	gb, err := good.MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+
+	// A message whose length extends past the end of the datagram.
+	bad := Message{
+		Header: Header{Length: 20, Type: 0x11, Sequence: 2},
+		Data:   []byte{0xff, 0xff, 0xff, 0xff},
+	}
+
+	bb, err := bad.MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	bad.Header.Length = 64
+	native.Endian.PutUint32(bb[0:4], bad.Header.Length)
+
+	msgs, derr := parseDatagram(append(gb, bb...))
+	if derr == nil {
+		t.Fatal("expected a decode error, but none occurred")
+	}
+
+	if want, got := []Message{good}, msgs; !reflect.DeepEqual(want, got) {
+		t.Fatalf("unexpected messages:\n- want: %v\n-  got: %v", want, got)
+	}
+
+	want := &DecodeError{
+		Header: bad.Header,
+		Data:   bad.Data,
+		Err:    syscall.EINVAL,
+	}
+
+	if got := derr; !reflect.DeepEqual(want, got) {
+		t.Fatalf("unexpected decode error:\n- want: %+v\n-  got: %+v", want, got)
+	}
+
+	if _, err := parseMessages(append(gb, bb...)); !errors.Is(err, syscall.EINVAL) {
+		t.Fatalf("expected EINVAL, but got: %v", err)
+	}
+}
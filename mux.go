@@ -0,0 +1,246 @@
+package netlink
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// errMuxClosed is returned by Mux.Execute once the Mux is closed.
+var errMuxClosed = errors.New("use of closed netlink mux")
+
+// A Mux multiplexes concurrent requests over a single Conn. Unlike
+// Conn.Execute, which holds the Conn's lock until all of the replies to a
+// request are received, a Mux runs a single receive loop and routes each
+// reply to the caller waiting on its sequence number, so many goroutines can
+// execute requests on one socket without waiting on each other.
+//
+// Because the kernel only processes a single dump request on a socket at a
+// time, requests which set the Dump flag are serialized with one another,
+// but not with other requests. The Dump flag shares its value with
+// Replace|Excl, so a request which also sets Create or Append is treated as a
+// creation request rather than as a dump. As a result, a dump request which
+// sets the obsolete Atomic flag, which shares its value with Create, is not
+// serialized.
+//
+// If messages are lost because the socket's receive buffer overflowed, such
+// as when multicast notifications arrive faster than they are discarded,
+// the pending calls to Execute return an error which wraps ENOBUFS, as their
+// replies may have been lost. Later calls to Execute are unaffected.
+//
+// While a Mux is in use, its Conn must not be used to send or receive
+// messages. Messages which do not match a pending request, such as multicast
+// group notifications, are discarded.
+type Mux struct {
+	c    *Conn
+	stop context.CancelFunc
+	done chan struct{}
+
+	// dumpMu serializes dump requests.
+	dumpMu sync.Mutex
+
+	// mu protects the fields below.
+	mu      sync.Mutex
+	pending map[uint32]*muxCall
+	err     error
+}
+
+// A muxCall is a request waiting on its replies from a Mux.
+type muxCall struct {
+	flags HeaderFlags
+	msgs  []Message
+	err   error
+	done  chan struct{}
+}
+
+// NewMux creates a Mux which takes ownership of c and starts its receive
+// loop. The receive loop stops when Close is called.
+func NewMux(c *Conn) *Mux {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	m := &Mux{
+		c:       c,
+		stop:    cancel,
+		done:    make(chan struct{}),
+		pending: make(map[uint32]*muxCall),
+	}
+
+	go m.receive(ctx)
+	return m
+}
+
+// Close stops the Mux's receive loop and closes its Conn. Any pending calls
+// to Execute return an error.
+func (m *Mux) Close() error {
+	m.stop()
+	err := m.c.Close()
+	<-m.done
+
+	return err
+}
+
+// Execute sends a single Message to netlink and waits for its replies,
+// returning them in the same way as Conn.Execute. Execute is safe for
+// concurrent use.
+//
+// The request must set one of the Acknowledge, Echo, or Dump flags, or the
+// kernel may not send any reply and Execute will block until ctx is canceled.
+func (m *Mux) Execute(ctx context.Context, req Message) ([]Message, error) {
+	if isDumpRequest(req.Header.Flags) {
+		m.dumpMu.Lock()
+		defer m.dumpMu.Unlock()
+	}
+
+	// Assign the sequence number up front so the call is registered before
+	// any replies can arrive. Zero is skipped as it cannot be routed.
+	seq := m.c.nextSequence()
+	for seq == 0 {
+		seq = m.c.nextSequence()
+	}
+	req.Header.Sequence = seq
+
+	call := &muxCall{
+		flags: req.Header.Flags,
+		done:  make(chan struct{}),
+	}
+
+	m.mu.Lock()
+	if m.err != nil {
+		err := m.err
+		m.mu.Unlock()
+		return nil, err
+	}
+	m.pending[seq] = call
+	m.mu.Unlock()
+
+	sent, err := m.c.SendContext(ctx, req)
+	if err != nil {
+		m.remove(seq)
+		return nil, err
+	}
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		m.remove(seq)
+		return nil, newOpError("receive", ctx.Err())
+	}
+
+	if call.err != nil {
+		return nil, call.err
+	}
+	if err := Validate(sent, call.msgs); err != nil {
+		return nil, err
+	}
+
+	return call.msgs, nil
+}
+
+// isDumpRequest reports whether a request with flags is a dump request rather
+// than a creation request which sets Replace|Excl.
+func isDumpRequest(flags HeaderFlags) bool {
+	return flags&Dump == Dump && flags&(Create|Append) == 0
+}
+
+// remove removes the pending call with sequence number seq.
+func (m *Mux) remove(seq uint32) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.pending, seq)
+}
+
+// receive implements the receive loop for a Mux.
+func (m *Mux) receive(ctx context.Context) {
+	defer close(m.done)
+
+	for {
+		m.c.mu.RLock()
+		msgs, _, _, err := m.c.sockReceive(ctx, nil)
+		m.c.mu.RUnlock()
+
+		if err != nil {
+			if isOverrun(err) && ctx.Err() == nil {
+				// The replies to any pending call may have been lost, but
+				// the socket remains usable for later calls.
+				atomic.AddUint64(&m.c.stats.overruns, 1)
+				m.failPending(newOpError("receive", err))
+				continue
+			}
+			if ctx.Err() != nil {
+				err = errMuxClosed
+			}
+
+			m.fail(newOpError("receive", err))
+			return
+		}
+
+		countMessages(&m.c.stats.messagesReceived, &m.c.stats.bytesReceived, msgs)
+
+		m.mu.Lock()
+		for _, msg := range msgs {
+			m.route(msg)
+		}
+		m.mu.Unlock()
+	}
+}
+
+// route delivers msg to its pending call, completing the call if msg is the
+// final reply. It must be called with m.mu held.
+func (m *Mux) route(msg Message) {
+	seq := msg.Header.Sequence
+	call, ok := m.pending[seq]
+	if !ok {
+		m.c.debug(func(d *debugger) {
+			d.debugf(1, "mux: discarding message with unknown sequence: %+v", msg)
+		})
+		return
+	}
+
+	var done bool
+	switch err := checkMessage(msg); {
+	case err != nil:
+		call.msgs, call.err = nil, err
+		done = true
+	case msg.Header.Flags&Multi != 0:
+		// Multi-part messages are complete once the final "multi-part done"
+		// message arrives, which is not returned to the caller.
+		done = msg.Header.Type == Done
+		if !done {
+			call.msgs = append(call.msgs, msg)
+		}
+	default:
+		// Requests which ask for an acknowledgement are complete once it
+		// arrives, and all others once a single reply arrives.
+		call.msgs = append(call.msgs, msg)
+		done = msg.Header.Type == Error || call.flags&Acknowledge == 0
+	}
+
+	if done {
+		delete(m.pending, seq)
+		close(call.done)
+	}
+}
+
+// fail completes all pending calls with err, which is also returned by any
+// later calls to Execute.
+func (m *Mux) fail(err error) {
+	m.mu.Lock()
+	m.err = err
+	m.mu.Unlock()
+
+	m.failPending(err)
+}
+
+// failPending completes all pending calls with err.
+func (m *Mux) failPending(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for seq, call := range m.pending {
+		call.msgs, call.err = nil, err
+		delete(m.pending, seq)
+		close(call.done)
+	}
+}
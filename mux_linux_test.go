@@ -0,0 +1,72 @@
+//go:build linux
+// +build linux
+
+package netlink
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/sys/unix"
+)
+
+func TestMuxExecuteOverrun(t *testing.T) {
+	s := newMuxSocket()
+	m := NewMux(NewConn(s, 1))
+	defer m.Close()
+
+	errC := make(chan error, 1)
+	go func() {
+		_, err := m.Execute(context.Background(), Message{
+			Header: Header{Flags: Request | Acknowledge},
+		})
+		errC <- err
+	}()
+
+	// The pending call fails as its reply may have been lost.
+	<-s.reqC
+	s.errC <- unix.ENOBUFS
+
+	if err := <-errC; !errors.Is(err, unix.ENOBUFS) {
+		t.Fatalf("expected overrun error, but got: %v", err)
+	}
+
+	// The Mux remains usable for later calls.
+	type result struct {
+		msgs []Message
+		err  error
+	}
+
+	resC := make(chan result, 1)
+	go func() {
+		msgs, err := m.Execute(context.Background(), Message{
+			Header: Header{Flags: Request | Acknowledge},
+		})
+		resC <- result{msgs: msgs, err: err}
+	}()
+
+	req := <-s.reqC
+	ack := Message{
+		Header: Header{
+			Type:     Error,
+			Sequence: req.Header.Sequence,
+			PID:      1,
+		},
+		Data: []byte{0x00, 0x00, 0x00, 0x00},
+	}
+	s.resC <- []Message{ack}
+
+	res := <-resC
+	if res.err != nil {
+		t.Fatalf("failed to execute after overrun: %v", res.err)
+	}
+	if diff := cmp.Diff([]Message{ack}, res.msgs); diff != "" {
+		t.Fatalf("unexpected replies (-want +got):\n%s", diff)
+	}
+
+	if diff := cmp.Diff(uint64(1), m.c.Stats().Overruns); diff != "" {
+		t.Fatalf("unexpected overruns (-want +got):\n%s", diff)
+	}
+}
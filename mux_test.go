@@ -0,0 +1,209 @@
+package netlink
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMuxExecute(t *testing.T) {
+	s := newMuxSocket()
+	m := NewMux(NewConn(s, 1))
+	defer m.Close()
+
+	type result struct {
+		msgs []Message
+		err  error
+	}
+
+	execute := func(flags HeaderFlags) <-chan result {
+		resC := make(chan result, 1)
+		go func() {
+			msgs, err := m.Execute(context.Background(), Message{
+				Header: Header{Flags: Request | flags},
+			})
+			resC <- result{msgs: msgs, err: err}
+		}()
+
+		return resC
+	}
+
+	// Issue an acknowledged request and a dump, and reply to the latter first.
+	ackC := execute(Acknowledge)
+	ack := <-s.reqC
+
+	dumpC := execute(Dump)
+	dump := <-s.reqC
+
+	reply := func(req Message, typ HeaderType, flags HeaderFlags, data []byte) Message {
+		return Message{
+			Header: Header{
+				Type:     typ,
+				Flags:    flags,
+				Sequence: req.Header.Sequence,
+				PID:      1,
+			},
+			Data: data,
+		}
+	}
+
+	parts := []Message{
+		reply(dump, 0, Multi, []byte{0x01}),
+		reply(dump, 0, Multi, []byte{0x02}),
+	}
+
+	s.resC <- parts[:1]
+	s.resC <- []Message{
+		// A message for an unknown sequence is discarded.
+		{Header: Header{Sequence: 0xffffffff}},
+		reply(ack, Error, 0, make([]byte, 4)),
+	}
+	s.resC <- []Message{parts[1], reply(dump, Done, Multi, make([]byte, 4))}
+
+	check := func(want []Message, resC <-chan result) {
+		t.Helper()
+
+		res := <-resC
+		if res.err != nil {
+			t.Fatalf("failed to execute: %v", res.err)
+		}
+
+		if diff := cmp.Diff(want, res.msgs); diff != "" {
+			t.Fatalf("unexpected replies (-want +got):\n%s", diff)
+		}
+	}
+
+	check(parts, dumpC)
+	check([]Message{reply(ack, Error, 0, make([]byte, 4))}, ackC)
+
+	// A pending call fails once the Mux is closed.
+	closedC := execute(Acknowledge)
+	<-s.reqC
+
+	if err := m.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	if res := <-closedC; !errors.Is(res.err, errMuxClosed) {
+		t.Fatalf("expected closed error, but got: %v", res.err)
+	}
+}
+
+func TestMuxExecuteError(t *testing.T) {
+	s := newMuxSocket()
+	m := NewMux(NewConn(s, 1))
+	defer m.Close()
+
+	go func() {
+		req := <-s.reqC
+
+		// An error number followed by the request header.
+		b := []byte{0xfe, 0xff, 0xff, 0xff}
+		b = append(b, make([]byte, nlmsgHeaderLen)...)
+
+		s.resC <- []Message{{
+			Header: Header{Type: Error, Sequence: req.Header.Sequence, PID: 1},
+			Data:   b,
+		}}
+	}()
+
+	_, err := m.Execute(context.Background(), Message{
+		Header: Header{Flags: Request | Acknowledge},
+	})
+
+	var oerr *OpError
+	if !errors.As(err, &oerr) || oerr.Err == nil {
+		t.Fatalf("expected a netlink error, but got: %v", err)
+	}
+}
+
+func TestMuxExecuteCanceled(t *testing.T) {
+	s := newMuxSocket()
+	m := NewMux(NewConn(s, 1))
+	defer m.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-s.reqC
+		cancel()
+	}()
+
+	_, err := m.Execute(ctx, Message{
+		Header: Header{Flags: Request | Acknowledge},
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected canceled error, but got: %v", err)
+	}
+}
+
+func Test_isDumpRequest(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags HeaderFlags
+		ok    bool
+	}{
+		{name: "dump", flags: FlagsDumpRequest, ok: true},
+		{name: "acknowledge", flags: Request | Acknowledge},
+		{name: "create exclusive", flags: FlagsCreateRequest},
+		{name: "create replace exclusive", flags: Request | Create | Replace | Excl},
+		{name: "append replace exclusive", flags: Request | Append | Replace | Excl},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.ok, isDumpRequest(tt.flags)); diff != "" {
+				t.Fatalf("unexpected dump request result for %s (-want +got):\n%s", tt.flags, diff)
+			}
+		})
+	}
+}
+
+var _ Socket = &muxSocket{}
+
+// A muxSocket is a Socket which passes requests and replies over channels.
+type muxSocket struct {
+	reqC   chan Message
+	resC   chan []Message
+	errC   chan error
+	closed chan struct{}
+}
+
+func newMuxSocket() *muxSocket {
+	return &muxSocket{
+		reqC:   make(chan Message),
+		resC:   make(chan []Message),
+		errC:   make(chan error),
+		closed: make(chan struct{}),
+	}
+}
+
+func (s *muxSocket) Send(m Message) error {
+	s.reqC <- m
+	return nil
+}
+
+func (s *muxSocket) Receive() ([]Message, error) {
+	select {
+	case msgs := <-s.resC:
+		return msgs, nil
+	case err := <-s.errC:
+		return nil, err
+	case <-s.closed:
+		return nil, os.ErrClosed
+	}
+}
+
+func (s *muxSocket) Close() error {
+	select {
+	case <-s.closed:
+	default:
+		close(s.closed)
+	}
+
+	return nil
+}
+
+func (s *muxSocket) SendMessages(_ []Message) error { panic("unimplemented") }
@@ -0,0 +1,173 @@
+package nltest
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nlenc"
+)
+
+// An Invariant is a property which must hold for every netlink request passed
+// to a Func and the responses the Func produces.
+type Invariant struct {
+	// Name is a short description of the Invariant, used in errors.
+	Name string
+
+	// Check checks the Invariant against a single request and its responses,
+	// returning an error if the Invariant does not hold.
+	Check func(req netlink.Message, res []netlink.Message) error
+}
+
+// Invariants returns the standard set of framing Invariants for a netlink
+// family whose messages carry a fixed-length family header of hdrLen bytes,
+// followed by netlink attributes. If hdrLen is negative, message data is not
+// checked for valid attributes.
+//
+// The standard Invariants check that:
+//   - each request sets the netlink.Request flag
+//   - each message header length matches the length of its data
+//   - the family header is properly aligned and attributes are well-formed,
+//     with zeroed alignment padding
+//   - each response echoes the sequence number and PID of its request
+func Invariants(hdrLen int) []Invariant {
+	return []Invariant{
+		{
+			Name: "request flag set",
+			Check: func(req netlink.Message, _ []netlink.Message) error {
+				if req.Header.Flags&netlink.Request == 0 {
+					return fmt.Errorf("flags %s do not include request", req.Header.Flags)
+				}
+
+				return nil
+			},
+		},
+		{
+			Name: "header length correct",
+			Check: func(req netlink.Message, res []netlink.Message) error {
+				for _, m := range append([]netlink.Message{req}, res...) {
+					if want, got := uint32(align(headerLen+len(m.Data))), m.Header.Length; want != got {
+						return fmt.Errorf("header length %d, want %d for %d bytes of data", got, want, len(m.Data))
+					}
+				}
+
+				return nil
+			},
+		},
+		{
+			Name: "attributes well-formed",
+			Check: func(req netlink.Message, res []netlink.Message) error {
+				if hdrLen < 0 {
+					return nil
+				}
+
+				for _, m := range append([]netlink.Message{req}, res...) {
+					// Error and done messages do not carry family data.
+					if m.Header.Type == netlink.Error || m.Header.Type == netlink.Done {
+						continue
+					}
+
+					if err := checkAttributes(m.Data, hdrLen); err != nil {
+						return err
+					}
+				}
+
+				return nil
+			},
+		},
+		{
+			Name: "sequence and PID echoed",
+			Check: func(req netlink.Message, res []netlink.Message) error {
+				for _, m := range res {
+					if m.Header.Sequence != req.Header.Sequence {
+						return fmt.Errorf("response sequence %d, want %d", m.Header.Sequence, req.Header.Sequence)
+					}
+					if m.Header.PID != req.Header.PID {
+						return fmt.Errorf("response PID %d, want %d", m.Header.PID, req.Header.PID)
+					}
+				}
+
+				return nil
+			},
+		},
+	}
+}
+
+// CheckConformance returns a Func which checks each request and the responses
+// produced by fn against invs, such as those returned by Invariants. If an
+// Invariant does not hold, the returned Func returns an error naming the
+// Invariant instead of the responses.
+//
+// CheckConformance is intended for packages which implement a netlink family,
+// to catch message framing bugs in their encoders and test fixtures.
+func CheckConformance(invs []Invariant, fn Func) Func {
+	return func(req []netlink.Message) ([]netlink.Message, error) {
+		res, err := fn(req)
+		if err != nil {
+			return res, err
+		}
+
+		// Multicast receives have no request to check against.
+		if len(req) == 0 {
+			return res, nil
+		}
+
+		for _, r := range req {
+			for _, inv := range invs {
+				if cerr := inv.Check(r, res); cerr != nil {
+					return nil, fmt.Errorf("nltest: invariant %q violated: %v", inv.Name, cerr)
+				}
+			}
+		}
+
+		return res, nil
+	}
+}
+
+// headerLen is the length of a netlink message header.
+const headerLen = 16
+
+// align aligns n to the netlink message and attribute alignment of 4 bytes.
+func align(n int) int {
+	return (n + 3) &^ 3
+}
+
+// checkAttributes checks that b contains a family header of hdrLen bytes
+// followed by well-formed attributes with zeroed padding.
+func checkAttributes(b []byte, hdrLen int) error {
+	if len(b) < hdrLen {
+		return fmt.Errorf("data length %d is shorter than family header length %d", len(b), hdrLen)
+	}
+	if hdrLen != align(hdrLen) {
+		return fmt.Errorf("family header length %d is not aligned", hdrLen)
+	}
+
+	b = b[hdrLen:]
+	for len(b) > 0 {
+		if len(b) < 4 {
+			return errors.New("trailing bytes are too short for an attribute")
+		}
+
+		l := int(nlenc.Uint16(b[0:2]))
+
+		if l < 4 || l > len(b) {
+			return fmt.Errorf("attribute length %d is invalid for %d remaining bytes", l, len(b))
+		}
+
+		end := align(l)
+		if end > len(b) {
+			// The final attribute may omit its trailing padding.
+			end = len(b)
+		}
+
+		for _, p := range b[l:end] {
+			if p != 0 {
+				return fmt.Errorf("attribute padding is not zeroed: [%# x]", b[l:end])
+			}
+		}
+
+		b = b[end:]
+	}
+
+	return nil
+}
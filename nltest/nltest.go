@@ -57,6 +57,24 @@ func Error(number int, reqs []netlink.Message) ([]netlink.Message, error) {
 	return []netlink.Message{req}, nil
 }
 
+// Canonicalize returns a copy of m suitable for comparison with other messages
+// in tests. The header sequence number and PID are zeroed, the header length is
+// recomputed from the length of the message data, and empty data is set to
+// nil so that messages compare equal using reflect.DeepEqual or cmp.Diff.
+func Canonicalize(m netlink.Message) netlink.Message {
+	const hdrLen = 16
+
+	m.Header.Sequence = 0
+	m.Header.PID = 0
+	m.Header.Length = uint32((hdrLen + len(m.Data) + 3) &^ 3)
+
+	if len(m.Data) == 0 {
+		m.Data = nil
+	}
+
+	return m
+}
+
 // A Func is a function that can be used to test netlink.Conn interactions.
 // The function can choose to return zero or more netlink messages, or an
 // error if needed.
@@ -83,6 +101,19 @@ func Dial(fn Func) *netlink.Conn {
 	return netlink.NewConn(sock, PID)
 }
 
+// Intercept registers a netlink.DialInterceptor which causes every subsequent
+// call to netlink.Dial in the process to return a netlink.Conn backed by fn,
+// regardless of the netlink family. The returned function restores the
+// previously registered netlink.DialInterceptor and should be called when the
+// test is complete.
+func Intercept(fn Func) (restore func()) {
+	prev := netlink.SetDialInterceptor(func(_ int, _ *netlink.Config) (netlink.Socket, uint32, error) {
+		return &socket{fn: fn}, PID, nil
+	})
+
+	return func() { netlink.SetDialInterceptor(prev) }
+}
+
 // CheckRequest returns a Func that verifies that each message in an incoming
 // request has the specified netlink header type and flags in the same slice
 // position index, and then passes the request through to fn.
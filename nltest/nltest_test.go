@@ -542,3 +542,161 @@ func skipBigEndian(t *testing.T) {
 		t.Skip("skipping test on big-endian system")
 	}
 }
+
+func TestIntercept(t *testing.T) {
+	var flags netlink.HeaderFlags
+	restore := nltest.Intercept(func(req []netlink.Message) ([]netlink.Message, error) {
+		flags = req[0].Header.Flags
+		return nltest.Multipart(req)
+	})
+	defer restore()
+
+	c, err := netlink.Dial(0, &netlink.Config{DefaultFlags: netlink.Acknowledge})
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer c.Close()
+
+	if _, err := c.Execute(netlink.Message{Header: netlink.Header{Flags: netlink.Request}}); err != nil {
+		t.Fatalf("failed to execute: %v", err)
+	}
+
+	if diff := cmp.Diff(netlink.Request|netlink.Acknowledge, flags); diff != "" {
+		t.Fatalf("unexpected request flags (-want +got):\n%s", diff)
+	}
+}
+
+func TestCheckConformance(t *testing.T) {
+	skipBigEndian(t)
+
+	attrs := nltest.MustMarshalAttributes([]netlink.Attribute{{
+		Type: 1,
+		Data: []byte("hi"),
+	}})
+
+	tests := []struct {
+		name  string
+		flags netlink.HeaderFlags
+		data  []byte
+		fn    nltest.Func
+		ok    bool
+	}{
+		{
+			name:  "OK",
+			flags: netlink.Request,
+			data:  append([]byte{0x01, 0x00, 0x00, 0x00}, attrs...),
+			fn:    nltest.Multipart,
+			ok:    true,
+		},
+		{
+			name: "no request flag",
+			data: []byte{0x01, 0x00, 0x00, 0x00},
+			fn:   nltest.Multipart,
+		},
+		{
+			name:  "short family header",
+			flags: netlink.Request,
+			data:  []byte{0x01, 0x00},
+			fn:    nltest.Multipart,
+		},
+		{
+			name:  "bad attribute padding",
+			flags: netlink.Request,
+			data: []byte{
+				0x01, 0x00, 0x00, 0x00,
+				0x05, 0x00, 0x01, 0x00, 0xff, 0xff, 0x00, 0x00,
+			},
+			fn: nltest.Multipart,
+		},
+		{
+			name:  "sequence not echoed",
+			flags: netlink.Request,
+			data:  []byte{0x01, 0x00, 0x00, 0x00},
+			fn: func(req []netlink.Message) ([]netlink.Message, error) {
+				res := req[0]
+				res.Header.Sequence++
+				return []netlink.Message{res}, nil
+			},
+		},
+		{
+			name:  "bad response length",
+			flags: netlink.Request,
+			data:  []byte{0x01, 0x00, 0x00, 0x00},
+			fn: func(req []netlink.Message) ([]netlink.Message, error) {
+				res := req[0]
+				res.Data = append(res.Data, 0xff, 0xff, 0xff, 0xff)
+				return []netlink.Message{res}, nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := nltest.Dial(nltest.CheckConformance(nltest.Invariants(4), tt.fn))
+			defer c.Close()
+
+			_, err := c.Execute(netlink.Message{
+				Header: netlink.Header{Flags: tt.flags},
+				Data:   tt.data,
+			})
+			if tt.ok && err != nil {
+				t.Fatalf("failed to execute: %v", err)
+			}
+			if !tt.ok && err == nil {
+				t.Fatal("expected an error, but none occurred")
+			}
+		})
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   netlink.Message
+		out  netlink.Message
+	}{
+		{
+			name: "empty",
+			in: netlink.Message{
+				Header: netlink.Header{
+					Sequence: 10,
+					PID:      1000,
+				},
+				Data: []byte{},
+			},
+			out: netlink.Message{
+				Header: netlink.Header{Length: 16},
+			},
+		},
+		{
+			name: "data",
+			in: netlink.Message{
+				Header: netlink.Header{
+					Length:   4096,
+					Type:     netlink.Error,
+					Flags:    netlink.Request | netlink.Acknowledge,
+					Sequence: 1,
+					PID:      nltest.PID,
+				},
+				Data: []byte{0x11, 0x22, 0x33, 0x44, 0x55},
+			},
+			out: netlink.Message{
+				Header: netlink.Header{
+					Length: 24,
+					Type:   netlink.Error,
+					Flags:  netlink.Request | netlink.Acknowledge,
+				},
+				Data: []byte{0x11, 0x22, 0x33, 0x44, 0x55},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if want, got := tt.out, nltest.Canonicalize(tt.in); !reflect.DeepEqual(want, got) {
+				t.Fatalf("unexpected canonical message:\n- want: %v\n-  got: %v",
+					want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package nltest
+
+import (
+	"math/rand"
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mdlayher/netlink"
+)
+
+// RoundTripIterations is the number of values checked by each call to
+// RoundTrip.
+const RoundTripIterations = 1000
+
+// maxAttributeData bounds the length of the data of attributes produced by
+// RandomAttributes.
+const maxAttributeData = 16
+
+// RandomAttributes produces a random tree of well-formed netlink attributes
+// using r, for use in property tests. Attribute data has random lengths so that
+// alignment padding is exercised, and nested attributes are produced up to
+// depth levels deep. Nested attributes have the netlink.Nested flag set in
+// their type, and data containing their marshaled children.
+//
+// Each Attribute's Length is set so that the tree is unchanged after a round
+// trip through netlink.MarshalAttributes and netlink.UnmarshalAttributes.
+func RandomAttributes(r *rand.Rand, depth int) []netlink.Attribute {
+	n := r.Intn(8)
+	if n == 0 {
+		// Match the output of netlink.UnmarshalAttributes.
+		return nil
+	}
+
+	attrs := make([]netlink.Attribute, n)
+	for i := range attrs {
+		// Avoid the flag bits which are reserved in attribute types.
+		typ := uint16(r.Intn(1<<14-1) + 1)
+
+		var b []byte
+		if depth > 0 && r.Intn(4) == 0 {
+			typ |= netlink.Nested
+			b = MustMarshalAttributes(RandomAttributes(r, depth-1))
+		} else {
+			b = make([]byte, r.Intn(maxAttributeData+1))
+			r.Read(b)
+		}
+
+		attrs[i] = netlink.Attribute{
+			Length: uint16(4 + len(b)),
+			Type:   typ,
+			Data:   b,
+		}
+	}
+
+	return attrs
+}
+
+// A Codec encodes and decodes values of an arbitrary type to and from their
+// netlink binary form, for use with RoundTrip.
+type Codec struct {
+	// Generate produces a random value using r.
+	Generate func(r *rand.Rand) interface{}
+
+	// Encode and Decode convert a value to and from its binary form.
+	Encode func(v interface{}) ([]byte, error)
+	Decode func(b []byte) (interface{}, error)
+}
+
+// AttributesCodec returns a Codec which generates trees of attributes using
+// RandomAttributes with the specified depth, and encodes and decodes them using
+// netlink.MarshalAttributes and netlink.UnmarshalAttributes.
+func AttributesCodec(depth int) Codec {
+	return Codec{
+		Generate: func(r *rand.Rand) interface{} {
+			return RandomAttributes(r, depth)
+		},
+		Encode: func(v interface{}) ([]byte, error) {
+			return netlink.MarshalAttributes(v.([]netlink.Attribute))
+		},
+		Decode: func(b []byte) (interface{}, error) {
+			return netlink.UnmarshalAttributes(b)
+		},
+	}
+}
+
+// RoundTrip checks the property that values produced by c are unchanged after
+// being encoded and decoded by c, for RoundTripIterations random values.
+//
+// The random seed is logged when the property does not hold. Set the
+// NLTEST_SEED environment variable to that seed to reproduce a failure.
+func RoundTrip(t testing.TB, c Codec) {
+	t.Helper()
+
+	seed := time.Now().UnixNano()
+	if s := os.Getenv("NLTEST_SEED"); s != "" {
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			t.Fatalf("invalid NLTEST_SEED: %v", err)
+		}
+
+		seed = v
+	}
+
+	r := rand.New(rand.NewSource(seed))
+	for i := 0; i < RoundTripIterations; i++ {
+		want := c.Generate(r)
+
+		b, err := c.Encode(want)
+		if err != nil {
+			t.Fatalf("seed %d: iteration %d: failed to encode %#v: %v", seed, i, want, err)
+		}
+
+		got, err := c.Decode(b)
+		if err != nil {
+			t.Fatalf("seed %d: iteration %d: failed to decode %#v: %v", seed, i, want, err)
+		}
+
+		if !reflect.DeepEqual(want, got) {
+			t.Fatalf("seed %d: iteration %d: value changed by round trip:\n- want: %#v\n-  got: %#v",
+				seed, i, want, got)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package nltest_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nltest"
+)
+
+func TestRoundTripMarshalAttributes(t *testing.T) {
+	nltest.RoundTrip(t, nltest.AttributesCodec(3))
+}
+
+func TestRoundTripAttributeEncoderDecoder(t *testing.T) {
+	nltest.RoundTrip(t, nltest.Codec{
+		Generate: func(r *rand.Rand) interface{} {
+			return nltest.RandomAttributes(r, 3)
+		},
+		Encode: func(v interface{}) ([]byte, error) {
+			ae := netlink.NewAttributeEncoder()
+			encodeTree(ae, v.([]netlink.Attribute))
+			return ae.Encode()
+		},
+		Decode: func(b []byte) (interface{}, error) {
+			ad, err := netlink.NewAttributeDecoder(b)
+			if err != nil {
+				return nil, err
+			}
+
+			attrs := decodeTree(ad)
+			return attrs, ad.Err()
+		},
+	})
+}
+
+// encodeTree encodes a tree of attributes using ae, descending into nested
+// attributes.
+func encodeTree(ae *netlink.AttributeEncoder, attrs []netlink.Attribute) {
+	for _, a := range attrs {
+		if a.Type&netlink.Nested == 0 {
+			ae.Bytes(a.Type, a.Data)
+			continue
+		}
+
+		children, err := netlink.UnmarshalAttributes(a.Data)
+		if err != nil {
+			panic(err)
+		}
+
+		ae.Nested(a.Type&^netlink.Nested, func(nae *netlink.AttributeEncoder) error {
+			encodeTree(nae, children)
+			return nil
+		})
+	}
+}
+
+// decodeTree decodes a tree of attributes using ad, descending into nested
+// attributes.
+func decodeTree(ad *netlink.AttributeDecoder) []netlink.Attribute {
+	var attrs []netlink.Attribute
+	for ad.Next() {
+		a := netlink.Attribute{Type: ad.Type() | ad.TypeFlags()}
+		if a.Type&netlink.Nested == 0 {
+			a.Data = ad.Bytes()
+		} else {
+			ad.Nested(func(nad *netlink.AttributeDecoder) error {
+				b, err := netlink.MarshalAttributes(decodeTree(nad))
+				a.Data = b
+				return err
+			})
+		}
+
+		a.Length = uint16(4 + len(a.Data))
+		attrs = append(attrs, a)
+	}
+
+	return attrs
+}
@@ -0,0 +1,11 @@
+// Package nltestenv provides disposable network namespaces and interfaces for
+// netlink integration tests.
+//
+// Resources created by this package are removed automatically when the test
+// which created them completes. Tests which require elevated privileges are
+// skipped when the caller lacks them. Main can optionally be used to run such
+// tests without root within an unprivileged user namespace.
+//
+// Package nltestenv is intended for use in tests only, and is only functional
+// on Linux.
+package nltestenv
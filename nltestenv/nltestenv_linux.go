@@ -0,0 +1,267 @@
+//go:build linux
+// +build linux
+
+package nltestenv
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"syscall"
+	"testing"
+
+	"github.com/mdlayher/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// SkipUnprivileged skips the test unless the caller has the CAP_NET_ADMIN
+// capability, which is required to create network namespaces and interfaces.
+func SkipUnprivileged(t testing.TB) {
+	t.Helper()
+
+	ok, err := netAdmin()
+	if err != nil {
+		t.Fatalf("failed to check capabilities: %v", err)
+	}
+	if !ok {
+		t.Skip("skipping, test requires CAP_NET_ADMIN")
+	}
+}
+
+const (
+	// modeEnv opts in to running tests within a user namespace when set to
+	// "userns".
+	modeEnv = "NLTESTENV"
+
+	// userNSEnv is set in the environment of a test binary which was
+	// re-executed within a user namespace by Main.
+	userNSEnv = "NLTESTENV_USERNS"
+)
+
+// Main runs the tests in m and returns an exit code for os.Exit, and is
+// intended to be called from TestMain:
+//
+//	func TestMain(m *testing.M) { os.Exit(nltestenv.Main(m)) }
+//
+// Because every test in the binary then runs in a different environment, Main
+// only takes effect when the NLTESTENV environment variable is set to
+// "userns", and otherwise runs the tests as usual. When enabled and the
+// caller lacks the CAP_NET_ADMIN capability, Main re-executes the test binary
+// as root within new user and network namespaces, so that tests which require
+// privileges can run without root, such as in CI. If the kernel does not
+// permit unprivileged user namespaces, the tests run as usual and tests which
+// require privileges are skipped.
+//
+// Main is best suited to a test package dedicated to privileged tests, so
+// that tests which exercise unprivileged behavior are unaffected.
+func Main(m *testing.M) int {
+	if os.Getenv(modeEnv) != "userns" || os.Getenv(userNSEnv) != "" {
+		// Not enabled, or already running within a user namespace.
+		return m.Run()
+	}
+
+	if ok, err := netAdmin(); err != nil || ok {
+		return m.Run()
+	}
+
+	code, err := runUserNS()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "nltestenv: running tests without a user namespace: %v\n", err)
+		return m.Run()
+	}
+
+	return code
+}
+
+// runUserNS re-executes the current binary with the same arguments as root
+// within new user and network namespaces, and returns its exit code.
+func runUserNS() (int, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return 0, err
+	}
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.Env = append(os.Environ(), userNSEnv+"=1")
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags: unix.CLONE_NEWUSER | unix.CLONE_NEWNET,
+		UidMappings: []syscall.SysProcIDMap{{
+			ContainerID: 0,
+			HostID:      os.Getuid(),
+			Size:        1,
+		}},
+		GidMappings: []syscall.SysProcIDMap{{
+			ContainerID: 0,
+			HostID:      os.Getgid(),
+			Size:        1,
+		}},
+	}
+
+	err = cmd.Run()
+	var eerr *exec.ExitError
+	switch {
+	case errors.As(err, &eerr):
+		// The tests ran but failed.
+		return eerr.ExitCode(), nil
+	case err != nil:
+		return 0, err
+	}
+
+	return 0, nil
+}
+
+// netAdmin reports whether the calling thread has the CAP_NET_ADMIN capability.
+func netAdmin() (bool, error) {
+	var (
+		hdr  = unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}
+		data [2]unix.CapUserData
+	)
+
+	if err := unix.Capget(&hdr, &data[0]); err != nil {
+		return false, os.NewSyscallError("capget", err)
+	}
+
+	return data[0].Effective&(1<<unix.CAP_NET_ADMIN) != 0, nil
+}
+
+// A NetNS is a disposable network namespace.
+type NetNS struct {
+	t testing.TB
+	f *os.File
+}
+
+// NewNetNS creates a new network namespace which is destroyed when the test
+// completes. The test is skipped if the caller lacks the privileges to create
+// a network namespace.
+func NewNetNS(t testing.TB) *NetNS {
+	t.Helper()
+	SkipUnprivileged(t)
+
+	var f *os.File
+	err := do(func() error {
+		if err := unix.Unshare(unix.CLONE_NEWNET); err != nil {
+			return os.NewSyscallError("unshare", err)
+		}
+
+		// Keep a reference to the namespace after the thread is discarded.
+		var err error
+		f, err = os.Open(fmt.Sprintf("/proc/self/task/%d/ns/net", unix.Gettid()))
+		return err
+	})
+	switch {
+	case errors.Is(err, unix.EPERM):
+		t.Skipf("skipping, permission denied: %v", err)
+	case err != nil:
+		t.Fatalf("failed to create network namespace: %v", err)
+	}
+
+	t.Cleanup(func() {
+		// The namespace is destroyed once all references to it are gone.
+		if err := f.Close(); err != nil {
+			t.Errorf("failed to close network namespace: %v", err)
+		}
+	})
+
+	return &NetNS{t: t, f: f}
+}
+
+// FD returns a file descriptor for the network namespace, suitable for use
+// with netlink.Config.NetNS.
+func (ns *NetNS) FD() int { return int(ns.f.Fd()) }
+
+// Do executes fn on an operating system thread within the network namespace.
+// fn must not start goroutines which depend on the network namespace.
+func (ns *NetNS) Do(fn func() error) error {
+	return do(func() error {
+		if err := unix.Setns(ns.FD(), unix.CLONE_NEWNET); err != nil {
+			return os.NewSyscallError("setns", err)
+		}
+
+		return fn()
+	})
+}
+
+// vethInfoPeer is VETH_INFO_PEER from linux/veth.h.
+const vethInfoPeer = 1
+
+// AddVeth creates a pair of veth interfaces with the specified names within
+// the network namespace. The interfaces are removed along with the namespace.
+func (ns *NetNS) AddVeth(name, peer string) {
+	ns.t.Helper()
+
+	pae := netlink.NewAttributeEncoder()
+	pae.String(unix.IFLA_IFNAME, peer)
+	pb, err := pae.Encode()
+	if err != nil {
+		ns.t.Fatalf("failed to encode veth peer attributes: %v", err)
+	}
+
+	ae := netlink.NewAttributeEncoder()
+	ae.String(unix.IFLA_IFNAME, name)
+	ae.Nested(unix.IFLA_LINKINFO, func(nae *netlink.AttributeEncoder) error {
+		nae.String(unix.IFLA_INFO_KIND, "veth")
+		nae.Nested(unix.IFLA_INFO_DATA, func(nae *netlink.AttributeEncoder) error {
+			// The peer is described by its own ifinfomsg and attributes.
+			nae.Bytes(vethInfoPeer, append(make([]byte, unix.SizeofIfInfomsg), pb...))
+			return nil
+		})
+		return nil
+	})
+
+	ns.link(unix.RTM_NEWLINK, netlink.FlagsCreateRequest, ae)
+}
+
+// DelLink removes the interface with the specified name from the network
+// namespace.
+func (ns *NetNS) DelLink(name string) {
+	ns.t.Helper()
+
+	ae := netlink.NewAttributeEncoder()
+	ae.String(unix.IFLA_IFNAME, name)
+
+	ns.link(unix.RTM_DELLINK, netlink.Request|netlink.Acknowledge, ae)
+}
+
+// link executes a route netlink link request with attributes from ae.
+func (ns *NetNS) link(typ netlink.HeaderType, flags netlink.HeaderFlags, ae *netlink.AttributeEncoder) {
+	ns.t.Helper()
+
+	attrs, err := ae.Encode()
+	if err != nil {
+		ns.t.Fatalf("failed to encode link attributes: %v", err)
+	}
+
+	c, err := netlink.Dial(unix.NETLINK_ROUTE, &netlink.Config{NetNS: ns.FD()})
+	if err != nil {
+		ns.t.Fatalf("failed to dial route netlink: %v", err)
+	}
+	defer c.Close()
+
+	// An empty ifinfomsg with AF_UNSPEC family, followed by attributes.
+	b := make([]byte, unix.SizeofIfInfomsg)
+
+	_, err = c.Execute(netlink.Message{
+		Header: netlink.Header{Type: typ, Flags: flags},
+		Data:   append(b, attrs...),
+	})
+	if err != nil {
+		ns.t.Fatalf("failed to execute link request: %v", err)
+	}
+}
+
+// do executes fn on a dedicated operating system thread which is discarded
+// afterward, so fn may modify the thread's network namespace freely.
+func do(fn func() error) error {
+	errC := make(chan error, 1)
+	go func() {
+		// Never unlock the thread: the runtime terminates it when this
+		// goroutine exits, rather than reusing it for other goroutines.
+		runtime.LockOSThread()
+		errC <- fn()
+	}()
+
+	return <-errC
+}
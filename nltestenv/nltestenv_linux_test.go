@@ -0,0 +1,56 @@
+package nltestenv_test
+
+import (
+	"net"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mdlayher/netlink/nltestenv"
+)
+
+func TestMain(m *testing.M) { os.Exit(nltestenv.Main(m)) }
+
+func TestNetNSAddVeth(t *testing.T) {
+	ns := nltestenv.NewNetNS(t)
+	ns.AddVeth("nltestenv0", "nltestenv1")
+
+	names := func() []string {
+		var names []string
+		err := ns.Do(func() error {
+			ifis, err := net.Interfaces()
+			if err != nil {
+				return err
+			}
+
+			for _, ifi := range ifis {
+				names = append(names, ifi.Name)
+			}
+
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("failed to list interfaces: %v", err)
+		}
+
+		sort.Strings(names)
+		return names
+	}
+
+	if diff := cmp.Diff([]string{"lo", "nltestenv0", "nltestenv1"}, names()); diff != "" {
+		t.Fatalf("unexpected interfaces (-want +got):\n%s", diff)
+	}
+
+	// The interfaces never existed in the host's network namespace.
+	if _, err := net.InterfaceByName("nltestenv1"); err == nil {
+		t.Fatal("veth interface unexpectedly exists in host network namespace")
+	}
+
+	// Deleting one end of a veth pair also deletes its peer.
+	ns.DelLink("nltestenv0")
+
+	if diff := cmp.Diff([]string{"lo"}, names()); diff != "" {
+		t.Fatalf("unexpected interfaces after delete (-want +got):\n%s", diff)
+	}
+}
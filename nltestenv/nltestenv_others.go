@@ -0,0 +1,35 @@
+//go:build !linux
+// +build !linux
+
+package nltestenv
+
+import (
+	"runtime"
+	"testing"
+)
+
+// SkipUnprivileged skips the test on platforms other than Linux.
+func SkipUnprivileged(t testing.TB) {
+	t.Helper()
+	t.Skipf("skipping, network namespaces are not supported on %s", runtime.GOOS)
+}
+
+// Main runs the tests in m and returns an exit code for os.Exit.
+func Main(m *testing.M) int { return m.Run() }
+
+// A NetNS is a disposable network namespace.
+type NetNS struct{}
+
+// NewNetNS skips the test on platforms other than Linux.
+func NewNetNS(t testing.TB) *NetNS {
+	t.Helper()
+	SkipUnprivileged(t)
+	return nil
+}
+
+// All methods are unreachable outside of Linux because NewNetNS skips the test.
+
+func (ns *NetNS) FD() int                 { return -1 }
+func (ns *NetNS) Do(_ func() error) error { return nil }
+func (ns *NetNS) AddVeth(_, _ string)     {}
+func (ns *NetNS) DelLink(_ string)        {}
@@ -0,0 +1,106 @@
+// Package nltrace provides an in-memory recorder of recent netlink
+// transactions, for post-mortem debugging without always-on logging, a simple
+// stream format for persisting netlink messages, and a writer for pcapng
+// captures which can be opened by tools such as Wireshark.
+package nltrace
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/mdlayher/netlink"
+)
+
+// A Recorder is a fixed-size ring buffer which retains the most recent
+// netlink.Transactions performed by one or more netlink.Conns. A Recorder is
+// safe for concurrent use.
+//
+// A Recorder retains references to the Messages in each Transaction, so
+// callers should not modify the Messages returned by Conn.Execute while they
+// may still be dumped.
+type Recorder struct {
+	mu   sync.Mutex
+	txs  []netlink.Transaction
+	next int
+	full bool
+}
+
+// NewRecorder creates a Recorder which retains the last n Transactions. If n
+// is less than 1, the Recorder retains a single Transaction.
+func NewRecorder(n int) *Recorder {
+	if n < 1 {
+		n = 1
+	}
+
+	return &Recorder{txs: make([]netlink.Transaction, n)}
+}
+
+// Observer returns a netlink.Observer which records Transactions into the
+// Recorder. It can be attached to a Conn using netlink.Conn.SetObserver.
+func (r *Recorder) Observer() *netlink.Observer {
+	return &netlink.Observer{Execute: r.Record}
+}
+
+// Record adds t to the Recorder, evicting the oldest Transaction if the
+// Recorder is full.
+func (r *Recorder) Record(t netlink.Transaction) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.txs[r.next] = t
+	r.next++
+	if r.next == len(r.txs) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+// Transactions returns a copy of the recorded Transactions, from oldest to
+// newest.
+func (r *Recorder) Transactions() []netlink.Transaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.full {
+		return append([]netlink.Transaction(nil), r.txs[:r.next]...)
+	}
+
+	out := make([]netlink.Transaction, 0, len(r.txs))
+	out = append(out, r.txs[r.next:]...)
+	return append(out, r.txs[:r.next]...)
+}
+
+// Dump writes a human-readable summary of the recorded Transactions to w, from
+// oldest to newest.
+func (r *Recorder) Dump(w io.Writer) error {
+	for _, t := range r.Transactions() {
+		if err := dump(w, t); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dump writes a summary of a single Transaction to w.
+func dump(w io.Writer, t netlink.Transaction) error {
+	h := t.Request.Header
+	_, err := fmt.Fprintf(w, "%s %s request: type: %d, flags: %s, seq: %d, pid: %d, replies: %d, parts: %d, bytes: %d",
+		t.Start.Format(time.RFC3339Nano), t.Duration(),
+		h.Type, h.Flags, h.Sequence, h.PID, len(t.Replies), t.Parts, t.Bytes,
+	)
+	if err != nil {
+		return err
+	}
+
+	if t.Err != nil {
+		if _, err := fmt.Fprintf(w, ", err: %v", t.Err); err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintln(w)
+	return err
+}
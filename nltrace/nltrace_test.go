@@ -0,0 +1,95 @@
+package nltrace_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nltest"
+	"github.com/mdlayher/netlink/nltrace"
+)
+
+func TestRecorderWraps(t *testing.T) {
+	r := nltrace.NewRecorder(2)
+
+	for i := 1; i <= 3; i++ {
+		r.Record(netlink.Transaction{
+			Request: netlink.Message{Header: netlink.Header{Sequence: uint32(i)}},
+		})
+	}
+
+	var seqs []uint32
+	for _, tx := range r.Transactions() {
+		seqs = append(seqs, tx.Request.Header.Sequence)
+	}
+
+	if diff := cmp.Diff([]uint32{2, 3}, seqs); diff != "" {
+		t.Fatalf("unexpected sequences (-want +got):\n%s", diff)
+	}
+}
+
+func TestRecorderConn(t *testing.T) {
+	errFail := errors.New("fail")
+
+	var calls int
+	c := nltest.Dial(func(req []netlink.Message) ([]netlink.Message, error) {
+		calls++
+		if calls == 2 {
+			return nil, errFail
+		}
+
+		return nltest.Multipart(req)
+	})
+	defer c.Close()
+
+	r := nltrace.NewRecorder(8)
+	c.SetObserver(r.Observer())
+
+	req := netlink.Message{Header: netlink.Header{Flags: netlink.Request | netlink.Acknowledge}}
+	if _, err := c.Execute(req); err != nil {
+		t.Fatalf("failed to execute: %v", err)
+	}
+	if _, err := c.Execute(req); err == nil {
+		t.Fatal("expected an error, but none occurred")
+	}
+
+	txs := r.Transactions()
+	if diff := cmp.Diff(2, len(txs)); diff != "" {
+		t.Fatalf("unexpected number of transactions (-want +got):\n%s", diff)
+	}
+
+	if txs[0].Err != nil || len(txs[0].Replies) != 1 {
+		t.Fatalf("unexpected first transaction: %+v", txs[0])
+	}
+	if !errors.Is(txs[1].Err, errFail) {
+		t.Fatalf("unexpected second transaction error: %v", txs[1].Err)
+	}
+	if txs[1].End.Before(txs[1].Start) {
+		t.Fatal("transaction ended before it started")
+	}
+
+	var b bytes.Buffer
+	if err := r.Dump(&b); err != nil {
+		t.Fatalf("failed to dump: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if diff := cmp.Diff(2, len(lines)); diff != "" {
+		t.Fatalf("unexpected number of dump lines (-want +got):\n%s", diff)
+	}
+	if !strings.Contains(lines[1], "err: ") {
+		t.Fatalf("expected error in dump line: %q", lines[1])
+	}
+
+	// Removing the Observer stops recording.
+	c.SetObserver(nil)
+	if _, err := c.Execute(req); err != nil {
+		t.Fatalf("failed to execute: %v", err)
+	}
+	if diff := cmp.Diff(2, len(r.Transactions())); diff != "" {
+		t.Fatalf("unexpected number of transactions (-want +got):\n%s", diff)
+	}
+}
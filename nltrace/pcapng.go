@@ -0,0 +1,200 @@
+package nltrace
+
+import (
+	"encoding/binary"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nlenc"
+)
+
+// pcapng block types and constants from the pcapng specification. Blocks are
+// written in native byte order, matching the netlink messages they contain,
+// as indicated by the byte-order magic of the section header.
+const (
+	blockSectionHeader        = 0x0a0d0d0a
+	blockInterfaceDescription = 0x00000001
+	blockEnhancedPacket       = 0x00000006
+
+	byteOrderMagic = 0x1a2b3c4d
+
+	// optIfTSResol specifies the resolution of packet timestamps, which
+	// pcapng writes as nanoseconds.
+	optIfTSResol = 9
+	tsResolNanos = 9
+
+	// linkTypeNetlink is LINKTYPE_NETLINK, as produced by nlmon devices.
+	linkTypeNetlink = 253
+
+	// blockAlign is the alignment of pcapng blocks, which is also the
+	// alignment of netlink messages.
+	blockAlign = 4
+)
+
+// The LINKTYPE_NETLINK pseudo-header is a Linux cooked capture (SLL) header,
+// consisting of big-endian fields:
+//   - a uint16 packet type: host (received) or outgoing (sent)
+//   - a uint16 ARPHRD type, which is always ARPHRD_NETLINK
+//   - a uint16 link-layer address length and 8 byte address, which are unused
+//   - a uint16 protocol type, which is the netlink family
+const (
+	sllHeaderLen  = 16
+	sllHost       = 0
+	sllOutgoing   = 4
+	arphrdNetlink = 824
+)
+
+// A PcapngWriter writes netlink messages to a pcapng capture using the
+// LINKTYPE_NETLINK link type, so captures can be opened by tools such as
+// Wireshark alongside captures from nlmon devices. A PcapngWriter is safe for
+// concurrent use.
+type PcapngWriter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	buf []byte
+	err error
+}
+
+// NewPcapngWriter creates a PcapngWriter which writes a pcapng capture to w,
+// beginning with the section header and interface description.
+func NewPcapngWriter(w io.Writer) (*PcapngWriter, error) {
+	pw := &PcapngWriter{w: w}
+
+	// Section header: magic, version 1.0, and unknown section length.
+	b := pw.block(blockSectionHeader, 16)
+	nlenc.PutUint32(b[8:12], byteOrderMagic)
+	nlenc.PutUint16(b[12:14], 1)
+	nlenc.PutUint16(b[14:16], 0)
+	nlenc.PutUint64(b[16:24], ^uint64(0))
+	if err := pw.write(b); err != nil {
+		return nil, err
+	}
+
+	// Interface description: link type, no snapshot length limit, and
+	// nanosecond timestamps, followed by the end of options.
+	b = pw.block(blockInterfaceDescription, 8+8+4)
+	nlenc.PutUint16(b[8:10], linkTypeNetlink)
+	nlenc.PutUint16(b[16:18], optIfTSResol)
+	nlenc.PutUint16(b[18:20], 1)
+	b[20] = tsResolNanos
+	if err := pw.write(b); err != nil {
+		return nil, err
+	}
+
+	return pw, nil
+}
+
+// WriteRecord writes r as a single packet for the specified netlink family.
+func (pw *PcapngWriter) WriteRecord(family netlink.Family, r Record) error {
+	return pw.WritePacket(family, r.Direction, r.Time, []netlink.Message{r.Message})
+}
+
+// WritePacket writes msgs as a single packet for the specified netlink family,
+// as if they were sent or received in a single datagram at time t.
+func (pw *PcapngWriter) WritePacket(family netlink.Family, d Direction, t time.Time, msgs []netlink.Message) error {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	var n int
+	for _, m := range msgs {
+		n += align(nlmsgHeaderLen + len(m.Data))
+	}
+
+	b := pw.block(blockEnhancedPacket, 20+sllHeaderLen+n)
+
+	ns := uint64(t.UnixNano())
+	nlenc.PutUint32(b[12:16], uint32(ns>>32))
+	nlenc.PutUint32(b[16:20], uint32(ns))
+	nlenc.PutUint32(b[20:24], uint32(sllHeaderLen+n))
+	nlenc.PutUint32(b[24:28], uint32(sllHeaderLen+n))
+
+	sll := b[28 : 28+sllHeaderLen]
+	pkt := uint16(sllHost)
+	if d == Send {
+		pkt = sllOutgoing
+	}
+	binary.BigEndian.PutUint16(sll[0:2], pkt)
+	binary.BigEndian.PutUint16(sll[2:4], arphrdNetlink)
+	binary.BigEndian.PutUint16(sll[14:16], uint16(family))
+
+	mb := b[28+sllHeaderLen:]
+	for _, m := range msgs {
+		h := m.Header
+		nlenc.PutUint32(mb[0:4], h.Length)
+		nlenc.PutUint16(mb[4:6], uint16(h.Type))
+		nlenc.PutUint16(mb[6:8], uint16(h.Flags))
+		nlenc.PutUint32(mb[8:12], h.Sequence)
+		nlenc.PutUint32(mb[12:16], h.PID)
+		copy(mb[nlmsgHeaderLen:], m.Data)
+
+		mb = mb[align(nlmsgHeaderLen+len(m.Data)):]
+	}
+
+	return pw.write(b)
+}
+
+// Observer returns a netlink.Observer which writes each datagram sent or
+// received by a Conn of the specified netlink family as a packet. It can be
+// attached to a Conn using netlink.Conn.SetObserver.
+//
+// Errors which occur while writing are reported by Err, and stop any further
+// packets from being written.
+func (pw *PcapngWriter) Observer(family netlink.Family) *netlink.Observer {
+	write := func(d Direction) func(msgs []netlink.Message) {
+		return func(msgs []netlink.Message) {
+			_ = pw.WritePacket(family, d, time.Now(), msgs)
+		}
+	}
+
+	return &netlink.Observer{
+		Send:    write(Send),
+		Receive: write(Receive),
+	}
+}
+
+// Err returns the first error which occurred while writing, if any.
+func (pw *PcapngWriter) Err() error {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	return pw.err
+}
+
+// block returns a zeroed buffer for a pcapng block of the specified type with
+// a body of n bytes, padded to a 4 byte boundary, and the block type and
+// lengths populated. block reuses the same buffer on each call, so pw.mu must
+// be held unless the PcapngWriter is not yet shared.
+func (pw *PcapngWriter) block(typ uint32, n int) []byte {
+	l := 4 + 4 + align(n) + 4
+	if cap(pw.buf) < l {
+		pw.buf = make([]byte, l)
+	}
+
+	b := pw.buf[:l]
+	for i := range b {
+		b[i] = 0
+	}
+
+	nlenc.PutUint32(b[0:4], typ)
+	nlenc.PutUint32(b[4:8], uint32(l))
+	nlenc.PutUint32(b[l-4:], uint32(l))
+	return b
+}
+
+// write writes b, recording any error so that later writes also fail.
+func (pw *PcapngWriter) write(b []byte) error {
+	if pw.err != nil {
+		return pw.err
+	}
+
+	_, pw.err = pw.w.Write(b)
+	return pw.err
+}
+
+// align rounds n up to the alignment of both netlink messages and pcapng
+// blocks.
+func align(n int) int {
+	return (n + blockAlign - 1) &^ (blockAlign - 1)
+}
@@ -0,0 +1,189 @@
+package nltrace_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nlenc"
+	"github.com/mdlayher/netlink/nltest"
+	"github.com/mdlayher/netlink/nltrace"
+)
+
+func TestPcapngWriter(t *testing.T) {
+	var buf bytes.Buffer
+	pw, err := nltrace.NewPcapngWriter(&buf)
+	if err != nil {
+		t.Fatalf("failed to create writer: %v", err)
+	}
+
+	now := time.Unix(1, 500)
+	m := netlink.Message{
+		Header: netlink.Header{
+			// Unaligned length, as may be sent by the kernel.
+			Length:   19,
+			Type:     0x10,
+			Flags:    netlink.Request,
+			Sequence: 1,
+			PID:      10,
+		},
+		Data: []byte{0x01, 0x02, 0x03},
+	}
+
+	if err := pw.WriteRecord(netlink.FamilyGeneric, nltrace.Record{
+		Direction: nltrace.Send,
+		Time:      now,
+		Message:   m,
+	}); err != nil {
+		t.Fatalf("failed to write record: %v", err)
+	}
+
+	blocks := parseBlocks(t, buf.Bytes())
+	if len(blocks) != 3 {
+		t.Fatalf("expected 3 blocks, but got: %d", len(blocks))
+	}
+
+	// Section header with native byte-order magic and version 1.0.
+	shb := blocks[0]
+	if diff := cmp.Diff(uint32(0x0a0d0d0a), shb.typ); diff != "" {
+		t.Fatalf("unexpected section header type (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(uint32(0x1a2b3c4d), nlenc.Uint32(shb.body[0:4])); diff != "" {
+		t.Fatalf("unexpected byte-order magic (-want +got):\n%s", diff)
+	}
+
+	// Interface description with LINKTYPE_NETLINK.
+	idb := blocks[1]
+	if idb.typ != 1 || nlenc.Uint16(idb.body[0:2]) != 253 {
+		t.Fatalf("unexpected interface description: %+v", idb)
+	}
+
+	// Enhanced packet with a cooked header and the message padded to 4 bytes.
+	epb := blocks[2]
+	if epb.typ != 6 {
+		t.Fatalf("unexpected enhanced packet type: %d", epb.typ)
+	}
+
+	ts := uint64(nlenc.Uint32(epb.body[4:8]))<<32 | uint64(nlenc.Uint32(epb.body[8:12]))
+	if diff := cmp.Diff(uint64(now.UnixNano()), ts); diff != "" {
+		t.Fatalf("unexpected timestamp (-want +got):\n%s", diff)
+	}
+
+	n := nlenc.Uint32(epb.body[12:16])
+	pkt := epb.body[20 : 20+n]
+
+	sll := pkt[:16]
+	if diff := cmp.Diff([]uint16{4, 824, 0}, []uint16{
+		binary.BigEndian.Uint16(sll[0:2]),
+		binary.BigEndian.Uint16(sll[2:4]),
+		binary.BigEndian.Uint16(sll[4:6]),
+	}); diff != "" {
+		t.Fatalf("unexpected cooked header (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(uint16(netlink.FamilyGeneric), binary.BigEndian.Uint16(sll[14:16])); diff != "" {
+		t.Fatalf("unexpected family (-want +got):\n%s", diff)
+	}
+
+	if !isLittleEndian() {
+		t.Skip("skipping payload check on big endian machine")
+	}
+
+	want := []byte{
+		// Header.
+		0x13, 0x00, 0x00, 0x00,
+		0x10, 0x00,
+		0x01, 0x00,
+		0x01, 0x00, 0x00, 0x00,
+		0x0a, 0x00, 0x00, 0x00,
+		// Data and padding.
+		0x01, 0x02, 0x03, 0x00,
+	}
+
+	if diff := cmp.Diff(want, pkt[16:]); diff != "" {
+		t.Fatalf("unexpected payload (-want +got):\n%s", diff)
+	}
+}
+
+func TestPcapngWriterObserver(t *testing.T) {
+	c := nltest.Dial(func(req []netlink.Message) ([]netlink.Message, error) {
+		return nltest.Multipart(req)
+	})
+	defer c.Close()
+
+	var buf bytes.Buffer
+	pw, err := nltrace.NewPcapngWriter(&buf)
+	if err != nil {
+		t.Fatalf("failed to create writer: %v", err)
+	}
+	c.SetObserver(pw.Observer(netlink.FamilyRoute))
+
+	if _, err := c.Execute(netlink.Message{
+		Header: netlink.Header{Flags: netlink.Request | netlink.Acknowledge},
+	}); err != nil {
+		t.Fatalf("failed to execute: %v", err)
+	}
+	if err := pw.Err(); err != nil {
+		t.Fatalf("failed to write packets: %v", err)
+	}
+
+	// Section header, interface description, and one packet each for the
+	// request and reply datagrams.
+	var dirs []uint16
+	for _, b := range parseBlocks(t, buf.Bytes())[2:] {
+		dirs = append(dirs, binary.BigEndian.Uint16(b.body[20:22]))
+	}
+
+	if diff := cmp.Diff([]uint16{4, 0}, dirs); diff != "" {
+		t.Fatalf("unexpected packet directions (-want +got):\n%s", diff)
+	}
+}
+
+func TestPcapngWriterError(t *testing.T) {
+	if _, err := nltrace.NewPcapngWriter(&failWriter{}); !errors.Is(err, errWrite) {
+		t.Fatalf("expected write error, but got: %v", err)
+	}
+}
+
+var errWrite = errors.New("write failed")
+
+// A failWriter is an io.Writer which always fails.
+type failWriter struct{}
+
+func (*failWriter) Write(_ []byte) (int, error) { return 0, errWrite }
+
+// A block is a parsed pcapng block.
+type block struct {
+	typ  uint32
+	body []byte
+}
+
+// parseBlocks parses a native byte order pcapng capture into blocks.
+func parseBlocks(t *testing.T, b []byte) []block {
+	t.Helper()
+
+	var blocks []block
+	for len(b) > 0 {
+		if len(b) < 12 {
+			t.Fatalf("short block: %d bytes", len(b))
+		}
+
+		l := int(nlenc.Uint32(b[4:8]))
+		if l%4 != 0 || l > len(b) || nlenc.Uint32(b[l-4:l]) != uint32(l) {
+			t.Fatalf("invalid block length: %d", l)
+		}
+
+		blocks = append(blocks, block{typ: nlenc.Uint32(b[0:4]), body: b[8 : l-4]})
+		b = b[l:]
+	}
+
+	return blocks
+}
+
+// isLittleEndian reports whether the machine is little endian.
+func isLittleEndian() bool {
+	return nlenc.Uint16([]byte{0x01, 0x00}) == 1
+}
@@ -0,0 +1,166 @@
+package nltrace
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nlenc"
+)
+
+// A Direction indicates whether a Record was sent to or received from netlink.
+type Direction uint8
+
+// Possible Direction values.
+const (
+	Send Direction = iota + 1
+	Receive
+)
+
+// String returns the string representation of a Direction.
+func (d Direction) String() string {
+	switch d {
+	case Send:
+		return "send"
+	case Receive:
+		return "receive"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(d))
+	}
+}
+
+// A Record is a single netlink Message in a stream, along with the direction
+// and time at which it was sent or received.
+type Record struct {
+	Direction Direction
+	Time      time.Time
+	Message   netlink.Message
+}
+
+// The stream format is a sequence of records, each consisting of:
+//   - a big-endian uint32 length of the remainder of the record
+//   - a uint8 Direction
+//   - a big-endian int64 timestamp in nanoseconds since the Unix epoch
+//   - the netlink message header in native byte order, as sent by the kernel
+//   - the netlink message data, without any trailing padding
+//
+// The header is stored as-is, so Messages with unusual Header.Length values
+// are preserved exactly.
+const (
+	recordHeaderLen = 4 + 1 + 8
+	nlmsgHeaderLen  = 16
+
+	// maxRecordLen guards against allocating huge buffers for a corrupt stream.
+	maxRecordLen = 1 << 24
+)
+
+var errRecordLength = errors.New("nltrace: invalid record length")
+
+// An Encoder writes Records to an output stream.
+type Encoder struct {
+	w   io.Writer
+	buf []byte
+}
+
+// NewEncoder creates an Encoder which writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes r to the stream.
+func (e *Encoder) Encode(r Record) error {
+	n := recordHeaderLen + nlmsgHeaderLen + len(r.Message.Data)
+	if n-4 > maxRecordLen {
+		return errRecordLength
+	}
+
+	if cap(e.buf) < n {
+		e.buf = make([]byte, n)
+	}
+	b := e.buf[:n]
+
+	h := r.Message.Header
+	binary.BigEndian.PutUint32(b[0:4], uint32(n-4))
+	b[4] = byte(r.Direction)
+	binary.BigEndian.PutUint64(b[5:13], uint64(r.Time.UnixNano()))
+
+	mb := b[recordHeaderLen:]
+	nlenc.PutUint32(mb[0:4], h.Length)
+	nlenc.PutUint16(mb[4:6], uint16(h.Type))
+	nlenc.PutUint16(mb[6:8], uint16(h.Flags))
+	nlenc.PutUint32(mb[8:12], h.Sequence)
+	nlenc.PutUint32(mb[12:16], h.PID)
+	copy(mb[nlmsgHeaderLen:], r.Message.Data)
+
+	_, err := e.w.Write(b)
+	return err
+}
+
+// EncodeTransaction writes the request of t as a Send Record at t.Start,
+// followed by each of its replies as Receive Records at t.End.
+func (e *Encoder) EncodeTransaction(t netlink.Transaction) error {
+	if err := e.Encode(Record{Direction: Send, Time: t.Start, Message: t.Request}); err != nil {
+		return err
+	}
+
+	for _, m := range t.Replies {
+		if err := e.Encode(Record{Direction: Receive, Time: t.End, Message: m}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// A Decoder reads Records from an input stream.
+type Decoder struct {
+	r io.Reader
+}
+
+// NewDecoder creates a Decoder which reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// Decode reads the next Record from the stream. Decode returns io.EOF when no
+// more Records are available, or io.ErrUnexpectedEOF if the stream ends in the
+// middle of a Record.
+func (d *Decoder) Decode() (Record, error) {
+	var lb [4]byte
+	if _, err := io.ReadFull(d.r, lb[:]); err != nil {
+		return Record{}, err
+	}
+
+	l := binary.BigEndian.Uint32(lb[:])
+	if l < recordHeaderLen-4+nlmsgHeaderLen || l > maxRecordLen {
+		return Record{}, errRecordLength
+	}
+
+	b := make([]byte, l)
+	if _, err := io.ReadFull(d.r, b); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+
+		return Record{}, err
+	}
+
+	mb := b[recordHeaderLen-4:]
+	return Record{
+		Direction: Direction(b[0]),
+		Time:      time.Unix(0, int64(binary.BigEndian.Uint64(b[1:9]))),
+		Message: netlink.Message{
+			Header: netlink.Header{
+				Length:   nlenc.Uint32(mb[0:4]),
+				Type:     netlink.HeaderType(nlenc.Uint16(mb[4:6])),
+				Flags:    netlink.HeaderFlags(nlenc.Uint16(mb[6:8])),
+				Sequence: nlenc.Uint32(mb[8:12]),
+				PID:      nlenc.Uint32(mb[12:16]),
+			},
+			Data: mb[nlmsgHeaderLen:],
+		},
+	}, nil
+}
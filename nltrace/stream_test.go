@@ -0,0 +1,93 @@
+package nltrace_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nltrace"
+)
+
+func TestStreamRoundTrip(t *testing.T) {
+	var (
+		start = time.Unix(1, 0)
+		end   = start.Add(time.Second)
+	)
+
+	tx := netlink.Transaction{
+		Request: netlink.Message{
+			Header: netlink.Header{
+				Length:   20,
+				Type:     0x10,
+				Flags:    netlink.Request | netlink.Dump,
+				Sequence: 1,
+				PID:      10,
+			},
+			Data: []byte{0x01, 0x02, 0x03, 0x04},
+		},
+		Replies: []netlink.Message{{
+			// Unaligned length, as may be sent by the kernel.
+			Header: netlink.Header{
+				Length:   19,
+				Type:     0x10,
+				Flags:    netlink.Multi,
+				Sequence: 1,
+				PID:      10,
+			},
+			Data: []byte{'a', 'b', 'c'},
+		}},
+		Start: start,
+		End:   end,
+	}
+
+	var buf bytes.Buffer
+	if err := nltrace.NewEncoder(&buf).EncodeTransaction(tx); err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+
+	var got []nltrace.Record
+	d := nltrace.NewDecoder(&buf)
+	for {
+		r, err := d.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to decode: %v", err)
+		}
+
+		got = append(got, r)
+	}
+
+	want := []nltrace.Record{
+		{Direction: nltrace.Send, Time: start, Message: tx.Request},
+		{Direction: nltrace.Receive, Time: end, Message: tx.Replies[0]},
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("unexpected records (-want +got):\n%s", diff)
+	}
+}
+
+func TestStreamTruncated(t *testing.T) {
+	var buf bytes.Buffer
+	err := nltrace.NewEncoder(&buf).Encode(nltrace.Record{
+		Direction: nltrace.Receive,
+		Message: netlink.Message{
+			Header: netlink.Header{Length: 16},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+
+	b := buf.Bytes()
+	_, err = nltrace.NewDecoder(bytes.NewReader(b[:len(b)-1])).Decode()
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected unexpected EOF, but got: %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package netlink
+
+import (
+	"errors"
+	"fmt"
+)
+
+// NetNSIDNotAssigned is the namespace ID reported for a network namespace
+// which has not been assigned an ID, such as by SetNetNSID.
+const NetNSIDNotAssigned = -1
+
+// Route netlink constants from linux/rtnetlink.h and linux/net_namespace.h,
+// defined here as they are used on all platforms.
+const (
+	rtmNewNSID = 88
+	rtmGetNSID = 90
+
+	netnsaNSID = 1
+	netnsaFD   = 3
+
+	// sizeofRtgenmsg is the aligned size of struct rtgenmsg.
+	sizeofRtgenmsg = 4
+)
+
+// NetNSID returns the ID assigned to the network namespace referred to by the
+// file descriptor fd, from the point of view of the network namespace of c,
+// or NetNSIDNotAssigned if no ID has been assigned. c must be a Conn for the
+// Route family.
+//
+// The kernel identifies the source namespace of messages received with the
+// ListenAllNSID ConnOption using these IDs, so NetNSID can be used by
+// applications which monitor multiple network namespaces to map events back
+// to a namespace.
+func NetNSID(c *Conn, fd int) (int32, error) {
+	msgs, err := c.Execute(nsidRequest(rtmGetNSID, Request, fd, nil))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, m := range msgs {
+		if m.Header.Type != rtmNewNSID || len(m.Data) < sizeofRtgenmsg {
+			continue
+		}
+
+		ad, err := NewAttributeDecoder(m.Data[sizeofRtgenmsg:])
+		if err != nil {
+			return 0, err
+		}
+
+		for ad.Next() {
+			if ad.Type() == netnsaNSID {
+				return ad.Int32(), nil
+			}
+		}
+		if err := ad.Err(); err != nil {
+			return 0, err
+		}
+	}
+
+	return 0, errors.New("netlink: no namespace ID in reply")
+}
+
+// SetNetNSID assigns the ID id to the network namespace referred to by the
+// file descriptor fd, from the point of view of the network namespace of c.
+// c must be a Conn for the Route family. The kernel returns an error if the
+// namespace already has an ID, or if id is in use by another namespace.
+//
+// Assigning IDs ahead of time gives applications control over the IDs which
+// tag messages received with the ListenAllNSID ConnOption.
+func SetNetNSID(c *Conn, fd int, id int32) error {
+	if id < 0 {
+		return fmt.Errorf("netlink: invalid namespace ID: %d", id)
+	}
+
+	_, err := c.Execute(nsidRequest(rtmNewNSID, Request|Acknowledge, fd, &id))
+	return err
+}
+
+// nsidRequest produces a route netlink namespace ID request for the namespace
+// referred to by fd, and optionally the ID id.
+func nsidRequest(typ HeaderType, flags HeaderFlags, fd int, id *int32) Message {
+	ae := NewAttributeEncoder()
+	ae.Uint32(netnsaFD, uint32(fd))
+	if id != nil {
+		ae.Int32(netnsaNSID, *id)
+	}
+
+	// The attributes cannot fail to encode.
+	attrs, _ := ae.Encode()
+
+	return Message{
+		Header: Header{Type: typ, Flags: flags},
+		// An rtgenmsg with AF_UNSPEC family, followed by attributes.
+		Data: append(make([]byte, sizeofRtgenmsg), attrs...),
+	}
+}
@@ -0,0 +1,279 @@
+package netlink
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// An Observer is a set of optional callbacks which are invoked as a Conn
+// performs netlink operations, for use in tracing, metrics, and debugging.
+// Any nil callbacks are ignored.
+//
+// Callbacks are invoked synchronously while the operation is in progress, so
+// they should return quickly and must not call methods on the Conn.
+type Observer struct {
+	// Execute is called when a call to Execute or Dump completes, whether or
+	// not it was successful.
+	Execute func(t Transaction)
+
+	// Gap is called when a Conn detects that received messages may have been
+	// lost, indicating that any state derived from them may be stale and
+	// should be refreshed with a new dump.
+	Gap func(g Gap)
+
+	// Sequence optionally extracts a family-specific sequence or generation
+	// number from a received message, reporting false if the message carries
+	// no such number. When set, Gap is called if the numbers of consecutive
+	// messages are not contiguous.
+	Sequence func(m Message) (uint32, bool)
+
+	// Warning is called when a Conn receives a successful acknowledgement
+	// which carries a non-fatal extended acknowledgement message. Warnings
+	// are only sent by the kernel when the ExtendedAcknowledge option is set.
+	Warning func(w Warning)
+
+	// Unusable is called at most once when a Conn detects that it can no
+	// longer be used, such as when its socket is closed or a receive fails
+	// with an error which will recur on every later call. err is the error
+	// which revealed the problem. Supervisors can use Unusable to discard and
+	// re-dial a Conn without inspecting the error from every operation.
+	//
+	// Unusable is not called when the Conn is closed using Close.
+	Unusable func(err error)
+
+	// Send is called with the Messages sent by each successful call to the
+	// Conn's Socket, with all header fields populated, such as by Send,
+	// SendMessages, and Execute.
+	Send func(msgs []Message)
+
+	// Receive is called with the Messages in each datagram received by the
+	// Conn, including messages which are not returned to the caller, such as
+	// the final "multi-part done" message and messages removed by
+	// SetTypeFilter. Together with Send, Receive allows applications to trace
+	// netlink traffic using their own logging or metrics, rather than the
+	// NLDEBUG environment variable.
+	Receive func(msgs []Message)
+
+	// Error is called with the error returned by each failed send or receive
+	// operation, which is typically an *OpError.
+	Error func(err error)
+}
+
+// A Warning is a non-fatal extended acknowledgement message sent by the
+// kernel along with a successful acknowledgement, such as a notice that a
+// request used a deprecated attribute.
+type Warning struct {
+	// Message and Offset are the extended acknowledgement message and the
+	// offset of the offending attribute in the request, if provided.
+	Message string
+	Offset  int
+
+	// Ack is the acknowledgement message which carried the Warning.
+	Ack Message
+}
+
+// A GapReason describes why a Gap was detected.
+type GapReason int
+
+// Possible GapReason values.
+const (
+	// GapOverrun indicates that the socket receive buffer overflowed and the
+	// kernel discarded messages (ENOBUFS).
+	GapOverrun GapReason = iota + 1
+
+	// GapDumpInterrupted indicates that the kernel state changed during a
+	// dump, so the dump may be inconsistent.
+	GapDumpInterrupted
+
+	// GapSequence indicates that the numbers produced by Observer.Sequence
+	// for consecutive messages were not contiguous.
+	GapSequence
+)
+
+// String returns the string representation of a GapReason.
+func (r GapReason) String() string {
+	switch r {
+	case GapOverrun:
+		return "overrun"
+	case GapDumpInterrupted:
+		return "dumpinterrupted"
+	case GapSequence:
+		return "sequence"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(r))
+	}
+}
+
+// A Gap describes a potential loss of messages detected by a Conn.
+type Gap struct {
+	// Reason indicates why the Gap was detected.
+	Reason GapReason
+
+	// Message is the message which revealed the Gap. It is empty for
+	// GapOverrun.
+	Message Message
+
+	// Want and Got are the expected and received numbers produced by
+	// Observer.Sequence for GapSequence. They are zero otherwise.
+	Want, Got uint32
+}
+
+// A gapState tracks the last number produced by Observer.Sequence.
+type gapState struct {
+	mu  sync.Mutex
+	seq uint32
+	ok  bool
+}
+
+// A Transaction describes a single request/reply exchange performed by
+// Conn.Execute.
+type Transaction struct {
+	// Request is the request Message, with any header fields populated by
+	// Send.
+	Request Message
+
+	// Replies are the reply Messages returned by Execute, if any. Replies is
+	// always empty for Dump, which does not retain its replies.
+	Replies []Message
+
+	// Parts is the number of reply Messages received, excluding the final
+	// "multi-part done" message, and Bytes is their total length in bytes,
+	// including headers. Parts and Bytes are also reported for Dump, and
+	// include replies which were discarded due to Config.MaxReplies or
+	// Config.MaxReplyBytes. Datagrams is the number of datagrams received.
+	//
+	// If Config.DumpRetries is set, only the final attempt is counted.
+	Parts, Bytes, Datagrams int
+
+	// Err is the error returned by Execute, if any.
+	Err error
+
+	// Start and End are the times at which Execute began and completed.
+	Start, End time.Time
+}
+
+// Duration returns the time elapsed between the start and end of the
+// Transaction.
+func (t Transaction) Duration() time.Duration { return t.End.Sub(t.Start) }
+
+// SetObserver sets an Observer which will be notified of operations performed
+// by the Conn, replacing any previously set Observer. If o is nil, the
+// current Observer is removed.
+func (c *Conn) SetObserver(o *Observer) {
+	c.gaps.mu.Lock()
+	defer c.gaps.mu.Unlock()
+
+	c.gaps.ok = false
+	c.obs.Store(o)
+}
+
+// observe executes fn with the Observer if one is set.
+func (c *Conn) observe(fn func(o *Observer)) {
+	o, _ := c.obs.Load().(*Observer)
+	if o == nil {
+		return
+	}
+
+	fn(o)
+}
+
+// observeSend reports msgs, which were sent successfully, to the Observer.
+func (c *Conn) observeSend(msgs []Message) {
+	c.observe(func(o *Observer) {
+		if o.Send != nil {
+			o.Send(msgs)
+		}
+	})
+}
+
+// observeReceive reports msgs, which were received in a single datagram, to
+// the Observer.
+func (c *Conn) observeReceive(msgs []Message) {
+	c.observe(func(o *Observer) {
+		if o.Receive != nil {
+			o.Receive(msgs)
+		}
+	})
+}
+
+// observeError reports err, returned by a send or receive operation, to the
+// Observer.
+func (c *Conn) observeError(err error) {
+	c.observe(func(o *Observer) {
+		if o.Error != nil {
+			o.Error(err)
+		}
+	})
+}
+
+// detectGaps reports any Gaps revealed by received messages msgs or the
+// receive error err to the Observer.
+func (c *Conn) detectGaps(msgs []Message, err error) {
+	c.observe(func(o *Observer) {
+		if o.Gap == nil {
+			return
+		}
+
+		if err != nil {
+			if isOverrun(err) {
+				o.Gap(Gap{Reason: GapOverrun})
+
+				// The next number is unknowable after messages are dropped.
+				c.gaps.mu.Lock()
+				c.gaps.ok = false
+				c.gaps.mu.Unlock()
+			}
+
+			return
+		}
+
+		for _, m := range msgs {
+			if m.Header.Flags&DumpInterrupted != 0 {
+				o.Gap(Gap{Reason: GapDumpInterrupted, Message: m})
+			}
+
+			if o.Sequence == nil {
+				continue
+			}
+
+			seq, ok := o.Sequence(m)
+			if !ok {
+				continue
+			}
+
+			c.gaps.mu.Lock()
+			want, check := c.gaps.seq+1, c.gaps.ok
+			c.gaps.seq, c.gaps.ok = seq, true
+			c.gaps.mu.Unlock()
+
+			if check && seq != want {
+				o.Gap(Gap{Reason: GapSequence, Message: m, Want: want, Got: seq})
+			}
+		}
+	})
+}
+
+// checkUnusable reports to the Observer if err indicates that the Conn can no
+// longer be used, unless the Conn was closed deliberately.
+func (c *Conn) checkUnusable(err error) {
+	if err == nil || !isUnusable(err) || atomic.LoadUint32(&c.closed) != 0 {
+		return
+	}
+
+	// Only report the first such error.
+	if !atomic.CompareAndSwapUint32(&c.unusable, 0, 1) {
+		return
+	}
+
+	c.debug(func(d *debugger) {
+		d.debugf(1, "conn: unusable: %v", err)
+	})
+
+	c.observe(func(o *Observer) {
+		if o.Unusable != nil {
+			o.Unusable(err)
+		}
+	})
+}
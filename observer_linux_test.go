@@ -0,0 +1,144 @@
+//go:build linux
+// +build linux
+
+package netlink_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nltest"
+	"golang.org/x/sys/unix"
+)
+
+func TestConnObserverGapOverrun(t *testing.T) {
+	c := nltest.Dial(func(_ []netlink.Message) ([]netlink.Message, error) {
+		return nil, unix.ENOBUFS
+	})
+	defer c.Close()
+
+	var gaps []netlink.Gap
+	c.SetObserver(&netlink.Observer{
+		Gap: func(g netlink.Gap) { gaps = append(gaps, g) },
+	})
+
+	if _, err := c.Receive(); err == nil {
+		t.Fatal("expected an error, but none occurred")
+	}
+
+	if diff := cmp.Diff([]netlink.Gap{{Reason: netlink.GapOverrun}}, gaps); diff != "" {
+		t.Fatalf("unexpected gaps (-want +got):\n%s", diff)
+	}
+
+	if diff := cmp.Diff(uint64(1), c.Stats().Overruns); diff != "" {
+		t.Fatalf("unexpected overruns (-want +got):\n%s", diff)
+	}
+}
+
+func TestConnObserverWarning(t *testing.T) {
+	skipBigEndian(t)
+
+	c := nltest.Dial(func(req []netlink.Message) ([]netlink.Message, error) {
+		return warningAck(req[0], "deprecated")
+	})
+	defer c.Close()
+
+	var warnings []string
+	c.SetObserver(&netlink.Observer{
+		Warning: func(w netlink.Warning) { warnings = append(warnings, w.Message) },
+	})
+
+	req := netlink.Message{
+		Header: netlink.Header{Flags: netlink.Request | netlink.Acknowledge},
+		Data:   []byte{0xff, 0xff, 0xff, 0xff},
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.Execute(req); err != nil {
+			t.Fatalf("failed to execute: %v", err)
+		}
+	}
+
+	if diff := cmp.Diff([]string{"deprecated", "deprecated"}, warnings); diff != "" {
+		t.Fatalf("unexpected warnings (-want +got):\n%s", diff)
+	}
+
+	want := netlink.Stats{
+		MessagesSent:     2,
+		BytesSent:        2 * 20,
+		MessagesReceived: 2,
+		BytesReceived:    2 * 52,
+		Warnings:         2,
+	}
+
+	if diff := cmp.Diff(want, c.Stats()); diff != "" {
+		t.Fatalf("unexpected stats (-want +got):\n%s", diff)
+	}
+}
+
+func TestConnOnOverrun(t *testing.T) {
+	var n int
+	restore := nltest.Intercept(func(_ []netlink.Message) ([]netlink.Message, error) {
+		// Report an overrun before delivering the next event.
+		n++
+		if n == 1 {
+			return nil, unix.ENOBUFS
+		}
+
+		return []netlink.Message{{Data: []byte{0xff}}}, nil
+	})
+	defer restore()
+
+	var overruns int
+	c, err := netlink.Dial(0, &netlink.Config{
+		OnOverrun: func() { overruns++ },
+	})
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer c.Close()
+
+	msgs, err := c.Receive()
+	if err != nil {
+		t.Fatalf("failed to receive: %v", err)
+	}
+
+	if diff := cmp.Diff([]byte{0xff}, msgs[0].Data); diff != "" {
+		t.Fatalf("unexpected message data (-want +got):\n%s", diff)
+	}
+	if overruns != 1 || c.Stats().Overruns != 1 {
+		t.Fatalf("expected 1 overrun, but got: %d callbacks, %d counted", overruns, c.Stats().Overruns)
+	}
+}
+
+// warningAck returns a successful capped acknowledgement of req carrying
+// warning.
+func warningAck(req netlink.Message, warning string) ([]netlink.Message, error) {
+	return cappedAck(req, []netlink.Attribute{{
+		Type: 1,
+		Data: []byte(warning + "\x00"),
+	}})
+}
+
+// cappedAck returns a successful capped acknowledgement of req: errno 0, the
+// request header, and the extended acknowledgement TLVs attrs.
+func cappedAck(req netlink.Message, attrs []netlink.Attribute) ([]netlink.Message, error) {
+	hb, err := req.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	data := append([]byte{0x00, 0x00, 0x00, 0x00}, hb[:16]...)
+	data = append(data, nltest.MustMarshalAttributes(attrs)...)
+
+	return []netlink.Message{{
+		Header: netlink.Header{
+			Type:     netlink.Error,
+			Flags:    netlink.AcknowledgeTLVs | netlink.Capped,
+			Sequence: req.Header.Sequence,
+			PID:      req.Header.PID,
+		},
+		Data: data,
+	}}, nil
+}
@@ -0,0 +1,244 @@
+package netlink_test
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nltest"
+)
+
+func TestConnObserverGap(t *testing.T) {
+	// Each message carries a big-endian family-specific sequence number.
+	msg := func(seq uint32, flags netlink.HeaderFlags) netlink.Message {
+		b := make([]byte, 4)
+		binary.BigEndian.PutUint32(b, seq)
+
+		return netlink.Message{
+			Header: netlink.Header{Flags: flags},
+			Data:   b,
+		}
+	}
+
+	msgs := []netlink.Message{
+		msg(1, 0),
+		msg(2, 0),
+		msg(4, netlink.DumpInterrupted),
+		// No sequence number.
+		{},
+		msg(5, 0),
+	}
+
+	c := nltest.Dial(func(_ []netlink.Message) ([]netlink.Message, error) {
+		return msgs, nil
+	})
+	defer c.Close()
+
+	var gaps []netlink.Gap
+	c.SetObserver(&netlink.Observer{
+		Gap: func(g netlink.Gap) { gaps = append(gaps, g) },
+		Sequence: func(m netlink.Message) (uint32, bool) {
+			if len(m.Data) != 4 {
+				return 0, false
+			}
+
+			return binary.BigEndian.Uint32(m.Data), true
+		},
+	})
+
+	if _, err := c.Receive(); err != nil {
+		t.Fatalf("failed to receive: %v", err)
+	}
+
+	want := []netlink.Gap{
+		{
+			Reason:  netlink.GapDumpInterrupted,
+			Message: msgs[2],
+		},
+		{
+			Reason:  netlink.GapSequence,
+			Message: msgs[2],
+			Want:    3,
+			Got:     4,
+		},
+	}
+
+	if diff := cmp.Diff(want, gaps); diff != "" {
+		t.Fatalf("unexpected gaps (-want +got):\n%s", diff)
+	}
+}
+
+func TestConnObserverUnusable(t *testing.T) {
+	c := nltest.Dial(func(_ []netlink.Message) ([]netlink.Message, error) {
+		return nil, os.ErrClosed
+	})
+	defer c.Close()
+
+	var errs []error
+	c.SetObserver(&netlink.Observer{
+		Unusable: func(err error) { errs = append(errs, err) },
+	})
+
+	// Only the first fatal error is reported.
+	for i := 0; i < 2; i++ {
+		if _, err := c.Receive(); !errors.Is(err, os.ErrClosed) {
+			t.Fatalf("expected closed error, but got: %v", err)
+		}
+	}
+
+	if diff := cmp.Diff([]error{os.ErrClosed}, errs, cmpopts.EquateErrors()); diff != "" {
+		t.Fatalf("unexpected unusable errors (-want +got):\n%s", diff)
+	}
+}
+
+func TestConnObserverUnusableClose(t *testing.T) {
+	c := nltest.Dial(func(_ []netlink.Message) ([]netlink.Message, error) {
+		return nil, os.ErrClosed
+	})
+
+	c.SetObserver(&netlink.Observer{
+		Unusable: func(err error) {
+			t.Fatalf("unexpected unusable callback after Close: %v", err)
+		},
+	})
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	if _, err := c.Receive(); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("expected closed error, but got: %v", err)
+	}
+}
+
+func TestConnObserverSendReceive(t *testing.T) {
+	c := nltest.Dial(func(req []netlink.Message) ([]netlink.Message, error) {
+		h := reply(req[0])
+		return nltest.Multipart([]netlink.Message{
+			{Header: h, Data: []byte{0x01}},
+			{Header: h, Data: []byte{0x02}},
+			{Header: netlink.Header{Type: netlink.Done, Sequence: h.Sequence, PID: h.PID}},
+		})
+	})
+	defer c.Close()
+
+	var sent, received [][]netlink.Message
+	c.SetObserver(&netlink.Observer{
+		Send:    func(msgs []netlink.Message) { sent = append(sent, msgs) },
+		Receive: func(msgs []netlink.Message) { received = append(received, msgs) },
+		Error:   func(err error) { t.Fatalf("unexpected error callback: %v", err) },
+	})
+
+	req, err := c.Send(netlink.Message{
+		Header: netlink.Header{Type: 0x10, Flags: netlink.Request | netlink.Dump},
+	})
+	if err != nil {
+		t.Fatalf("failed to send: %v", err)
+	}
+
+	res, err := c.Receive()
+	if err != nil {
+		t.Fatalf("failed to receive: %v", err)
+	}
+
+	if diff := cmp.Diff([][]netlink.Message{{req}}, sent); diff != "" {
+		t.Fatalf("unexpected sent messages (-want +got):\n%s", diff)
+	}
+
+	// Each datagram is reported, including the final "multi-part done"
+	// message which is not returned by Receive.
+	if len(received) != 2 {
+		t.Fatalf("expected 2 datagrams, but got: %d", len(received))
+	}
+	if diff := cmp.Diff(res, received[0]); diff != "" {
+		t.Fatalf("unexpected received messages (-want +got):\n%s", diff)
+	}
+	if typ := received[1][0].Header.Type; typ != netlink.Done {
+		t.Fatalf("expected done message, but got type: %s", typ)
+	}
+}
+
+func TestConnObserverError(t *testing.T) {
+	errReceive := errors.New("receive failed")
+	c := nltest.Dial(func(_ []netlink.Message) ([]netlink.Message, error) {
+		return nil, errReceive
+	})
+	defer c.Close()
+
+	var errs []error
+	c.SetObserver(&netlink.Observer{
+		Error: func(err error) { errs = append(errs, err) },
+	})
+
+	if _, err := c.Receive(); !errors.Is(err, errReceive) {
+		t.Fatalf("expected receive error, but got: %v", err)
+	}
+
+	if len(errs) != 1 || !errors.Is(errs[0], errReceive) {
+		t.Fatalf("unexpected error callbacks: %v", errs)
+	}
+
+	var oerr *netlink.OpError
+	if !errors.As(errs[0], &oerr) || oerr.Op != "receive" {
+		t.Fatalf("expected receive OpError, but got: %#v", errs[0])
+	}
+}
+
+func TestConnObserverExecuteSummary(t *testing.T) {
+	// nltest returns the final "multi-part done" message in a second
+	// datagram.
+	c := nltest.Dial(func(req []netlink.Message) ([]netlink.Message, error) {
+		h := reply(req[0])
+		h.Flags = netlink.Multi
+
+		done := h
+		done.Type = netlink.Done
+
+		return []netlink.Message{
+			{Header: h, Data: []byte{0x01, 0x02, 0x03, 0x04}},
+			{Header: h, Data: []byte{0x05}},
+			{Header: done, Data: make([]byte, 4)},
+		}, nil
+	})
+	defer c.Close()
+
+	var txs []netlink.Transaction
+	c.SetObserver(&netlink.Observer{
+		Execute: func(t netlink.Transaction) { txs = append(txs, t) },
+	})
+
+	req := netlink.Message{Header: netlink.Header{Flags: netlink.Request | netlink.Dump}}
+	if _, err := c.Execute(req); err != nil {
+		t.Fatalf("failed to execute: %v", err)
+	}
+	err := c.Dump(context.Background(), req, func(_ netlink.Message) error { return nil })
+	if err != nil {
+		t.Fatalf("failed to dump: %v", err)
+	}
+
+	if diff := cmp.Diff(2, len(txs)); diff != "" {
+		t.Fatalf("unexpected number of transactions (-want +got):\n%s", diff)
+	}
+
+	for i, tx := range txs {
+		// Two replies of 20 and 17 bytes, excluding the final "multi-part
+		// done" message.
+		got := [3]int{tx.Parts, tx.Bytes, tx.Datagrams}
+		if diff := cmp.Diff([3]int{2, 37, 2}, got); diff != "" {
+			t.Fatalf("unexpected summary %d (-want +got):\n%s", i, diff)
+		}
+		if tx.Duration() < 0 {
+			t.Fatalf("negative duration %d: %v", i, tx.Duration())
+		}
+	}
+
+	// Replies are only retained by Execute.
+	if diff := cmp.Diff([]int{2, 0}, []int{len(txs[0].Replies), len(txs[1].Replies)}); diff != "" {
+		t.Fatalf("unexpected number of replies (-want +got):\n%s", diff)
+	}
+}
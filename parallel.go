@@ -0,0 +1,80 @@
+package netlink
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// parallelBatch is the number of consecutive Messages handed to a worker at
+// once by DecodeMessages, which amortizes coordination costs for the many
+// small messages typical of large dumps.
+const parallelBatch = 64
+
+// DecodeMessages calls fn for each of msgs using up to workers goroutines, so
+// CPU-bound decoding of the results of a very large dump, such as a full
+// routing table, can make use of multiple CPUs. If workers is less than 1,
+// runtime.GOMAXPROCS workers are used.
+//
+// fn is called with the index of each Message in msgs, so results can be
+// stored in a slice of the same length to preserve the order of msgs. fn must
+// be safe for concurrent use.
+//
+// If fn returns an error, DecodeMessages stops calling fn as soon as possible
+// and returns the error with the lowest index among those which occurred.
+func DecodeMessages(msgs []Message, workers int, fn func(i int, m Message) error) error {
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if batches := (len(msgs) + parallelBatch - 1) / parallelBatch; workers > batches {
+		workers = batches
+	}
+
+	var (
+		next   int64
+		failed uint32
+
+		mu     sync.Mutex
+		errIdx = len(msgs)
+		err    error
+
+		wg sync.WaitGroup
+	)
+
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+
+			for atomic.LoadUint32(&failed) == 0 {
+				end := int(atomic.AddInt64(&next, parallelBatch))
+				start := end - parallelBatch
+				if start >= len(msgs) {
+					return
+				}
+				if end > len(msgs) {
+					end = len(msgs)
+				}
+
+				for i := start; i < end; i++ {
+					ferr := fn(i, msgs[i])
+					if ferr == nil {
+						continue
+					}
+
+					mu.Lock()
+					if i < errIdx {
+						errIdx, err = i, ferr
+					}
+					mu.Unlock()
+
+					atomic.StoreUint32(&failed, 1)
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	return err
+}
@@ -0,0 +1,64 @@
+package netlink_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nlenc"
+)
+
+func TestDecodeMessages(t *testing.T) {
+	const n = 1000
+
+	msgs := make([]netlink.Message, n)
+	want := make([]uint32, n)
+	for i := range msgs {
+		msgs[i].Data = nlenc.Uint32Bytes(uint32(i))
+		want[i] = uint32(i)
+	}
+
+	for _, workers := range []int{0, 1, 3, 100} {
+		t.Run(fmt.Sprintf("workers %d", workers), func(t *testing.T) {
+			got := make([]uint32, len(msgs))
+			err := netlink.DecodeMessages(msgs, workers, func(i int, m netlink.Message) error {
+				got[i] = nlenc.Uint32(m.Data)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("failed to decode messages: %v", err)
+			}
+
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Fatalf("unexpected decoded values (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDecodeMessagesError(t *testing.T) {
+	msgs := make([]netlink.Message, 1000)
+
+	errBad := errors.New("bad message")
+	err := netlink.DecodeMessages(msgs, 4, func(i int, _ netlink.Message) error {
+		if i == 500 {
+			return errBad
+		}
+
+		return nil
+	})
+	if !errors.Is(err, errBad) {
+		t.Fatalf("expected bad message error, but got: %v", err)
+	}
+}
+
+func TestDecodeMessagesEmpty(t *testing.T) {
+	err := netlink.DecodeMessages(nil, 0, func(_ int, _ netlink.Message) error {
+		panic("should not be called")
+	})
+	if err != nil {
+		t.Fatalf("failed to decode no messages: %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package netlink
+
+import "fmt"
+
+// A Profile is a curated combination of a Family and Config which encapsulates
+// best practices for a common netlink use case. Profiles are used with
+// DialProfile, or may be used as a starting point for a custom Config.
+type Profile int
+
+// Possible Profile values.
+const (
+	// ProfileGenericRequest is a Profile for request/response interactions
+	// with generic netlink families. It enables Config.Strict for extended
+	// acknowledgements and strict request checking.
+	ProfileGenericRequest Profile = iota
+
+	// ProfileRouteRequest is a Profile for request/response interactions with
+	// route netlink. It enables Config.Strict for extended acknowledgements
+	// and strict request checking, which route netlink dumps rely upon to
+	// apply filters.
+	ProfileRouteRequest
+
+	// ProfileRouteMonitor is a Profile for receiving route netlink multicast
+	// notifications about changes to links, addresses, routes, and neighbors.
+	// It enables Config.Strict and sets a large Config.ReadBuffer to reduce the
+	// likelihood of ENOBUFS errors during bursts of events.
+	ProfileRouteMonitor
+)
+
+// Multicast groups and buffer sizes used by Profiles. The group values are
+// equivalent to the Linux RTMGRP_* constants.
+const (
+	rtmgrpLink       = 0x1
+	rtmgrpNeigh      = 0x4
+	rtmgrpIPv4IfAddr = 0x10
+	rtmgrpIPv4Route  = 0x40
+	rtmgrpIPv6IfAddr = 0x100
+	rtmgrpIPv6Route  = 0x400
+
+	monitorReadBuffer = 4 << 20
+)
+
+// String returns the string representation of a Profile.
+func (p Profile) String() string {
+	switch p {
+	case ProfileGenericRequest:
+		return "generic-request"
+	case ProfileRouteRequest:
+		return "route-request"
+	case ProfileRouteMonitor:
+		return "route-monitor"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(p))
+	}
+}
+
+// Family returns the Family used by a Profile, or an error if the Profile is
+// unknown.
+func (p Profile) Family() (Family, error) {
+	switch p {
+	case ProfileGenericRequest:
+		return FamilyGeneric, nil
+	case ProfileRouteRequest, ProfileRouteMonitor:
+		return FamilyRoute, nil
+	default:
+		return 0, fmt.Errorf("netlink: unknown profile %d", int(p))
+	}
+}
+
+// Config returns a newly allocated Config for a Profile. The Config may be
+// modified by the caller before it is passed to Dial.
+func (p Profile) Config() *Config {
+	switch p {
+	case ProfileRouteMonitor:
+		return &Config{
+			Groups: rtmgrpLink | rtmgrpNeigh |
+				rtmgrpIPv4IfAddr | rtmgrpIPv4Route |
+				rtmgrpIPv6IfAddr | rtmgrpIPv6Route,
+			Strict:     true,
+			ReadBuffer: monitorReadBuffer,
+		}
+	default:
+		return &Config{Strict: true}
+	}
+}
+
+// DialProfile dials a connection to netlink using the Family and Config
+// specified by Profile p.
+func DialProfile(p Profile) (*Conn, error) {
+	family, err := p.Family()
+	if err != nil {
+		return nil, err
+	}
+
+	return Dial(int(family), p.Config())
+}
@@ -0,0 +1,72 @@
+package netlink
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestProfile(t *testing.T) {
+	tests := []struct {
+		p      Profile
+		s      string
+		family Family
+		config *Config
+	}{
+		{
+			p:      ProfileGenericRequest,
+			s:      "generic-request",
+			family: FamilyGeneric,
+			config: &Config{Strict: true},
+		},
+		{
+			p:      ProfileRouteRequest,
+			s:      "route-request",
+			family: FamilyRoute,
+			config: &Config{Strict: true},
+		},
+		{
+			p:      ProfileRouteMonitor,
+			s:      "route-monitor",
+			family: FamilyRoute,
+			config: &Config{
+				Groups:     0x555,
+				Strict:     true,
+				ReadBuffer: 4 << 20,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			if diff := cmp.Diff(tt.s, tt.p.String()); diff != "" {
+				t.Fatalf("unexpected string (-want +got):\n%s", diff)
+			}
+
+			family, err := tt.p.Family()
+			if err != nil {
+				t.Fatalf("failed to get family: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.family, family); diff != "" {
+				t.Fatalf("unexpected family (-want +got):\n%s", diff)
+			}
+
+			if diff := cmp.Diff(tt.config, tt.p.Config()); diff != "" {
+				t.Fatalf("unexpected config (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestProfileUnknown(t *testing.T) {
+	if _, err := Profile(100).Family(); err == nil {
+		t.Fatal("expected an error, but none occurred")
+	}
+}
+
+func TestDialProfileUnknown(t *testing.T) {
+	if _, err := DialProfile(100); err == nil {
+		t.Fatal("expected an error, but none occurred")
+	}
+}
@@ -0,0 +1,166 @@
+package netlink
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mdlayher/netlink/nlenc"
+)
+
+// A Result holds the replies to a request sent by Conn.ExecuteResult. The
+// attributes of each reply are only parsed when requested using Attributes,
+// so callers which only check whether a request succeeded do not pay the cost
+// of decoding large replies, such as objects echoed by the Echo flag.
+type Result struct {
+	msgs      []Message
+	headerLen int
+}
+
+// ExecuteResult is like Execute, but returns the replies as a Result.
+// headerLen is the length in bytes of the family-specific header which
+// precedes the attributes in the data of each reply, such as 4 for generic
+// netlink's genlmsghdr, and is skipped by Result.Attributes.
+func (c *Conn) ExecuteResult(m Message, headerLen int) (*Result, error) {
+	if headerLen < 0 {
+		return nil, fmt.Errorf("netlink: invalid result header length: %d", headerLen)
+	}
+
+	res, err := c.observeExecute(context.Background(), m, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{msgs: res, headerLen: headerLen}, nil
+}
+
+// An Acknowledgement describes the successful acknowledgement which completed
+// a request, as returned by Conn.ExecuteAck.
+type Acknowledgement struct {
+	// Header is the header of the acknowledgement message itself, which can
+	// be used to verify that the kernel acknowledged the expected sequence.
+	Header Header
+
+	// Request is the header of the request, as echoed by the kernel, or a
+	// zero Header if the acknowledgement did not include it.
+	Request Header
+
+	// Message and Offset contain a non-fatal extended acknowledgement
+	// warning, if any. See Warning for details.
+	Message string
+	Offset  int
+
+	// Cookie is an opaque value which some subsystems attach to a successful
+	// acknowledgement, such as an identifier for a newly created object, or
+	// nil if none was provided. Cookie is only sent by the kernel when the
+	// ExtendedAcknowledge option is set.
+	Cookie []byte
+}
+
+// ExecuteAck is like Execute, but for requests sent with the Acknowledge
+// flag, it returns the acknowledgement which completed the request as an
+// Acknowledgement rather than as the final reply. The returned replies never
+// include the acknowledgement message. If m does not set the Acknowledge
+// flag, the returned Acknowledgement is nil.
+//
+// Errors carried by acknowledgement messages are returned as an *OpError, as
+// with Execute.
+func (c *Conn) ExecuteAck(m Message) ([]Message, *Acknowledgement, error) {
+	res, err := c.observeExecute(context.Background(), m, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	i := ackIndex(res)
+	if i < 0 {
+		return res, nil, nil
+	}
+
+	ack := res[i]
+	a, _, _ := parseAck(ack)
+	out := &Acknowledgement{
+		Header:  ack.Header,
+		Message: a.Message,
+		Offset:  a.Offset,
+		Cookie:  a.Cookie,
+	}
+	if b := ack.Data; len(b) >= 4+nlmsgHeaderLen {
+		// The errno is followed by the header of the request.
+		out.Request = Header{
+			Length:   nlenc.Uint32(b[4:8]),
+			Type:     HeaderType(nlenc.Uint16(b[8:10])),
+			Flags:    HeaderFlags(nlenc.Uint16(b[10:12])),
+			Sequence: nlenc.Uint32(b[12:16]),
+			PID:      nlenc.Uint32(b[16:20]),
+		}
+	}
+
+	return append(res[:i:i], res[i+1:]...), out, nil
+}
+
+// Messages returns all of the replies in the Result, including any
+// acknowledgement.
+func (r *Result) Messages() []Message { return r.msgs }
+
+// Len returns the number of replies in the Result.
+func (r *Result) Len() int { return len(r.msgs) }
+
+// Attributes returns an AttributeDecoder for the attributes of the reply at
+// index i, following the family-specific header passed to ExecuteResult. Each
+// call returns a new AttributeDecoder, so the attributes of a reply may be
+// decoded any number of times.
+func (r *Result) Attributes(i int) (*AttributeDecoder, error) {
+	if i < 0 || i >= len(r.msgs) {
+		return nil, fmt.Errorf("netlink: result index %d out of range [0, %d)", i, len(r.msgs))
+	}
+
+	m := r.msgs[i]
+	if _, ok, _ := parseAck(m); ok {
+		return nil, fmt.Errorf("netlink: result %d is an acknowledgement and has no attributes", i)
+	}
+	if len(m.Data) < r.headerLen {
+		return nil, fmt.Errorf("netlink: result %d is too short for a %d byte header: %d bytes",
+			i, r.headerLen, len(m.Data))
+	}
+
+	return NewAttributeDecoder(m.Data[r.headerLen:])
+}
+
+// Ack returns the acknowledgement which completed the request and true, or
+// false if the request did not set the Acknowledge flag. Errors are reported
+// by ExecuteResult rather than by Ack.
+func (r *Result) Ack() (Message, bool) {
+	i := ackIndex(r.msgs)
+	if i < 0 {
+		return Message{}, false
+	}
+
+	return r.msgs[i], true
+}
+
+// ackIndex returns the index of the successful acknowledgement in msgs, or -1
+// if there is none.
+func ackIndex(msgs []Message) int {
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if a, ok, _ := parseAck(msgs[i]); ok && a.Errno == 0 && msgs[i].Header.Type == Error {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Warnings returns any non-fatal extended acknowledgement messages carried by
+// successful acknowledgements in the Result, such as a notice that the
+// request used a deprecated attribute. Warnings are only sent by the kernel
+// when the ExtendedAcknowledge option is set. To observe warnings for every
+// request made by a Conn, use Observer.Warning.
+func (r *Result) Warnings() []Warning {
+	var ws []Warning
+	for _, m := range r.msgs {
+		if w, ok := parseWarning(m); ok {
+			ws = append(ws, w)
+		}
+	}
+
+	return ws
+}
@@ -0,0 +1,117 @@
+//go:build linux
+// +build linux
+
+package netlink_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nltest"
+)
+
+func TestResultWarnings(t *testing.T) {
+	skipBigEndian(t)
+
+	c := nltest.Dial(func(req []netlink.Message) ([]netlink.Message, error) {
+		return warningAck(req[0], "deprecated")
+	})
+	defer c.Close()
+
+	r, err := c.ExecuteResult(netlink.Message{
+		Header: netlink.Header{Flags: netlink.Request | netlink.Acknowledge},
+		Data:   []byte{0xff, 0xff, 0xff, 0xff},
+	}, 0)
+	if err != nil {
+		t.Fatalf("failed to execute: %v", err)
+	}
+
+	ack, ok := r.Ack()
+	if !ok {
+		t.Fatal("expected an acknowledgement, but none was found")
+	}
+
+	want := []netlink.Warning{{Message: "deprecated", Ack: ack}}
+	if diff := cmp.Diff(want, r.Warnings()); diff != "" {
+		t.Fatalf("unexpected warnings (-want +got):\n%s", diff)
+	}
+}
+
+func TestResultNoWarnings(t *testing.T) {
+	c := nltest.Dial(func(req []netlink.Message) ([]netlink.Message, error) {
+		return nltest.Error(0, req)
+	})
+	defer c.Close()
+
+	r, err := c.ExecuteResult(netlink.Message{
+		Header: netlink.Header{Flags: netlink.Request | netlink.Acknowledge},
+	}, 0)
+	if err != nil {
+		t.Fatalf("failed to execute: %v", err)
+	}
+
+	if diff := cmp.Diff(0, len(r.Warnings())); diff != "" {
+		t.Fatalf("unexpected number of warnings (-want +got):\n%s", diff)
+	}
+}
+
+func TestConnExecuteAck(t *testing.T) {
+	skipBigEndian(t)
+
+	cookie := []byte{0xde, 0xad, 0xbe, 0xef}
+	c := nltest.Dial(func(req []netlink.Message) ([]netlink.Message, error) {
+		ack, err := cappedAck(req[0], []netlink.Attribute{{
+			Type: 3, // NLMSGERR_ATTR_COOKIE
+			Data: cookie,
+		}})
+		if err != nil {
+			return nil, err
+		}
+
+		return append([]netlink.Message{{Header: reply(req[0])}}, ack...), nil
+	})
+	defer c.Close()
+
+	req := netlink.Message{
+		Header: netlink.Header{
+			Type:  16,
+			Flags: netlink.Request | netlink.Acknowledge,
+		},
+		Data: []byte{0xff, 0xff, 0xff, 0xff},
+	}
+
+	msgs, ack, err := c.ExecuteAck(req)
+	if err != nil {
+		t.Fatalf("failed to execute: %v", err)
+	}
+
+	if diff := cmp.Diff(1, len(msgs)); diff != "" {
+		t.Fatalf("unexpected number of replies (-want +got):\n%s", diff)
+	}
+	if ack == nil {
+		t.Fatal("expected an acknowledgement, but none was returned")
+	}
+
+	want := &netlink.Acknowledgement{
+		Header: netlink.Header{
+			Length:   ack.Header.Length,
+			Type:     netlink.Error,
+			Flags:    netlink.AcknowledgeTLVs | netlink.Capped,
+			Sequence: msgs[0].Header.Sequence,
+			PID:      msgs[0].Header.PID,
+		},
+		Request: netlink.Header{
+			Length:   20,
+			Type:     16,
+			Flags:    netlink.Request | netlink.Acknowledge,
+			Sequence: msgs[0].Header.Sequence,
+			PID:      msgs[0].Header.PID,
+		},
+		Cookie: cookie,
+	}
+
+	if diff := cmp.Diff(want, ack); diff != "" {
+		t.Fatalf("unexpected acknowledgement (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,161 @@
+package netlink_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nltest"
+)
+
+func TestConnExecuteResult(t *testing.T) {
+	// A 4 byte family header followed by attributes.
+	hdr := []byte{0xff, 0xff, 0xff, 0xff}
+	attrs := nltest.MustMarshalAttributes([]netlink.Attribute{
+		{Type: 1, Data: []byte("hello")},
+		{Type: 2, Data: []byte{0x01}},
+	})
+
+	c := nltest.Dial(func(req []netlink.Message) ([]netlink.Message, error) {
+		ack, _ := nltest.Error(0, req)
+		return append([]netlink.Message{{
+			Header: reply(req[0]),
+			Data:   append(hdr, attrs...),
+		}}, ack...), nil
+	})
+	defer c.Close()
+
+	r, err := c.ExecuteResult(netlink.Message{
+		Header: netlink.Header{
+			Type:  16,
+			Flags: netlink.Request | netlink.Acknowledge | netlink.Echo,
+		},
+	}, len(hdr))
+	if err != nil {
+		t.Fatalf("failed to execute: %v", err)
+	}
+
+	if diff := cmp.Diff(2, r.Len()); diff != "" {
+		t.Fatalf("unexpected number of replies (-want +got):\n%s", diff)
+	}
+
+	ack, ok := r.Ack()
+	if !ok {
+		t.Fatal("expected an acknowledgement, but none was found")
+	}
+	if diff := cmp.Diff(r.Messages()[1], ack); diff != "" {
+		t.Fatalf("unexpected acknowledgement (-want +got):\n%s", diff)
+	}
+
+	ad, err := r.Attributes(0)
+	if err != nil {
+		t.Fatalf("failed to get attributes: %v", err)
+	}
+
+	var (
+		s string
+		u uint8
+	)
+	for ad.Next() {
+		switch ad.Type() {
+		case 1:
+			s = ad.String()
+		case 2:
+			u = ad.Uint8()
+		}
+	}
+	if err := ad.Err(); err != nil {
+		t.Fatalf("failed to decode attributes: %v", err)
+	}
+
+	if s != "hello" || u != 1 {
+		t.Fatalf("unexpected attribute values: %q, %d", s, u)
+	}
+}
+
+func TestResultAttributesErrors(t *testing.T) {
+	c := nltest.Dial(func(req []netlink.Message) ([]netlink.Message, error) {
+		ack, _ := nltest.Error(0, req)
+		return append([]netlink.Message{{
+			Header: reply(req[0]),
+			Data:   []byte{0x01},
+		}}, ack...), nil
+	})
+	defer c.Close()
+
+	r, err := c.ExecuteResult(netlink.Message{
+		Header: netlink.Header{
+			Type:  16,
+			Flags: netlink.Request | netlink.Acknowledge,
+		},
+	}, 4)
+	if err != nil {
+		t.Fatalf("failed to execute: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		i    int
+	}{
+		{name: "negative index", i: -1},
+		{name: "out of range", i: 2},
+		{name: "short header", i: 0},
+		{name: "acknowledgement", i: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := r.Attributes(tt.i); err == nil {
+				t.Fatal("expected an error, but none occurred")
+			}
+		})
+	}
+}
+
+func TestResultNoAck(t *testing.T) {
+	c := nltest.Dial(func(req []netlink.Message) ([]netlink.Message, error) {
+		return []netlink.Message{{Header: reply(req[0])}}, nil
+	})
+	defer c.Close()
+
+	r, err := c.ExecuteResult(netlink.Message{
+		Header: netlink.Header{Type: 16, Flags: netlink.Request},
+	}, 0)
+	if err != nil {
+		t.Fatalf("failed to execute: %v", err)
+	}
+
+	if _, ok := r.Ack(); ok {
+		t.Fatal("expected no acknowledgement, but one was found")
+	}
+}
+
+func TestConnExecuteAckNoAck(t *testing.T) {
+	c := nltest.Dial(func(req []netlink.Message) ([]netlink.Message, error) {
+		return []netlink.Message{{Header: reply(req[0])}}, nil
+	})
+	defer c.Close()
+
+	msgs, ack, err := c.ExecuteAck(netlink.Message{
+		Header: netlink.Header{Type: 16, Flags: netlink.Request},
+	})
+	if err != nil {
+		t.Fatalf("failed to execute: %v", err)
+	}
+
+	if diff := cmp.Diff(1, len(msgs)); diff != "" {
+		t.Fatalf("unexpected number of replies (-want +got):\n%s", diff)
+	}
+	if ack != nil {
+		t.Fatalf("expected no acknowledgement, but got: %+v", ack)
+	}
+}
+
+// reply returns a Header for a reply to req.
+func reply(req netlink.Message) netlink.Header {
+	return netlink.Header{
+		Type:     req.Header.Type,
+		Sequence: req.Header.Sequence,
+		PID:      req.Header.PID,
+	}
+}
@@ -0,0 +1,200 @@
+package netlink
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+)
+
+// Serve receives messages from netlink in a loop and invokes fn with each
+// group of received messages, as returned by Receive. Serve is intended for
+// applications which process multicast group notifications.
+//
+// Serve returns when ctx is canceled, when fn returns an error, or when an
+// error occurs while receiving messages. When ctx is canceled, any pending
+// receive operation is interrupted and ctx.Err() is returned. Unlike closing
+// the Conn to unblock Receive, the Conn remains usable once Serve returns.
+//
+// If fn panics, the panic is recovered and returned as a *PanicError.
+//
+// Serve only holds the Conn's lock while receiving messages, so fn may safely
+// call other methods on the Conn, including Execute.
+func (c *Conn) Serve(ctx context.Context, fn func(msgs []Message) error) error {
+	return c.serve(ctx, fn, nil)
+}
+
+// ServeErrors is like Serve, but errors returned by fn and panics recovered
+// from fn (as a *PanicError) are sent on errC, and ServeErrors continues to
+// receive messages. This ensures that a single bad message handler cannot
+// terminate an entire monitoring application.
+//
+// ServeErrors returns when ctx is canceled or when an error occurs while
+// receiving messages. Sending on errC blocks until the error is received or
+// ctx is canceled.
+func (c *Conn) ServeErrors(ctx context.Context, fn func(msgs []Message) error, errC chan<- error) error {
+	return c.serve(ctx, fn, errC)
+}
+
+// serve implements Serve and ServeErrors. If errC is nil, errors from fn
+// terminate the loop.
+func (c *Conn) serve(ctx context.Context, fn func(msgs []Message) error, errC chan<- error) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		msgs, err := c.serveReceive(ctx)
+		if err != nil {
+			// Report cancelation directly rather than the wrapped error
+			// produced by the interrupted receive operation.
+			if cerr := ctx.Err(); cerr != nil {
+				return cerr
+			}
+
+			return err
+		}
+
+		err = handle(fn, msgs)
+		if err == nil {
+			continue
+		}
+
+		if errC == nil {
+			return err
+		}
+
+		select {
+		case errC <- err:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// serveReceive acquires c.mu and receives messages for Serve.
+func (c *Conn) serveReceive(ctx context.Context) ([]Message, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.lockedReceive(ctx, &receiveState{inject: true})
+}
+
+// Messages returns channels which deliver each message received from netlink,
+// and any errors which occur while receiving messages. Messages is intended
+// for applications which process multicast group notifications in a select
+// loop alongside other channels, such as timers.
+//
+// The first call to Messages starts a goroutine which receives messages until
+// the Conn is closed, and later calls return the same channels. The goroutine
+// holds the Conn's lock while receiving messages, so Messages should only be
+// used with a Conn dedicated to receiving notifications.
+//
+// An error indicating that the kernel discarded messages because the socket's
+// receive buffer was full, ENOBUFS on Linux, is sent on the error channel and
+// receiving continues. Any other error is sent on the error channel and stops
+// the goroutine. Both channels are closed once the goroutine stops, including
+// when the Conn is closed.
+func (c *Conn) Messages() (<-chan Message, <-chan error) {
+	c.msgs.mu.Lock()
+	defer c.msgs.mu.Unlock()
+
+	if c.msgs.msgC == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		c.msgs.msgC = make(chan Message)
+		c.msgs.errC = make(chan error, 1)
+		c.msgs.stop = cancel
+
+		go c.receiveMessages(ctx, c.msgs.msgC, c.msgs.errC)
+	}
+
+	return c.msgs.msgC, c.msgs.errC
+}
+
+// messagesState contains the state of the goroutine started by Messages.
+type messagesState struct {
+	mu   sync.Mutex
+	msgC chan Message
+	errC chan error
+	stop context.CancelFunc
+}
+
+// stopMessages stops the goroutine started by Messages, if any.
+func (c *Conn) stopMessages() {
+	c.msgs.mu.Lock()
+	defer c.msgs.mu.Unlock()
+
+	if c.msgs.stop != nil {
+		c.msgs.stop()
+	}
+}
+
+// receiveMessages implements the goroutine started by Messages.
+func (c *Conn) receiveMessages(ctx context.Context, msgC chan<- Message, errC chan<- error) {
+	defer func() {
+		close(msgC)
+		close(errC)
+	}()
+
+	for {
+		msgs, err := c.ReceiveContext(ctx)
+		if err != nil {
+			if ctx.Err() != nil || atomic.LoadUint32(&c.closed) != 0 {
+				// The Conn was closed.
+				return
+			}
+
+			select {
+			case errC <- err:
+			case <-ctx.Done():
+				return
+			}
+
+			if !isOverrun(err) {
+				return
+			}
+
+			continue
+		}
+
+		for _, m := range msgs {
+			select {
+			case msgC <- m:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// handle invokes fn with msgs, converting any panic into a *PanicError.
+func handle(fn func(msgs []Message) error, msgs []Message) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{
+				Value: r,
+				Stack: debug.Stack(),
+			}
+		}
+	}()
+
+	return fn(msgs)
+}
+
+var _ error = &PanicError{}
+
+// A PanicError is an error produced when a message handler function passed to
+// Serve or ServeErrors panics.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value interface{}
+
+	// Stack is the stack trace of the goroutine at the time of the panic.
+	Stack []byte
+}
+
+// Error implements error.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("netlink: message handler panic: %v", e.Value)
+}
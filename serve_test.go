@@ -0,0 +1,194 @@
+package netlink_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nltest"
+)
+
+func TestConnServe(t *testing.T) {
+	var seq uint32
+	c := nltest.Dial(func(_ []netlink.Message) ([]netlink.Message, error) {
+		// Simulate an infinite stream of multicast messages.
+		seq++
+		return []netlink.Message{{
+			Header: netlink.Header{Sequence: seq},
+		}}, nil
+	})
+	defer c.Close()
+
+	errDone := errors.New("done")
+
+	var got []uint32
+	err := c.Serve(context.Background(), func(msgs []netlink.Message) error {
+		for _, m := range msgs {
+			got = append(got, m.Header.Sequence)
+		}
+
+		if len(got) == 3 {
+			return errDone
+		}
+
+		return nil
+	})
+	if !errors.Is(err, errDone) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if diff := cmp.Diff([]uint32{1, 2, 3}, got); diff != "" {
+		t.Fatalf("unexpected sequence numbers (-want +got):\n%s", diff)
+	}
+}
+
+func TestConnServeCanceled(t *testing.T) {
+	c := nltest.Dial(func(_ []netlink.Message) ([]netlink.Message, error) {
+		return []netlink.Message{{}}, nil
+	})
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var n int
+	err := c.Serve(ctx, func(_ []netlink.Message) error {
+		n++
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if diff := cmp.Diff(1, n); diff != "" {
+		t.Fatalf("unexpected number of handler calls (-want +got):\n%s", diff)
+	}
+}
+
+func TestConnServePanic(t *testing.T) {
+	c := nltest.Dial(func(_ []netlink.Message) ([]netlink.Message, error) {
+		return []netlink.Message{{}}, nil
+	})
+	defer c.Close()
+
+	err := c.Serve(context.Background(), func(_ []netlink.Message) error {
+		panic("oops")
+	})
+
+	var perr *netlink.PanicError
+	if !errors.As(err, &perr) {
+		t.Fatalf("expected *netlink.PanicError, but got: %#v", err)
+	}
+
+	if diff := cmp.Diff("oops", perr.Value); diff != "" {
+		t.Fatalf("unexpected panic value (-want +got):\n%s", diff)
+	}
+	if len(perr.Stack) == 0 {
+		t.Fatal("expected a stack trace, but none was captured")
+	}
+}
+
+func TestConnServeErrors(t *testing.T) {
+	c := nltest.Dial(func(_ []netlink.Message) ([]netlink.Message, error) {
+		return []netlink.Message{{}}, nil
+	})
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errC := make(chan error)
+	doneC := make(chan error, 1)
+
+	var n int
+	go func() {
+		doneC <- c.ServeErrors(ctx, func(_ []netlink.Message) error {
+			n++
+			switch n {
+			case 1:
+				panic("oops")
+			case 2:
+				return errors.New("handler error")
+			default:
+				// Keep receiving until canceled.
+				return nil
+			}
+		}, errC)
+	}()
+
+	// The first handler call panics and the second returns an error, but
+	// ServeErrors keeps running for both.
+	var perr *netlink.PanicError
+	if err := <-errC; !errors.As(err, &perr) {
+		t.Fatalf("expected *netlink.PanicError, but got: %v", err)
+	}
+	if err := <-errC; err == nil || err.Error() != "handler error" {
+		t.Fatalf("unexpected handler error: %v", err)
+	}
+
+	cancel()
+	if err := <-doneC; !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestConnMessages(t *testing.T) {
+	var seq uint32
+	c := nltest.Dial(func(_ []netlink.Message) ([]netlink.Message, error) {
+		// Simulate an infinite stream of multicast messages.
+		seq++
+		return []netlink.Message{{
+			Header: netlink.Header{Sequence: seq},
+		}}, nil
+	})
+
+	msgC, errC := c.Messages()
+	if msgC2, _ := c.Messages(); msgC != msgC2 {
+		t.Fatal("Messages returned different channels on the second call")
+	}
+
+	var got []uint32
+	for len(got) < 3 {
+		select {
+		case m := <-msgC:
+			got = append(got, m.Header.Sequence)
+		case err := <-errC:
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if diff := cmp.Diff([]uint32{1, 2, 3}, got); diff != "" {
+		t.Fatalf("unexpected sequence numbers (-want +got):\n%s", diff)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	// Both channels are closed without an error once the Conn is closed.
+	for range msgC {
+	}
+	if err, ok := <-errC; ok {
+		t.Fatalf("unexpected error after close: %v", err)
+	}
+}
+
+func TestConnMessagesError(t *testing.T) {
+	errFail := errors.New("failed")
+	c := nltest.Dial(func(_ []netlink.Message) ([]netlink.Message, error) {
+		return nil, errFail
+	})
+	defer c.Close()
+
+	msgC, errC := c.Messages()
+	if err := <-errC; !errors.Is(err, errFail) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, ok := <-msgC; ok {
+		t.Fatal("messages channel was not closed after an error")
+	}
+}
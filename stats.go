@@ -0,0 +1,82 @@
+package netlink
+
+import "sync/atomic"
+
+// Stats contains statistics about the operation of a Conn. All counters are
+// cumulative over the lifetime of the Conn, and are suitable for export as
+// metrics by long-running applications.
+type Stats struct {
+	// MessagesSent and BytesSent are the number of messages and bytes
+	// successfully sent by the Conn, including message headers.
+	MessagesSent uint64
+	BytesSent    uint64
+
+	// MessagesReceived and BytesReceived are the number of messages and bytes
+	// received by the Conn, including message headers and messages which are
+	// not returned to the caller, such as the final "multi-part done" message.
+	MessagesReceived uint64
+	BytesReceived    uint64
+
+	// DumpsCompleted is the number of multi-part messages, such as the
+	// replies to a dump request, which were received in full.
+	DumpsCompleted uint64
+
+	// Errors is the number of messages received by the Conn which indicated
+	// a netlink error.
+	Errors uint64
+
+	// Overruns is the number of times the kernel reported that messages were
+	// discarded because the socket's receive buffer was full (ENOBUFS).
+	Overruns uint64
+
+	// Warnings is the number of non-fatal extended acknowledgement warnings
+	// received by the Conn. Warnings are only sent by the kernel when the
+	// ExtendedAcknowledge option is set.
+	Warnings uint64
+
+	// Duplicates is the number of received messages which were discarded by
+	// the filter set with Conn.SetDuplicateFilter.
+	Duplicates uint64
+}
+
+// connStats contains the atomically incremented counters used to produce
+// Stats. It must be the first field of Conn to guarantee 64-bit alignment.
+type connStats struct {
+	messagesSent     uint64
+	bytesSent        uint64
+	messagesReceived uint64
+	bytesReceived    uint64
+	dumpsCompleted   uint64
+	errors           uint64
+	overruns         uint64
+	warnings         uint64
+	duplicates       uint64
+}
+
+// Stats returns statistics about the operation of the Conn.
+func (c *Conn) Stats() Stats {
+	return Stats{
+		MessagesSent:     atomic.LoadUint64(&c.stats.messagesSent),
+		BytesSent:        atomic.LoadUint64(&c.stats.bytesSent),
+		MessagesReceived: atomic.LoadUint64(&c.stats.messagesReceived),
+		BytesReceived:    atomic.LoadUint64(&c.stats.bytesReceived),
+		DumpsCompleted:   atomic.LoadUint64(&c.stats.dumpsCompleted),
+		Errors:           atomic.LoadUint64(&c.stats.errors),
+		Overruns:         atomic.LoadUint64(&c.stats.overruns),
+		Warnings:         atomic.LoadUint64(&c.stats.warnings),
+		Duplicates:       atomic.LoadUint64(&c.stats.duplicates),
+	}
+}
+
+// countMessages adds the number and length of msgs to the counters n and b.
+func countMessages(n, b *uint64, msgs []Message) {
+	var l uint64
+	for _, m := range msgs {
+		// Compute the length from the data, as a Socket may not report
+		// accurate header lengths.
+		l += uint64(nlmsgLength(len(m.Data)))
+	}
+
+	atomic.AddUint64(n, uint64(len(msgs)))
+	atomic.AddUint64(b, l)
+}
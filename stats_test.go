@@ -0,0 +1,52 @@
+package netlink_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nltest"
+)
+
+func TestConnStats(t *testing.T) {
+	c := nltest.Dial(func(req []netlink.Message) ([]netlink.Message, error) {
+		if req[0].Header.Flags&netlink.Dump == 0 {
+			return nltest.Error(1, req)
+		}
+
+		return nltest.Multipart([]netlink.Message{
+			{Header: netlink.Header{Sequence: req[0].Header.Sequence}, Data: []byte{0xff, 0xff, 0xff, 0xff}},
+			{Header: netlink.Header{Sequence: req[0].Header.Sequence}},
+			{},
+		})
+	})
+	defer c.Close()
+
+	if _, err := c.Execute(netlink.Message{
+		Header: netlink.Header{Flags: netlink.Request | netlink.Dump},
+	}); err != nil {
+		t.Fatalf("failed to execute dump: %v", err)
+	}
+
+	if _, err := c.Execute(netlink.Message{
+		Header: netlink.Header{Flags: netlink.Request | netlink.Acknowledge},
+		Data:   []byte{0xff, 0xff, 0xff, 0xff},
+	}); err == nil {
+		t.Fatal("expected an error, but none occurred")
+	}
+
+	want := netlink.Stats{
+		MessagesSent:     2,
+		BytesSent:        16 + 20,
+		MessagesReceived: 4,
+		// Two dump messages and done, then an error number followed by the
+		// request data.
+		BytesReceived:  20 + 16 + 16 + 24,
+		DumpsCompleted: 1,
+		Errors:         1,
+	}
+
+	if diff := cmp.Diff(want, c.Stats()); diff != "" {
+		t.Fatalf("unexpected stats (-want +got):\n%s", diff)
+	}
+}
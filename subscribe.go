@@ -0,0 +1,107 @@
+package netlink
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// Default values for Backoff.
+const (
+	defaultBackoffInitial = 100 * time.Millisecond
+	defaultBackoffMax     = 30 * time.Second
+)
+
+// A Backoff specifies the delays between attempts of an operation which is
+// retried with exponential backoff, such as Conn.JoinGroupRetry. The zero
+// value of Backoff retries indefinitely using default delays.
+type Backoff struct {
+	// Initial is the delay before the first retry, which doubles after each
+	// subsequent attempt. If Initial is zero, a default of 100 milliseconds is
+	// used.
+	Initial time.Duration
+
+	// Max is the maximum delay between attempts. If Max is zero, a default of
+	// 30 seconds is used.
+	Max time.Duration
+
+	// Attempts is the maximum number of attempts, including the first. If
+	// Attempts is less than 1, attempts continue until the operation succeeds
+	// or is canceled.
+	Attempts int
+}
+
+// delay returns the delay before retry i, starting from 0.
+func (b Backoff) delay(i int) time.Duration {
+	d, max := b.Initial, b.Max
+	if d <= 0 {
+		d = defaultBackoffInitial
+	}
+	if max <= 0 {
+		max = defaultBackoffMax
+	}
+
+	for ; i > 0 && d < max; i-- {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+
+	return d
+}
+
+// JoinGroupRetry joins a netlink multicast group by its ID like JoinGroup,
+// but retries failed attempts in the background with exponential backoff as
+// specified by b. JoinGroup can fail transiently, such as while the kernel
+// module which provides a multicast group is being loaded.
+//
+// JoinGroupRetry returns immediately. The returned channel receives the
+// result of the final attempt, which is nil if the group was joined, and is
+// then closed. Attempts stop early if ctx is canceled, if the Conn is closed,
+// or if the Conn's Socket does not support joining groups.
+func (c *Conn) JoinGroupRetry(ctx context.Context, group uint32, b Backoff) <-chan error {
+	errC := make(chan error, 1)
+
+	go func() {
+		defer close(errC)
+		errC <- c.joinGroupRetry(ctx, group, b)
+	}()
+
+	return errC
+}
+
+// joinGroupRetry implements JoinGroupRetry.
+func (c *Conn) joinGroupRetry(ctx context.Context, group uint32, b Backoff) error {
+	for i := 0; ; i++ {
+		if err := ctx.Err(); err != nil {
+			return newOpError("join-group", err)
+		}
+
+		err := c.JoinGroup(group)
+		if err == nil || !retryJoin(err) || atomic.LoadUint32(&c.closed) != 0 {
+			return err
+		}
+		if b.Attempts > 0 && i+1 >= b.Attempts {
+			return err
+		}
+
+		wait := b.delay(i)
+		c.debug(func(d *debugger) {
+			d.debugf(1, "join-group: group %d: %v, retrying in %s", group, err, wait)
+		})
+
+		select {
+		case <-ctx.Done():
+			return newOpError("join-group", ctx.Err())
+		case <-c.after(wait):
+		}
+	}
+}
+
+// retryJoin reports whether a failed attempt to join a group which returned
+// err should be retried.
+func retryJoin(err error) bool {
+	return !errors.Is(err, errNotSupported) && !isUnusable(err)
+}
@@ -0,0 +1,132 @@
+package netlink_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nltest"
+)
+
+func TestConnJoinGroupRetry(t *testing.T) {
+	errJoin := errors.New("join failed")
+
+	tests := []struct {
+		name     string
+		failures int
+		b        netlink.Backoff
+		ok       bool
+		joins    int
+	}{
+		{
+			name:  "immediate",
+			b:     netlink.Backoff{Attempts: 1},
+			ok:    true,
+			joins: 1,
+		},
+		{
+			name:     "retried",
+			failures: 3,
+			ok:       true,
+			joins:    4,
+		},
+		{
+			name:     "attempts exhausted",
+			failures: 10,
+			b:        netlink.Backoff{Attempts: 3},
+			joins:    3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sock := &flakyJoinSocket{failures: tt.failures, err: errJoin}
+			c := dialFlakyJoinSocket(t, sock)
+
+			err := <-c.JoinGroupRetry(context.Background(), 1, tt.b)
+			if tt.ok && err != nil {
+				t.Fatalf("failed to join group: %v", err)
+			}
+			if !tt.ok && !errors.Is(err, errJoin) {
+				t.Fatalf("expected join error, but got: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.joins, sock.joins); diff != "" {
+				t.Fatalf("unexpected number of joins (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConnJoinGroupRetryCanceled(t *testing.T) {
+	sock := &flakyJoinSocket{failures: 1, err: errors.New("join failed")}
+	c := dialFlakyJoinSocket(t, sock)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := <-c.JoinGroupRetry(ctx, 1, netlink.Backoff{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context canceled, but got: %v", err)
+	}
+	if sock.joins != 0 {
+		t.Fatalf("expected no joins, but got: %d", sock.joins)
+	}
+}
+
+func TestConnJoinGroupRetryUnsupported(t *testing.T) {
+	c := nltest.Dial(nil)
+	defer c.Close()
+
+	err := <-c.JoinGroupRetry(context.Background(), 1, netlink.Backoff{})
+	if err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// dialFlakyJoinSocket dials a netlink.Conn backed by sock, with a Clock whose
+// timers fire immediately.
+func dialFlakyJoinSocket(t *testing.T, sock *flakyJoinSocket) *netlink.Conn {
+	t.Helper()
+
+	prev := netlink.SetDialInterceptor(func(_ int, _ *netlink.Config) (netlink.Socket, uint32, error) {
+		return sock, nltest.PID, nil
+	})
+	defer netlink.SetDialInterceptor(prev)
+
+	c, err := netlink.Dial(0, &netlink.Config{
+		Clock: &fakeClock{now: time.Unix(1, 0)},
+	})
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+
+	return c
+}
+
+// flakyJoinSocket is a netlink.Socket whose first failures attempts to join a
+// group return err.
+type flakyJoinSocket struct {
+	failures int
+	err      error
+	joins    int
+}
+
+func (s *flakyJoinSocket) Close() error                           { return nil }
+func (s *flakyJoinSocket) Send(_ netlink.Message) error           { panic("unimplemented") }
+func (s *flakyJoinSocket) SendMessages(_ []netlink.Message) error { panic("unimplemented") }
+func (s *flakyJoinSocket) Receive() ([]netlink.Message, error)    { panic("unimplemented") }
+func (s *flakyJoinSocket) LeaveGroup(_ uint32) error              { panic("unimplemented") }
+func (s *flakyJoinSocket) JoinGroup(_ uint32) error {
+	s.joins++
+	if s.joins <= s.failures {
+		return s.err
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package netlink
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// A TraceEvent identifies a point at which a TraceFunc is called.
+type TraceEvent int
+
+// Possible TraceEvent values.
+const (
+	// TraceSend is a system call which sends one or more messages.
+	TraceSend TraceEvent = iota + 1
+
+	// TraceReceive is a system call which receives a datagram.
+	TraceReceive
+
+	// TraceParse is the parsing of the messages in a received datagram.
+	TraceParse
+)
+
+// String returns the string representation of a TraceEvent.
+func (e TraceEvent) String() string {
+	switch e {
+	case TraceSend:
+		return "send"
+	case TraceReceive:
+		return "receive"
+	case TraceParse:
+		return "parse"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(e))
+	}
+}
+
+// A TraceFunc is called once each TraceEvent completes, with the number of
+// bytes processed and the time spent, for fine-grained latency attribution
+// when profiling. A TraceFunc must be safe for concurrent use and should
+// return quickly.
+type TraceFunc func(ev TraceEvent, n int, d time.Duration)
+
+// traceHook stores the TraceFunc registered by SetTraceFunc.
+var traceHook atomic.Value
+
+// SetTraceFunc registers fn as the process-wide TraceFunc, or removes the
+// TraceFunc if fn is nil.
+//
+// Trace hooks are only called in binaries built with the netlink_trace build
+// tag, as reported by TraceEnabled. Otherwise, the hooks are compiled out so
+// tracing has no runtime cost, and SetTraceFunc has no effect.
+func SetTraceFunc(fn TraceFunc) { traceHook.Store(fn) }
+
+// traceStart returns the start time of a TraceEvent, if tracing is enabled.
+func traceStart() time.Time {
+	if !TraceEnabled {
+		return time.Time{}
+	}
+
+	return time.Now()
+}
+
+// traceSince calls the registered TraceFunc, if any, for a TraceEvent which
+// began at start. Callers must check TraceEnabled first so the call is
+// compiled out when tracing is disabled.
+func traceSince(ev TraceEvent, n int, start time.Time) {
+	if fn, _ := traceHook.Load().(TraceFunc); fn != nil {
+		fn(ev, n, time.Since(start))
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !netlink_trace
+// +build !netlink_trace
+
+package netlink
+
+// TraceEnabled reports whether the binary was built with the netlink_trace
+// build tag, which enables the hooks registered by SetTraceFunc.
+const TraceEnabled = false
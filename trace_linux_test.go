@@ -0,0 +1,65 @@
+//go:build linux
+// +build linux
+
+package netlink_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mdlayher/netlink"
+	"golang.org/x/sys/unix"
+)
+
+func TestConnTrace(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		seen = make(map[netlink.TraceEvent]bool)
+	)
+
+	netlink.SetTraceFunc(func(ev netlink.TraceEvent, n int, _ time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if n > 0 {
+			seen[ev] = true
+		}
+	})
+	defer netlink.SetTraceFunc(nil)
+
+	c, err := netlink.Dial(unix.NETLINK_GENERIC, nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer c.Close()
+
+	_, err = c.Execute(netlink.Message{
+		Header: netlink.Header{
+			Type:  unix.GENL_ID_CTRL,
+			Flags: netlink.Request | netlink.Acknowledge,
+		},
+		Data: []byte{unix.CTRL_CMD_GETFAMILY, 1, 0, 0},
+	})
+	if err == nil {
+		t.Fatal("expected an error for a request without a family, but none occurred")
+	}
+
+	// The hooks are only called when built with the netlink_trace tag.
+	want := map[netlink.TraceEvent]bool{}
+	if netlink.TraceEnabled {
+		want = map[netlink.TraceEvent]bool{
+			netlink.TraceSend:    true,
+			netlink.TraceReceive: true,
+			netlink.TraceParse:   true,
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if diff := cmp.Diff(want, seen); diff != "" {
+		t.Fatalf("unexpected trace events (-want +got):\n%s", diff)
+	}
+}